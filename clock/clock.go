@@ -0,0 +1,56 @@
+// Package clock provides an abstraction over the current time, allowing time-based features to be
+// tested deterministically.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock describes the functionality needed to read the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// New returns a Clock backed by the system time.
+func New() Clock {
+	return realClock{}
+}
+
+// Fake is a Clock whose time only changes when explicitly set or advanced. It is meant for tests.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a new Fake clock set to the provided time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the current time of the fake clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the fake clock forward by the provided duration.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set changes the current time of the fake clock.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
@@ -23,11 +23,9 @@ import (
 )
 
 const (
-	testPort               = 8787
-	testRssFeed            = "https://www.nytimes.com/svc/collections/v1/publish/https://www.nytimes.com/section/world/rss.xml"
-	testRssFeedID          = "7ebf8f47-3aef-509e-a3fe-1bfe55e317a8"
-	testSecondaryRssFeed   = "http://feeds.bbci.co.uk/news/uk/rss.xml"
-	testSecondaryRssFeedID = "0792cd43-d8f3-5a38-9739-c797bd08c6fa"
+	testPort             = 8787
+	testRssFeed          = "https://www.nytimes.com/svc/collections/v1/publish/https://www.nytimes.com/section/world/rss.xml"
+	testSecondaryRssFeed = "http://feeds.bbci.co.uk/news/uk/rss.xml"
 )
 
 // ResponseError reads the error message returned in a JSON response.
@@ -59,8 +57,8 @@ func (s *TestSuite) SetupSuite() {
 	s.feedStore = store.NewFeedStore()
 	s.articleStore = store.NewArticleStore()
 	s.feed = rssreader.NewFeed()
-	consumer := feedconsumer.NewFeedConsumer(s.feed, s.articleStore)
-	s.service = service.NewService(consumer, s.feedStore, s.articleStore)
+	consumer := feedconsumer.NewFeedConsumer(s.feed, s.articleStore, feedconsumer.WithFeedStore(s.feedStore))
+	s.service = service.NewService(consumer, s.feed, s.feedStore, s.articleStore)
 	go s.service.ServeForever(testPort)
 }
 
@@ -77,7 +75,7 @@ func (s *TestSuite) TestCanReadArticlesFromANewsFeed() {
 	a.Equal("c", feed.Category)
 	a.Equal("p", feed.Provider)
 	a.Equal(testRssFeed, feed.Address)
-	r.Equal(testRssFeedID, feed.ID)
+	r.Equal(s.feedStore.ComputeID(testRssFeed, "c"), feed.ID)
 
 	// Load the feed.
 	s.loadFeed(feed.ID)
@@ -95,19 +93,19 @@ func (s *TestSuite) TestCanReadRequiredNewsFeed() {
 	r := require.New(t)
 
 	exampleFeeds := map[string]string{
-		"0792cd43-d8f3-5a38-9739-c797bd08c6fa": "http://feeds.bbci.co.uk/news/uk/rss.xml",
-		"eb486396-2226-5371-a2b3-15f9dcfa6235": "http://feeds.bbci.co.uk/news/technology/rss.xml",
-		"d9641b79-1061-590e-b904-9ed34a852b47": "http://feeds.skynews.com/feeds/rss/uk.xml",
-		"101371f8-b500-5a45-8eef-14c6101ab650": "http://feeds.skynews.com/feeds/rss/technology.xml",
+		"bbc_uk":         "http://feeds.bbci.co.uk/news/uk/rss.xml",
+		"bbc_technology": "http://feeds.bbci.co.uk/news/technology/rss.xml",
+		"sky_uk":         "http://feeds.skynews.com/feeds/rss/uk.xml",
+		"sky_technology": "http://feeds.skynews.com/feeds/rss/technology.xml",
 	}
 
 	// Create new feeds and load articles.
-	for id, feedAddress := range exampleFeeds {
-		feed := s.createFeed(id+"_p", id+"_c", feedAddress)
-		a.Equal(id+"_c", feed.Category)
-		a.Equal(id+"_p", feed.Provider)
+	for name, feedAddress := range exampleFeeds {
+		feed := s.createFeed(name+"_p", name+"_c", feedAddress)
+		a.Equal(name+"_c", feed.Category)
+		a.Equal(name+"_p", feed.Provider)
 		a.Equal(feedAddress, feed.Address)
-		r.Equal(id, feed.ID)
+		r.Equal(s.feedStore.ComputeID(feedAddress, name+"_c"), feed.ID)
 		s.loadFeed(feed.ID)
 	}
 
@@ -144,7 +142,7 @@ func (s *TestSuite) TestCanLazyLoadArticles() {
 	a.Equal("c", feed.Category)
 	a.Equal("p", feed.Provider)
 	a.Equal(testRssFeed, feed.Address)
-	r.Equal(testRssFeedID, feed.ID)
+	r.Equal(s.feedStore.ComputeID(testRssFeed, "c"), feed.ID)
 
 	// Load the feed.
 	s.loadFeed(feed.ID)
@@ -177,7 +175,7 @@ func (s *TestSuite) TestCanFilterArticlesByCategories() {
 	a.Equal("c", feed.Category)
 	a.Equal("p", feed.Provider)
 	a.Equal(testRssFeed, feed.Address)
-	r.Equal(testRssFeedID, feed.ID)
+	r.Equal(s.feedStore.ComputeID(testRssFeed, "c"), feed.ID)
 
 	// Load the feed.
 	s.loadFeed(feed.ID)
@@ -221,14 +219,14 @@ func (s *TestSuite) TestCanFilterArticlesByFeed() {
 	a.Equal("c1", feed.Category)
 	a.Equal("p1", feed.Provider)
 	a.Equal(testRssFeed, feed.Address)
-	r.Equal(testRssFeedID, feed.ID)
+	r.Equal(s.feedStore.ComputeID(testRssFeed, "c1"), feed.ID)
 
 	// Create a secondary feed to have two stored feeds and articles in the store.
 	feed2 := s.createFeed("p2", "c2", testSecondaryRssFeed)
 	a.Equal("c2", feed2.Category)
 	a.Equal("p2", feed2.Provider)
 	a.Equal(testSecondaryRssFeed, feed2.Address)
-	r.Equal(testSecondaryRssFeedID, feed2.ID)
+	r.Equal(s.feedStore.ComputeID(testSecondaryRssFeed, "c2"), feed2.ID)
 
 	// Load the feeds.
 	s.loadFeed(feed.ID)
@@ -269,7 +267,7 @@ func (s *TestSuite) TestCanGetSingleArticleByID() {
 	a.Equal("c", feed.Category)
 	a.Equal("p", feed.Provider)
 	a.Equal(testRssFeed, feed.Address)
-	r.Equal(testRssFeedID, feed.ID)
+	r.Equal(s.feedStore.ComputeID(testRssFeed, "c"), feed.ID)
 
 	// Load the feed.
 	s.loadFeed(feed.ID)
@@ -298,7 +296,7 @@ func (s *TestSuite) TestArticlesAreRetunedOrderedByPublishDate() {
 	a.Equal("c", feed.Category)
 	a.Equal("p", feed.Provider)
 	a.Equal(testRssFeed, feed.Address)
-	r.Equal(testRssFeedID, feed.ID)
+	r.Equal(s.feedStore.ComputeID(testRssFeed, "c"), feed.ID)
 
 	// Load the feed.
 	s.loadFeed(feed.ID)
@@ -313,6 +311,34 @@ func (s *TestSuite) TestArticlesAreRetunedOrderedByPublishDate() {
 	}
 }
 
+// TestCanRefreshFeed tests that a feed can be loaded and its latest articles returned in a single
+// call.
+func (s *TestSuite) TestCanRefreshFeed() {
+	t := s.T()
+	a := assert.New(t)
+	r := require.New(t)
+
+	// Create a new feed.
+	feed := s.createFeed("p", "c", testRssFeed)
+	r.Equal(s.feedStore.ComputeID(testRssFeed, "c"), feed.ID)
+
+	// Refresh the feed, which loads it and returns its latest articles.
+	articles := s.refreshFeed(feed.ID)
+	r.NotEqual(0, len(articles), "no articles returned after refreshing the feed")
+	for _, article := range articles {
+		a.Equal(feed.ID, article.FeedID)
+	}
+
+	// Checking the articles are newest first.
+	for i := 0; i < len(articles)-1; i++ {
+		r.True(!articles[i].PublishDate.Before(articles[i+1].PublishDate), "found unordered article")
+	}
+
+	// Checking the articles returned were stored.
+	article := s.getArticle(articles[0].ID)
+	a.Equal(articles[0].ID, article.ID)
+}
+
 func (s *TestSuite) createFeed(provider string, category string, address string) *types.Feed {
 	r := require.New(s.T())
 	feedData := map[string]string{
@@ -352,6 +378,21 @@ func (s *TestSuite) loadFeed(ID string) {
 	r.Empty(responseError.Error)
 }
 
+func (s *TestSuite) refreshFeed(ID string) []*types.Article {
+	r := require.New(s.T())
+	req, err := http.NewRequest(http.MethodPost, getAPIUrl("feeds", ID, "refresh"), nil)
+	r.NoError(err)
+	client := &http.Client{}
+	res, err := client.Do(req)
+	r.NoError(err)
+	defer res.Body.Close()
+	r.Equal(http.StatusOK, res.StatusCode)
+	var articles []*types.Article
+	err = json.NewDecoder(res.Body).Decode(&articles)
+	r.NoError(err)
+	return articles
+}
+
 func (s *TestSuite) listArticles(cursor string, pageSize uint, feed string, categories ...string) []*types.Article {
 	r := require.New(s.T())
 	req, err := http.NewRequest(http.MethodGet, getAPIUrl("articles"), nil)
@@ -359,6 +400,9 @@ func (s *TestSuite) listArticles(cursor string, pageSize uint, feed string, cate
 	q := req.URL.Query()
 	q.Add("c", cursor)
 	q.Add("pageSize", fmt.Sprintf("%d", pageSize))
+	if pageSize == 0 {
+		q.Add("all", "true")
+	}
 	q.Add("feed", feed)
 	for _, c := range categories {
 		q.Add("cat", c)
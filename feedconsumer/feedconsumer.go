@@ -4,50 +4,394 @@ package feedconsumer
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"../clock"
 	"../types"
+
+	"github.com/google/uuid"
 )
 
 // Feed describes the functionality required to load data from a feed.
 type Feed interface {
-	Load(address string) ([]*types.Article, error)
+	Read(address string, opts types.ReadOptions) (*types.Channel, error)
 }
 
 // ArticleStore describes the functionality needed to store articles.
 type ArticleStore interface {
 	Create(article *types.Article) (*types.Article, error)
+	Upsert(article *types.Article) (*types.Article, *types.ArticleDiff, error)
+}
+
+// ArticleProcessor describes the functionality needed to transform articles before storing them,
+// allowing custom logic such as tagging or enrichment to be applied to each consumed article.
+type ArticleProcessor interface {
+	Process(article *types.Article) (*types.Article, error)
+}
+
+// FeedStore describes the functionality needed to update feeds that have moved to a new address, or
+// whose category is taken from their channel.
+type FeedStore interface {
+	UpdateAddress(ID string, address string) (*types.Feed, error)
+	UpdateCategory(ID string, category string) (*types.Feed, error)
+}
+
+// LoadRecorder describes the functionality needed to keep the history of the loads of each feed.
+type LoadRecorder interface {
+	RecordLoad(ID string, success bool) (*types.Feed, error)
 }
 
+// FuturePolicy describes how articles published further into the future than the configured
+// tolerance are handled.
+type FuturePolicy int
+
+const (
+	// ClampFuture sets the publish date of the article to the current time.
+	ClampFuture FuturePolicy = iota
+	// DropFuture discards the article.
+	DropFuture
+)
+
 // FeedConsumer is a consumer that fetches articles from a feed and stores them in a store.
 type FeedConsumer struct {
-	feed  Feed
-	store ArticleStore
+	feed            Feed
+	store           ArticleStore
+	feedStore       FeedStore
+	loadRecorder    LoadRecorder
+	processors      []ArticleProcessor
+	clock           clock.Clock
+	limitFuture     bool
+	futureTolerance time.Duration
+	futurePolicy    FuturePolicy
+	batchIDs        bool
+	maxArticleAge   time.Duration
+	maxIngest       int
+
+	mu       sync.Mutex
+	inFlight map[loadKey]*load
+}
+
+// loadKey identifies the loads of a feed that can be shared, as forced loads have a different
+// outcome than regular ones.
+type loadKey struct {
+	feedID string
+	force  bool
+}
+
+// load is an in-flight load of a feed, whose outcome is shared with the concurrent loads of the same
+// feed started while it runs.
+type load struct {
+	done    chan struct{}
+	summary *types.LoadSummary
+	err     error
+}
+
+// Option configures optional behaviour of a FeedConsumer.
+type Option func(*FeedConsumer)
+
+// WithProcessors adds processors that are applied in order to each article before it is stored,
+// each one receiving the article returned by the previous. If a processor returns a nil article,
+// the article is discarded.
+func WithProcessors(processors ...ArticleProcessor) Option {
+	return func(c *FeedConsumer) {
+		c.processors = append(c.processors, processors...)
+	}
+}
+
+// WithFeedStore enables updating the address of feeds whose primary address permanently redirects
+// to a new one, and setting the category of feeds created without one to the title of their
+// channel, using the provided store.
+func WithFeedStore(store FeedStore) Option {
+	return func(c *FeedConsumer) {
+		c.feedStore = store
+	}
+}
+
+// WithLoadRecorder records whether each load of a feed succeeded using the provided recorder.
+func WithLoadRecorder(recorder LoadRecorder) Option {
+	return func(c *FeedConsumer) {
+		c.loadRecorder = recorder
+	}
 }
 
-// Consume fetches news from the provided feed and saves them in the provided store.
-func (c *FeedConsumer) Consume(feed *types.Feed) error {
-	articles, err := c.feed.Load(feed.Address)
+// WithClock sets the clock used by the consumer for time-based features, such as the future dates
+// tolerance.
+func WithClock(c clock.Clock) Option {
+	return func(fc *FeedConsumer) {
+		fc.clock = c
+	}
+}
+
+// WithFutureTolerance handles articles published more than the provided tolerance into the future,
+// which usually happens with feeds having clock skew, according to the provided policy.
+func WithFutureTolerance(tolerance time.Duration, policy FuturePolicy) Option {
+	return func(c *FeedConsumer) {
+		c.limitFuture = true
+		c.futureTolerance = tolerance
+		c.futurePolicy = policy
+	}
+}
+
+// WithBatchIDs tags the articles created by each load with an ID generated for the load, which is
+// reported in its summary, so it can be traced which load ingested each article.
+func WithBatchIDs(enabled bool) Option {
+	return func(c *FeedConsumer) {
+		c.batchIDs = enabled
+	}
+}
+
+// WithMaxArticleAge discards the articles published longer ago than the provided age, which are
+// counted as expired in the load summary. Zero means no limit.
+func WithMaxArticleAge(age time.Duration) Option {
+	return func(c *FeedConsumer) {
+		c.maxArticleAge = age
+	}
+}
+
+// WithMaxIngest limits the articles stored by each load to the n newest ones by publish date, so a
+// single load can't flood the store. The rest are counted as capped in the load summary. Zero means
+// no limit.
+func WithMaxIngest(n int) Option {
+	return func(c *FeedConsumer) {
+		c.maxIngest = n
+	}
+}
+
+// Consume fetches news from the provided feed and saves them in the provided store, returning a
+// summary of the articles stored. If the primary address of the feed fails to load, its fallback
+// addresses are tried in order. Articles already present in the store are kept as they are, unless
+// force is set, in which case they are updated with the values read from the feed. Concurrent calls
+// for the same feed and force value wait for the first one and return its outcome, so the feed is
+// only read once.
+func (c *FeedConsumer) Consume(feed *types.Feed, force bool) (*types.LoadSummary, error) {
+	key := loadKey{feedID: feed.ID, force: force}
+	c.mu.Lock()
+	if l, ok := c.inFlight[key]; ok {
+		c.mu.Unlock()
+		<-l.done
+		return l.summary, l.err
+	}
+	l := &load{done: make(chan struct{})}
+	c.inFlight[key] = l
+	c.mu.Unlock()
+
+	l.summary, l.err = c.consume(feed, force)
+	if c.loadRecorder != nil {
+		// Failing to record the outcome, such as for a feed deleted meanwhile, doesn't change it.
+		c.loadRecorder.RecordLoad(feed.ID, l.err == nil)
+	}
+	c.mu.Lock()
+	delete(c.inFlight, key)
+	c.mu.Unlock()
+	close(l.done)
+	return l.summary, l.err
+}
+
+// consume loads the feed as described by Consume.
+func (c *FeedConsumer) consume(feed *types.Feed, force bool) (*types.LoadSummary, error) {
+	channel, address, err := c.read(feed)
 	if err != nil {
-		return fmt.Errorf("could not load articles from the feed: %v", err)
+		return nil, fmt.Errorf("could not load articles from the feed: %w", err)
 	}
-	if len(articles) == 0 {
-		return nil
+	if c.feedStore != nil && address == feed.Address && channel.Moved {
+		if _, err := c.feedStore.UpdateAddress(feed.ID, channel.Address); err != nil {
+			return nil, fmt.Errorf("could not update the feed address: %v", err)
+		}
+	}
+	// The channel doesn't declare a category, so its title is the closest description of the feed.
+	if title := strings.TrimSpace(channel.Title); c.feedStore != nil && feed.Category == "" && title != "" {
+		if _, err := c.feedStore.UpdateCategory(feed.ID, title); err != nil {
+			return nil, fmt.Errorf("could not update the feed category: %v", err)
+		}
+	}
+	return c.ingest(feed, channel, force)
+}
+
+// Ingest saves the articles of a channel that was not read by the consumer, such as content pushed
+// by a WebSub hub, returning a summary of the articles stored. Articles already present in the store
+// are updated with the pushed values, since hubs only push feeds whose content changed.
+func (c *FeedConsumer) Ingest(feed *types.Feed, channel *types.Channel) (*types.LoadSummary, error) {
+	return c.ingest(feed, channel, true)
+}
+
+// ingest saves the articles of the channel read for the provided feed, as described by Consume.
+func (c *FeedConsumer) ingest(feed *types.Feed, channel *types.Channel, force bool) (*types.LoadSummary, error) {
+	summary := &types.LoadSummary{
+		Skipped:  channel.Skipped,
+		Attempts: channel.Attempts,
+		Retried:  channel.Attempts > 1,
+		Changes:  map[string][]string{},
+	}
+	if c.batchIDs {
+		summary.BatchID = uuid.New().String()
+	}
+	var articles []*types.Article
+	for _, article := range channel.Articles {
+		if c.expired(article) {
+			summary.Expired++
+			continue
+		}
+		articles = append(articles, article)
+	}
+	if c.maxIngest > 0 && len(articles) > c.maxIngest {
+		summary.Capped = len(articles) - c.maxIngest
+		articles = newest(articles, c.maxIngest)
 	}
 	for _, article := range articles {
 		article.FeedID = feed.ID
-		_, err := c.store.Create(article)
+		article.Provider = feed.Provider
+		if feed.ClonedFrom != "" {
+			article.Scope = feed.ID
+		}
+		article.BatchID = summary.BatchID
+		article, err := c.process(article)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("could not process article: %v", err)
 		}
+		article = c.limitPublishDate(article)
+		if article == nil {
+			continue
+		}
+		if force {
+			err = c.upsert(article, summary)
+		} else {
+			err = c.create(article, summary)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return summary, nil
+}
+
+// create stores the article unless already present, recording the outcome in the summary.
+func (c *FeedConsumer) create(article *types.Article, summary *types.LoadSummary) error {
+	stored, err := c.store.Create(article)
+	if err != nil {
+		return err
+	}
+	// The store returns the existing article, discarding the provided one, when already present, or
+	// nil when the article was deleted or evicted right away.
+	switch stored {
+	case nil:
+		summary.Deleted++
+	case article:
+		summary.Created++
+	default:
+		summary.Unchanged++
+	}
+	return nil
+}
+
+// upsert stores or updates the article, recording the outcome in the summary.
+func (c *FeedConsumer) upsert(article *types.Article, summary *types.LoadSummary) error {
+	stored, diff, err := c.store.Upsert(article)
+	if err != nil {
+		return err
+	}
+	switch {
+	case stored == nil:
+		// The store returns nil for articles that were deleted or evicted right away.
+		summary.Deleted++
+	case diff.Created:
+		summary.Created++
+	case diff.Changed():
+		summary.Updated++
+		summary.Changes[stored.ID] = diff.Fields
+	default:
+		summary.Unchanged++
 	}
 	return nil
 }
 
+// read loads the channel from the first address of the feed that succeeds, returning it along with
+// the address it was read from. Feeds that were never loaded are read paginating, so their history
+// is ingested on the first load.
+func (c *FeedConsumer) read(feed *types.Feed) (*types.Channel, string, error) {
+	opts := feed.ReadOptions()
+	opts.Paginate = feed.Loads == 0
+	var err error
+	for _, address := range feed.Addresses() {
+		var channel *types.Channel
+		channel, err = c.feed.Read(address, opts)
+		if err == nil {
+			return channel, address, nil
+		}
+	}
+	return nil, "", err
+}
+
+// process runs the article through the configured processors chain.
+func (c *FeedConsumer) process(article *types.Article) (*types.Article, error) {
+	for _, p := range c.processors {
+		var err error
+		article, err = p.Process(article)
+		if err != nil || article == nil {
+			return nil, err
+		}
+	}
+	return article, nil
+}
+
+// limitPublishDate applies the future dates policy to the article, returning nil if it must be
+// discarded.
+func (c *FeedConsumer) limitPublishDate(article *types.Article) *types.Article {
+	if !c.limitFuture || article == nil {
+		return article
+	}
+	now := c.clock.Now()
+	if !article.PublishDate.After(now.Add(c.futureTolerance)) {
+		return article
+	}
+	if c.futurePolicy == DropFuture {
+		return nil
+	}
+	article.PublishDate = now
+	return article
+}
+
+// newest returns the n newest articles by publish date, keeping the order they were provided in.
+func newest(articles []*types.Article, n int) []*types.Article {
+	sorted := append([]*types.Article(nil), articles...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PublishDate.After(sorted[j].PublishDate)
+	})
+	keep := make(map[*types.Article]bool, n)
+	for _, article := range sorted[:n] {
+		keep[article] = true
+	}
+	res := make([]*types.Article, 0, n)
+	for _, article := range articles {
+		if keep[article] {
+			res = append(res, article)
+		}
+	}
+	return res
+}
+
+// expired returns whether the article was published longer ago than the maximum article age.
+// Articles without a publish date never expire.
+func (c *FeedConsumer) expired(article *types.Article) bool {
+	if c.maxArticleAge <= 0 || article.PublishDate.IsZero() {
+		return false
+	}
+	return article.PublishDate.Before(c.clock.Now().Add(-c.maxArticleAge))
+}
+
 // NewFeedConsumer returns a new FeedConsumer providing functionality to gather news/articles from
 // the provided feed and saving them in the provided store.
-func NewFeedConsumer(feed Feed, store ArticleStore) *FeedConsumer {
-	return &FeedConsumer{
-		feed:  feed,
-		store: store,
+func NewFeedConsumer(feed Feed, store ArticleStore, opts ...Option) *FeedConsumer {
+	c := &FeedConsumer{
+		feed:     feed,
+		store:    store,
+		clock:    clock.New(),
+		inFlight: map[loadKey]*load{},
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
@@ -2,12 +2,16 @@ package feedconsumer
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"../clock"
 	"../types"
 )
 
@@ -15,12 +19,12 @@ type MockFeed struct {
 	mock.Mock
 }
 
-func (mf *MockFeed) Load(address string) ([]*types.Article, error) {
-	args := mf.Called(address)
+func (mf *MockFeed) Read(address string, opts types.ReadOptions) (*types.Channel, error) {
+	args := mf.Called(address, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]*types.Article), args.Error(1)
+	return args.Get(0).(*types.Channel), args.Error(1)
 }
 
 type MockArticleStore struct {
@@ -35,26 +39,73 @@ func (mas *MockArticleStore) Create(article *types.Article) (*types.Article, err
 	return args.Get(0).(*types.Article), args.Error(1)
 }
 
+func (mas *MockArticleStore) Upsert(article *types.Article) (*types.Article, *types.ArticleDiff, error) {
+	args := mas.Called(article)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*types.Article), args.Get(1).(*types.ArticleDiff), args.Error(2)
+}
+
+type MockFeedStore struct {
+	mock.Mock
+}
+
+func (mfs *MockFeedStore) UpdateAddress(ID string, address string) (*types.Feed, error) {
+	args := mfs.Called(ID, address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Feed), args.Error(1)
+}
+
+func (mfs *MockFeedStore) UpdateCategory(ID string, category string) (*types.Feed, error) {
+	args := mfs.Called(ID, category)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Feed), args.Error(1)
+}
+
+// ArticleProcessorFunc adapts a function into an ArticleProcessor.
+type ArticleProcessorFunc func(article *types.Article) (*types.Article, error)
+
+func (f ArticleProcessorFunc) Process(article *types.Article) (*types.Article, error) {
+	return f(article)
+}
+
 func TestConsume(t *testing.T) {
 	t.Run("bypasses feed loading error", func(t *testing.T) {
 		r := require.New(t)
 		a := assert.New(t)
 		mockFeed := &MockFeed{}
-		mockFeed.On("Load", "address").Return(nil, errors.New("random error"))
+		mockFeed.On("Read", "address", mock.Anything).Return(nil, errors.New("random error"))
 		feedConsumer := NewFeedConsumer(mockFeed, nil)
-		err := feedConsumer.Consume(&types.Feed{Address: "address"})
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
 		r.Error(err)
 		a.Contains(err.Error(), "random error")
 		mockFeed.AssertExpectations(t)
 	})
 
+	t.Run("feed loading errors can be inspected", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		errEmpty := errors.New("empty feed")
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(nil, errEmpty)
+		feedConsumer := NewFeedConsumer(mockFeed, nil)
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.Error(err)
+		a.True(errors.Is(err, errEmpty))
+	})
+
 	t.Run("return nil if no articles are fetched", func(t *testing.T) {
 		r := require.New(t)
 		mockFeed := &MockFeed{}
-		mockFeed.On("Load", "address").Return(nil, nil)
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{}, nil)
 		mockArticleStore := &MockArticleStore{}
 		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
-		err := feedConsumer.Consume(&types.Feed{Address: "address"})
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
 		r.Nil(err)
 		mockFeed.AssertExpectations(t)
 	})
@@ -66,11 +117,11 @@ func TestConsume(t *testing.T) {
 		articlesToReturn := []*types.Article{
 			&types.Article{},
 		}
-		mockFeed.On("Load", "address").Return(articlesToReturn, nil)
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: articlesToReturn}, nil)
 		mockArticleStore := &MockArticleStore{}
 		mockArticleStore.On("Create", articlesToReturn[0]).Return(nil, errors.New("random error"))
 		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
-		err := feedConsumer.Consume(&types.Feed{Address: "address"})
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
 		r.Error(err)
 		a.Contains(err.Error(), "random error")
 		mockFeed.AssertExpectations(t)
@@ -86,11 +137,11 @@ func TestConsume(t *testing.T) {
 			ID:   "generated_uuid",
 			GUID: "test_guid",
 		}
-		mockFeed.On("Load", "address").Return(articlesToReturn, nil)
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: articlesToReturn}, nil)
 		mockArticleStore := &MockArticleStore{}
 		mockArticleStore.On("Create", articlesToReturn[0]).Return(savedArticle, nil)
 		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
-		err := feedConsumer.Consume(&types.Feed{Address: "address"})
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
 		r.NoError(err)
 		mockFeed.AssertExpectations(t)
 		mockArticleStore.AssertExpectations(t)
@@ -107,15 +158,634 @@ func TestConsume(t *testing.T) {
 			ID:   "generated_uuid",
 			GUID: "test_guid",
 		}
-		mockFeed.On("Load", "address").Return(articlesToReturn, nil)
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: articlesToReturn}, nil)
 		mockArticleStore := &MockArticleStore{}
 		mockArticleStore.On("Create", articlesToReturn[0]).Return(savedArticle, nil)
 		mockArticleStore.On("Create", articlesToReturn[1]).Return(savedArticle, nil)
 		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
-		err := feedConsumer.Consume(&types.Feed{Address: "address"})
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
 		r.NoError(err)
 		mockFeed.AssertExpectations(t)
 		mockArticleStore.AssertExpectations(t)
 	})
 
+	t.Run("loads articles from fallback when primary fails", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		fallbackArticles := []*types.Article{
+			&types.Article{GUID: "fallback_guid"},
+		}
+		mockFeed.On("Read", "primary", mock.Anything).Return(nil, errors.New("random error"))
+		mockFeed.On("Read", "fallback", mock.Anything).Return(&types.Channel{Articles: fallbackArticles}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", fallbackArticles[0]).Return(fallbackArticles[0], nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+		_, err := feedConsumer.Consume(&types.Feed{
+			ID:        "feed_id",
+			Provider:  "provider",
+			Address:   "primary",
+			Fallbacks: []string{"fallback"},
+		}, false)
+		r.NoError(err)
+		a.Equal("feed_id", fallbackArticles[0].FeedID)
+		a.Equal("provider", fallbackArticles[0].Provider)
+		mockFeed.AssertExpectations(t)
+		mockArticleStore.AssertExpectations(t)
+	})
+
+	t.Run("stops trying fallbacks once one succeeds", func(t *testing.T) {
+		r := require.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "primary", mock.Anything).Return(nil, errors.New("random error"))
+		mockFeed.On("Read", "fallback", mock.Anything).Return(&types.Channel{}, nil)
+		mockArticleStore := &MockArticleStore{}
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+		_, err := feedConsumer.Consume(&types.Feed{
+			Address:   "primary",
+			Fallbacks: []string{"fallback", "other_fallback"},
+		}, false)
+		r.NoError(err)
+		mockFeed.AssertExpectations(t)
+		mockFeed.AssertNotCalled(t, "Read", "other_fallback", mock.Anything)
+	})
+
+	t.Run("errors if all addresses fail", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "primary", mock.Anything).Return(nil, errors.New("primary error"))
+		mockFeed.On("Read", "fallback", mock.Anything).Return(nil, errors.New("fallback error"))
+		feedConsumer := NewFeedConsumer(mockFeed, nil)
+		_, err := feedConsumer.Consume(&types.Feed{
+			Address:   "primary",
+			Fallbacks: []string{"fallback"},
+		}, false)
+		r.Error(err)
+		a.Contains(err.Error(), "fallback error")
+		mockFeed.AssertExpectations(t)
+	})
+}
+
+func TestConsumeWithProcessors(t *testing.T) {
+	upperTitle := ArticleProcessorFunc(func(article *types.Article) (*types.Article, error) {
+		article.Title = strings.ToUpper(article.Title)
+		return article, nil
+	})
+	suffixTitle := ArticleProcessorFunc(func(article *types.Article) (*types.Article, error) {
+		article.Title += "_processed"
+		return article, nil
+	})
+
+	t.Run("stores the value returned by the processor", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		article := &types.Article{GUID: "test_guid", Title: "title"}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{article}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", article).Return(article, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore, WithProcessors(upperTitle))
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		stored := mockArticleStore.Calls[0].Arguments.Get(0).(*types.Article)
+		a.Equal("TITLE", stored.Title)
+		mockArticleStore.AssertExpectations(t)
+	})
+
+	t.Run("chains processors in order", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		article := &types.Article{GUID: "test_guid", Title: "title"}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{article}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", article).Return(article, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore, WithProcessors(upperTitle, suffixTitle))
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		stored := mockArticleStore.Calls[0].Arguments.Get(0).(*types.Article)
+		a.Equal("TITLE_processed", stored.Title)
+	})
+
+	t.Run("discards articles when a processor returns nil", func(t *testing.T) {
+		r := require.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{&types.Article{}}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		discard := ArticleProcessorFunc(func(article *types.Article) (*types.Article, error) {
+			return nil, nil
+		})
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore, WithProcessors(discard, upperTitle))
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		mockArticleStore.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("bypasses processor error", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{&types.Article{}}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		failing := ArticleProcessorFunc(func(article *types.Article) (*types.Article, error) {
+			return nil, errors.New("random error")
+		})
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore, WithProcessors(failing))
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.Error(err)
+		a.Contains(err.Error(), "random error")
+		mockArticleStore.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestConsumeMovedFeed(t *testing.T) {
+	t.Run("updates the address of a permanently moved feed", func(t *testing.T) {
+		r := require.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Address: "new_address", Moved: true}, nil)
+		mockFeedStore := &MockFeedStore{}
+		mockFeedStore.On("UpdateAddress", "feed_id", "new_address").Return(&types.Feed{}, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{}, WithFeedStore(mockFeedStore))
+		_, err := feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address"}, false)
+		r.NoError(err)
+		mockFeedStore.AssertExpectations(t)
+	})
+
+	t.Run("sets the category of a feed without one from the channel title", func(t *testing.T) {
+		r := require.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Title: " Channel ", Address: "address"}, nil)
+		mockFeedStore := &MockFeedStore{}
+		mockFeedStore.On("UpdateCategory", "feed_id", "Channel").Return(&types.Feed{}, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{}, WithFeedStore(mockFeedStore))
+		_, err := feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address"}, false)
+		r.NoError(err)
+		mockFeedStore.AssertExpectations(t)
+
+		_, err = feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address", Category: "world"}, false)
+		r.NoError(err)
+		mockFeedStore.AssertNumberOfCalls(t, "UpdateCategory", 1)
+	})
+
+	t.Run("keeps the address of a temporarily moved feed", func(t *testing.T) {
+		r := require.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Address: "new_address"}, nil)
+		mockFeedStore := &MockFeedStore{}
+		feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{}, WithFeedStore(mockFeedStore))
+		_, err := feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address"}, false)
+		r.NoError(err)
+		mockFeedStore.AssertNotCalled(t, "UpdateAddress", mock.Anything, mock.Anything)
+	})
+
+	t.Run("keeps the address when a fallback moved", func(t *testing.T) {
+		r := require.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(nil, errors.New("random error"))
+		mockFeed.On("Read", "fallback", mock.Anything).Return(&types.Channel{Address: "new_address", Moved: true}, nil)
+		mockFeedStore := &MockFeedStore{}
+		feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{}, WithFeedStore(mockFeedStore))
+		_, err := feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address", Fallbacks: []string{"fallback"}}, false)
+		r.NoError(err)
+		mockFeedStore.AssertNotCalled(t, "UpdateAddress", mock.Anything, mock.Anything)
+	})
+
+	t.Run("bypasses address update error", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Address: "new_address", Moved: true}, nil)
+		mockFeedStore := &MockFeedStore{}
+		mockFeedStore.On("UpdateAddress", "feed_id", "new_address").Return(nil, errors.New("random error"))
+		feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{}, WithFeedStore(mockFeedStore))
+		_, err := feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address"}, false)
+		r.Error(err)
+		a.Contains(err.Error(), "random error")
+	})
+}
+
+func TestConsumeForce(t *testing.T) {
+	t.Run("upserts articles when forced", func(t *testing.T) {
+		r := require.New(t)
+		mockFeed := &MockFeed{}
+		article := &types.Article{GUID: "test_guid"}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{article}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Upsert", article).Return(article, &types.ArticleDiff{Created: true}, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, true)
+		r.NoError(err)
+		mockArticleStore.AssertExpectations(t)
+		mockArticleStore.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("bypasses upsert error", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		article := &types.Article{GUID: "test_guid"}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{article}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Upsert", article).Return(nil, nil, errors.New("random error"))
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, true)
+		r.Error(err)
+		a.Contains(err.Error(), "random error")
+	})
+}
+
+func TestIngest(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	mockFeed := &MockFeed{}
+	article := &types.Article{GUID: "test_guid"}
+	mockArticleStore := &MockArticleStore{}
+	mockArticleStore.On("Upsert", article).Return(article, &types.ArticleDiff{Created: true}, nil)
+	feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+	summary, err := feedConsumer.Ingest(&types.Feed{ID: "feed_id", Provider: "provider", Address: "address"},
+		&types.Channel{Articles: []*types.Article{article}})
+	r.NoError(err)
+	a.Equal(1, summary.Created)
+	a.Equal("feed_id", article.FeedID)
+	a.Equal("provider", article.Provider)
+	a.Empty(article.Scope)
+	mockArticleStore.AssertExpectations(t)
+	mockFeed.AssertNotCalled(t, "Read", mock.Anything, mock.Anything)
+}
+
+func TestIngestClone(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	article := &types.Article{GUID: "test_guid"}
+	mockArticleStore := &MockArticleStore{}
+	mockArticleStore.On("Upsert", article).Return(article, &types.ArticleDiff{Created: true}, nil)
+	feedConsumer := NewFeedConsumer(&MockFeed{}, mockArticleStore)
+	_, err := feedConsumer.Ingest(&types.Feed{ID: "clone_id", Address: "address", ClonedFrom: "feed_id"},
+		&types.Channel{Articles: []*types.Article{article}})
+	r.NoError(err)
+	a.Equal("clone_id", article.FeedID)
+	a.Equal("clone_id", article.Scope)
+	mockArticleStore.AssertExpectations(t)
+}
+
+func TestConsumeSummary(t *testing.T) {
+	t.Run("summarizes created and existing articles", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		created := &types.Article{GUID: "created"}
+		existing := &types.Article{GUID: "existing"}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{created, existing}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", created).Return(created, nil)
+		mockArticleStore.On("Create", existing).Return(&types.Article{ID: "existing_id", GUID: "existing"}, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(1, summary.Created)
+		a.Equal(0, summary.Updated)
+		a.Equal(1, summary.Unchanged)
+		a.Empty(summary.Changes)
+	})
+
+	t.Run("summarizes updated and unchanged articles when forced", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		created := &types.Article{GUID: "created"}
+		updated := &types.Article{GUID: "updated"}
+		unchanged := &types.Article{GUID: "unchanged"}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{created, updated, unchanged}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Upsert", created).Return(created, &types.ArticleDiff{Created: true}, nil)
+		mockArticleStore.On("Upsert", updated).Return(&types.Article{ID: "updated_id"}, &types.ArticleDiff{Fields: []string{"Title"}}, nil)
+		mockArticleStore.On("Upsert", unchanged).Return(&types.Article{ID: "unchanged_id"}, &types.ArticleDiff{}, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, true)
+		r.NoError(err)
+		a.Equal(1, summary.Created)
+		a.Equal(1, summary.Updated)
+		a.Equal(1, summary.Unchanged)
+		a.Equal(map[string][]string{"updated_id": []string{"Title"}}, summary.Changes)
+	})
+
+	t.Run("reports items skipped by the reader", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Skipped: 2}, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{})
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(2, summary.Skipped)
+	})
+}
+
+func TestConsumeFutureTolerance(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("keeps future articles when disabled", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		article := &types.Article{GUID: "future", PublishDate: now.Add(time.Hour)}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{article}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", article).Return(article, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore, WithClock(clock.NewFake(now)))
+		_, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(now.Add(time.Hour), article.PublishDate)
+	})
+
+	t.Run("clamps articles beyond the tolerance", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		future := &types.Article{GUID: "future", PublishDate: now.Add(time.Hour)}
+		tolerated := &types.Article{GUID: "tolerated", PublishDate: now.Add(time.Minute)}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{future, tolerated}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", future).Return(future, nil)
+		mockArticleStore.On("Create", tolerated).Return(tolerated, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore,
+			WithClock(clock.NewFake(now)),
+			WithFutureTolerance(5*time.Minute, ClampFuture),
+		)
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(2, summary.Created)
+		a.Equal(now, future.PublishDate)
+		a.Equal(now.Add(time.Minute), tolerated.PublishDate)
+	})
+
+	t.Run("drops articles beyond the tolerance", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		future := &types.Article{GUID: "future", PublishDate: now.Add(time.Hour)}
+		tolerated := &types.Article{GUID: "tolerated", PublishDate: now.Add(time.Minute)}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{future, tolerated}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", tolerated).Return(tolerated, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore,
+			WithClock(clock.NewFake(now)),
+			WithFutureTolerance(5*time.Minute, DropFuture),
+		)
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(1, summary.Created)
+		mockArticleStore.AssertNotCalled(t, "Create", future)
+	})
+}
+
+func TestConsumeMaxArticleAge(t *testing.T) {
+	now := time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	t.Run("discards articles older than the maximum age", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		old := &types.Article{GUID: "old", PublishDate: now.Add(-31 * 24 * time.Hour)}
+		recent := &types.Article{GUID: "recent", PublishDate: now.Add(-24 * time.Hour)}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{old, recent}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", recent).Return(recent, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore,
+			WithClock(clock.NewFake(now)),
+			WithMaxArticleAge(30*24*time.Hour),
+		)
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(1, summary.Created)
+		a.Equal(1, summary.Expired)
+		mockArticleStore.AssertNotCalled(t, "Create", old)
+	})
+
+	t.Run("keeps old articles by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		old := &types.Article{GUID: "old", PublishDate: now.Add(-365 * 24 * time.Hour)}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: []*types.Article{old}}, nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", old).Return(old, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore, WithClock(clock.NewFake(now)))
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(1, summary.Created)
+		a.Equal(0, summary.Expired)
+	})
+}
+
+func TestConsumeMaxIngest(t *testing.T) {
+	now := time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC)
+	var articles []*types.Article
+	for i := 0; i < 5; i++ {
+		articles = append(articles, &types.Article{GUID: fmt.Sprintf("guid_%d", i), PublishDate: now.Add(time.Duration(i%3) * time.Hour)})
+	}
+
+	t.Run("stores only the newest articles", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: articles}, nil)
+		mockArticleStore := &MockArticleStore{}
+		// guid_2 is the newest, followed by guid_1 and guid_4 published at the same time.
+		for _, i := range []int{1, 2, 4} {
+			mockArticleStore.On("Create", articles[i]).Return(articles[i], nil)
+		}
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore, WithMaxIngest(3))
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(3, summary.Created)
+		a.Equal(2, summary.Capped)
+		mockArticleStore.AssertNotCalled(t, "Create", articles[0])
+		mockArticleStore.AssertNotCalled(t, "Create", articles[3])
+	})
+
+	t.Run("stores all articles by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{Articles: articles}, nil)
+		mockArticleStore := &MockArticleStore{}
+		for _, article := range articles {
+			mockArticleStore.On("Create", article).Return(article, nil)
+		}
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+		summary, err := feedConsumer.Consume(&types.Feed{Address: "address"}, false)
+		r.NoError(err)
+		a.Equal(5, summary.Created)
+		a.Zero(summary.Capped)
+	})
+}
+
+func TestConsumeReadOptions(t *testing.T) {
+	r := require.New(t)
+	mockFeed := &MockFeed{}
+	credentials := &types.Credentials{Username: "user", Password: "pass"}
+	// The feed was never loaded, so it is read paginating.
+	opts := types.ReadOptions{Credentials: credentials, Timeout: time.Second, Paginate: true}
+	mockFeed.On("Read", "primary", opts).Return(nil, errors.New("random error"))
+	mockFeed.On("Read", "fallback", opts).Return(&types.Channel{}, nil)
+	feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{})
+	_, err := feedConsumer.Consume(&types.Feed{
+		Address:     "primary",
+		Fallbacks:   []string{"fallback"},
+		Credentials: credentials,
+		Timeout:     time.Second,
+	}, false)
+	r.NoError(err)
+	mockFeed.AssertExpectations(t)
+}
+
+func TestConsumePaginatesFirstLoad(t *testing.T) {
+	for name, tc := range map[string]struct {
+		loads    int
+		paginate bool
+	}{
+		"first load":  {loads: 0, paginate: true},
+		"later loads": {loads: 3, paginate: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			mockFeed := &MockFeed{}
+			mockFeed.On("Read", "address", types.ReadOptions{Paginate: tc.paginate}).Return(&types.Channel{}, nil)
+			feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{})
+			_, err := feedConsumer.Consume(&types.Feed{Address: "address", Loads: tc.loads}, false)
+			r.NoError(err)
+			mockFeed.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConsumeConcurrent(t *testing.T) {
+	t.Run("concurrent loads of a feed read it once", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		release := make(chan time.Time)
+		mockFeed := &MockFeed{}
+		articlesToReturn := []*types.Article{&types.Article{GUID: "test_guid"}}
+		mockFeed.On("Read", "address", mock.Anything).WaitUntil(release).
+			Return(&types.Channel{Articles: articlesToReturn}, nil).Once()
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", articlesToReturn[0]).Return(articlesToReturn[0], nil).Once()
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore)
+		feed := &types.Feed{ID: "feed_id", Address: "address"}
+
+		summaries := make(chan *types.LoadSummary, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				summary, err := feedConsumer.Consume(feed, false)
+				a.NoError(err)
+				summaries <- summary
+			}()
+		}
+		// The feed is only released once a load is reading it, giving the other one time to wait for it.
+		r.Eventually(func() bool {
+			feedConsumer.mu.Lock()
+			defer feedConsumer.mu.Unlock()
+			_, ok := feedConsumer.inFlight[loadKey{feedID: "feed_id"}]
+			return ok
+		}, time.Second, time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+
+		first, second := <-summaries, <-summaries
+		a.Same(first, second)
+		a.Equal(1, first.Created)
+		mockFeed.AssertNumberOfCalls(t, "Read", 1)
+		mockArticleStore.AssertExpectations(t)
+	})
+
+	t.Run("later loads read the feed again", func(t *testing.T) {
+		r := require.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{}, nil)
+		feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{})
+		feed := &types.Feed{ID: "feed_id", Address: "address"}
+		_, err := feedConsumer.Consume(feed, false)
+		r.NoError(err)
+		_, err = feedConsumer.Consume(feed, false)
+		r.NoError(err)
+		mockFeed.AssertNumberOfCalls(t, "Read", 2)
+	})
+}
+
+type MockLoadRecorder struct {
+	mock.Mock
+}
+
+func (mlr *MockLoadRecorder) RecordLoad(ID string, success bool) (*types.Feed, error) {
+	args := mlr.Called(ID, success)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Feed), args.Error(1)
+}
+
+func TestConsumeRecordsLoads(t *testing.T) {
+	r := require.New(t)
+	mockFeed := &MockFeed{}
+	mockFeed.On("Read", "address", mock.Anything).Return(&types.Channel{}, nil)
+	mockFeed.On("Read", "broken", mock.Anything).Return(nil, errors.New("random error"))
+	mockRecorder := &MockLoadRecorder{}
+	mockRecorder.On("RecordLoad", "feed_id", true).Return(nil, errors.New("random error")).Once()
+	mockRecorder.On("RecordLoad", "broken_id", false).Return(&types.Feed{}, nil).Once()
+	feedConsumer := NewFeedConsumer(mockFeed, &MockArticleStore{}, WithLoadRecorder(mockRecorder))
+
+	_, err := feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address"}, false)
+	r.NoError(err, "recording errors must not fail the load")
+	_, err = feedConsumer.Consume(&types.Feed{ID: "broken_id", Address: "broken"}, false)
+	r.Error(err)
+	mockRecorder.AssertExpectations(t)
+}
+
+func TestConsumeBatchIDs(t *testing.T) {
+	newChannel := func() *types.Channel {
+		return &types.Channel{Articles: []*types.Article{{GUID: "guid_1"}, {GUID: "guid_2"}}}
+	}
+
+	t.Run("tags the articles of each load with its batch ID", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(newChannel(), nil).Once()
+		mockFeed.On("Read", "address", mock.Anything).Return(newChannel(), nil).Once()
+		var stored []*types.Article
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", mock.Anything).Return(nil, nil).Run(func(args mock.Arguments) {
+			stored = append(stored, args.Get(0).(*types.Article))
+		})
+		feedConsumer := NewFeedConsumer(mockFeed, mockArticleStore, WithBatchIDs(true))
+
+		first, err := feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address"}, false)
+		r.NoError(err)
+		second, err := feedConsumer.Consume(&types.Feed{ID: "feed_id", Address: "address"}, false)
+		r.NoError(err)
+		a.NotEmpty(first.BatchID)
+		a.NotEqual(first.BatchID, second.BatchID)
+		r.Len(stored, 4, "unexpected number of articles")
+		a.Equal(first.BatchID, stored[0].BatchID)
+		a.Equal(first.BatchID, stored[1].BatchID)
+		a.Equal(second.BatchID, stored[2].BatchID)
+		a.Equal(second.BatchID, stored[3].BatchID)
+	})
+
+	t.Run("doesn't tag articles by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mockFeed := &MockFeed{}
+		mockFeed.On("Read", "address", mock.Anything).Return(newChannel(), nil)
+		mockArticleStore := &MockArticleStore{}
+		mockArticleStore.On("Create", mock.Anything).Return(nil, nil)
+		summary, err := NewFeedConsumer(mockFeed, mockArticleStore).Consume(&types.Feed{ID: "feed_id", Address: "address"}, false)
+		r.NoError(err)
+		a.Empty(summary.BatchID)
+		for _, call := range mockArticleStore.Calls {
+			a.Empty(call.Arguments.Get(0).(*types.Article).BatchID)
+		}
+	})
 }
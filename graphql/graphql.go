@@ -0,0 +1,112 @@
+// Package graphql provides a lightweight implementation of the GraphQL query language, enough for
+// exposing read-only data through a schema of objects and resolver functions.
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Object describes a type whose fields can be selected in a query.
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field describes a selectable field of an object. Type is the object type of the resolved value,
+// or of its elements when it is a slice, and must be nil for scalar values.
+type Field struct {
+	Type    *Object
+	Resolve func(source interface{}, args map[string]interface{}) (interface{}, error)
+}
+
+// Execute runs the provided query against the root object and returns the selected data. Variables
+// referenced by the query are replaced with the provided values, or their declared defaults.
+// Syntax errors are reported as *SyntaxError.
+func Execute(root *Object, query string, variables map[string]interface{}) (map[string]interface{}, error) {
+	doc, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]interface{}{}
+	for _, def := range doc.variables {
+		if v, ok := variables[def.name]; ok {
+			vars[def.name] = v
+		} else if def.hasDefault {
+			vars[def.name] = def.defaultVal
+		}
+	}
+	return executeSelections(root, nil, doc.selections, vars)
+}
+
+func executeSelections(obj *Object, source interface{}, selections []*selection, vars map[string]interface{}) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	for _, sel := range selections {
+		field, ok := obj.Fields[sel.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q on %s", sel.name, obj.Name)
+		}
+		args := map[string]interface{}{}
+		for k, arg := range sel.args {
+			args[k] = resolveVariables(arg, vars)
+		}
+		v, err := field.Resolve(source, args)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %q: %v", sel.name, err)
+		}
+		if res[sel.alias], err = complete(field, sel, v, vars); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// complete applies the sub-selections of the field to the resolved value.
+func complete(field *Field, sel *selection, v interface{}, vars map[string]interface{}) (interface{}, error) {
+	if field.Type == nil {
+		if sel.selections != nil {
+			return nil, fmt.Errorf("field %q can't have a selection", sel.name)
+		}
+		return v, nil
+	}
+	if sel.selections == nil {
+		return nil, fmt.Errorf("field %q of type %s must have a selection", sel.name, field.Type.Name)
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return nil, nil
+	}
+	if rv.Kind() != reflect.Slice {
+		return executeSelections(field.Type, v, sel.selections, vars)
+	}
+	list := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		item, err := executeSelections(field.Type, rv.Index(i).Interface(), sel.selections, vars)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// resolveVariables replaces the variable references in the provided value with their values.
+func resolveVariables(v interface{}, vars map[string]interface{}) interface{} {
+	switch val := v.(type) {
+	case variable:
+		return vars[string(val)]
+	case []interface{}:
+		list := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			list = append(list, resolveVariables(item, vars))
+		}
+		return list
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			obj[k] = resolveVariables(item, vars)
+		}
+		return obj
+	}
+	return v
+}
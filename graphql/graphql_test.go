@@ -0,0 +1,141 @@
+package graphql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	name string
+	tags []string
+}
+
+func testSchema() *Object {
+	itemObject := &Object{
+		Name: "Item",
+		Fields: map[string]*Field{
+			"name": &Field{Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+				return source.(*item).name, nil
+			}},
+			"tags": &Field{Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+				return source.(*item).tags, nil
+			}},
+		},
+	}
+	return &Object{
+		Name: "Query",
+		Fields: map[string]*Field{
+			"items": &Field{
+				Type: itemObject,
+				Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+					items := []*item{{name: "first", tags: []string{"a"}}, {name: "second"}}
+					if n, ok := args["limit"].(int); ok && n < len(items) {
+						items = items[:n]
+					}
+					return items, nil
+				},
+			},
+			"echo": &Field{Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+				return args["value"], nil
+			}},
+			"fail": &Field{Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+				return nil, errors.New("random error")
+			}},
+		},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	t.Run("selects nested fields of lists", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		data, err := Execute(testSchema(), `{ items { name tags } }`, nil)
+		r.NoError(err)
+		a.Equal(map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"name": "first", "tags": []string{"a"}},
+				map[string]interface{}{"name": "second", "tags": []string(nil)},
+			},
+		}, data)
+	})
+
+	t.Run("supports aliases and arguments", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		data, err := Execute(testSchema(), `{ one: items(limit: 1) { name }, echo(value: {a: [1, 2.5, "s", true, null]}) }`, nil)
+		r.NoError(err)
+		a.Equal(map[string]interface{}{
+			"one":  []interface{}{map[string]interface{}{"name": "first"}},
+			"echo": map[string]interface{}{"a": []interface{}{1, 2.5, "s", true, nil}},
+		}, data)
+	})
+
+	t.Run("resolves variables and their defaults", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		query := `query Echo($value: String!, $other: Int = 3) { value: echo(value: $value) other: echo(value: $other) }`
+		data, err := Execute(testSchema(), query, map[string]interface{}{"value": "v"})
+		r.NoError(err)
+		a.Equal(map[string]interface{}{"value": "v", "other": 3}, data)
+	})
+
+	t.Run("ignores comments", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		data, err := Execute(testSchema(), "{\n# comment\necho(value: \"v\") }", nil)
+		r.NoError(err)
+		a.Equal(map[string]interface{}{"echo": "v"}, data)
+	})
+
+	t.Run("reports syntax errors", func(t *testing.T) {
+		r := require.New(t)
+		for _, query := range []string{`{ items { name }`, `mutation { items }`, `{ echo(value: "v) }`, `{}`, `{ items } }`} {
+			_, err := Execute(testSchema(), query, nil)
+			r.Error(err, query)
+			var syntaxErr *SyntaxError
+			r.True(errors.As(err, &syntaxErr), query)
+		}
+	})
+
+	t.Run("reports queries nested too deeply", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		for _, query := range []string{
+			strings.Repeat("{ items ", 10000) + strings.Repeat("}", 10000),
+			`{ echo(value: ` + strings.Repeat("[", 10000) + `) }`,
+			`query ($v: ` + strings.Repeat("[", 10000) + `) { echo }`,
+		} {
+			_, err := Execute(testSchema(), query, nil)
+			r.Error(err)
+			var syntaxErr *SyntaxError
+			r.True(errors.As(err, &syntaxErr))
+			a.Contains(err.Error(), "nested deeper")
+		}
+	})
+
+	t.Run("reports invalid selections", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := Execute(testSchema(), `{ unknown }`, nil)
+		r.Error(err)
+		a.Contains(err.Error(), `unknown field "unknown" on Query`)
+		_, err = Execute(testSchema(), `{ items }`, nil)
+		r.Error(err)
+		a.Contains(err.Error(), "must have a selection")
+		_, err = Execute(testSchema(), `{ echo { name } }`, nil)
+		r.Error(err)
+		a.Contains(err.Error(), "can't have a selection")
+	})
+
+	t.Run("reports resolver errors", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := Execute(testSchema(), `{ fail }`, nil)
+		r.Error(err)
+		a.Contains(err.Error(), "random error")
+	})
+}
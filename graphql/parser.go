@@ -0,0 +1,414 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError is returned when a query can't be parsed.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// document holds a parsed query operation.
+type document struct {
+	variables  []*variableDefinition
+	selections []*selection
+}
+
+// variableDefinition holds a variable declared by an operation along with its default value.
+type variableDefinition struct {
+	name       string
+	defaultVal interface{}
+	hasDefault bool
+}
+
+// selection holds a field selected in a query.
+type selection struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	selections []*selection
+}
+
+// variable is a reference to an operation variable, resolved when the query is executed.
+type variable string
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenString
+	tokenNumber
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+// maxDepth is the maximum nesting of the selection sets, values and types of a query, which bounds
+// the recursion of the parser.
+const maxDepth = 32
+
+// parser is a recursive descent parser for the subset of the GraphQL query language supported:
+// a single query operation with variables, aliases, arguments and nested selections.
+type parser struct {
+	src   string
+	pos   int
+	tok   token
+	depth int
+}
+
+func parse(query string) (*document, error) {
+	p := &parser{src: query}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	doc := &document{}
+	if p.tok.kind == tokenName {
+		if p.tok.val != "query" {
+			return nil, p.errorf("unsupported operation %q", p.tok.val)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.isPunct("(") {
+			variables, err := p.parseVariableDefinitions()
+			if err != nil {
+				return nil, err
+			}
+			doc.variables = variables
+		}
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	doc.selections = selections
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %q after the operation", p.tok.val)
+	}
+	return doc, nil
+}
+
+func (p *parser) parseVariableDefinitions() ([]*variableDefinition, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var defs []*variableDefinition
+	for !p.isPunct(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if err := p.parseType(); err != nil {
+			return nil, err
+		}
+		def := &variableDefinition{name: name}
+		if p.isPunct("=") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if def.defaultVal, err = p.parseValue(); err != nil {
+				return nil, err
+			}
+			def.hasDefault = true
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.next()
+}
+
+// parseType skips a variable type, since values are not validated against their declared types.
+func (p *parser) parseType() error {
+	if err := p.enter(); err != nil {
+		return err
+	}
+	defer p.leave()
+	if p.isPunct("[") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.parseType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if p.isPunct("!") {
+		return p.next()
+	}
+	return nil
+}
+
+func (p *parser) parseSelectionSet() ([]*selection, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []*selection
+	for !p.isPunct("}") {
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return selections, p.next()
+}
+
+func (p *parser) parseSelection() (*selection, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	sel := &selection{alias: name, name: name}
+	if p.isPunct(":") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if sel.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isPunct("(") {
+		if sel.args, err = p.parseArguments(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isPunct("{") {
+		if sel.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *parser) parseArguments() (map[string]interface{}, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := map[string]interface{}{}
+	for !p.isPunct(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.parseValue(); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.next()
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	tok := p.tok
+	switch {
+	case p.isPunct("$"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		return variable(name), err
+	case p.isPunct("["):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.isPunct("]") {
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+	case p.isPunct("{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		obj := map[string]interface{}{}
+		for !p.isPunct("}") {
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.next()
+	case tok.kind == tokenString:
+		var s string
+		if err := json.Unmarshal([]byte(tok.val), &s); err != nil {
+			return nil, p.errorf("invalid string %s", tok.val)
+		}
+		return s, p.next()
+	case tok.kind == tokenNumber:
+		if n, err := strconv.Atoi(tok.val); err == nil {
+			return n, p.next()
+		}
+		f, err := strconv.ParseFloat(tok.val, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", tok.val)
+		}
+		return f, p.next()
+	case tok.kind == tokenName:
+		var v interface{}
+		switch tok.val {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			// Enum values are handled as plain strings.
+			v = tok.val
+		}
+		return v, p.next()
+	}
+	return nil, p.errorf("unexpected %q, expecting a value", tok.val)
+}
+
+// enter increases the nesting depth, erroring when it exceeds the maximum.
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return p.errorf("query nested deeper than %d levels", maxDepth)
+	}
+	return nil
+}
+
+// leave decreases the nesting depth.
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) isPunct(val string) bool {
+	return p.tok.kind == tokenPunct && p.tok.val == val
+}
+
+func (p *parser) expect(val string) error {
+	if !p.isPunct(val) {
+		return p.errorf("unexpected %q, expecting %q", p.tok.val, val)
+	}
+	return p.next()
+}
+
+func (p *parser) expectName() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.errorf("unexpected %q, expecting a name", p.tok.val)
+	}
+	name := p.tok.val
+	return name, p.next()
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// next reads the following token from the source, skipping whitespace, commas and comments.
+func (p *parser) next() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if c != ',' && !unicode.IsSpace(rune(c)) {
+			break
+		}
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokenEOF, pos: start}
+		return nil
+	}
+	c := p.src[p.pos]
+	switch {
+	case strings.IndexByte("{}()[]:!$=", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokenPunct, val: string(c), pos: start}
+	case c == '"':
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != '"' {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			return &SyntaxError{Pos: start, Msg: "unterminated string"}
+		}
+		p.pos++
+		p.tok = token{kind: tokenString, val: p.src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		p.pos++
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || strings.IndexByte(".eE+-", p.src[p.pos]) >= 0) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenNumber, val: p.src[start:p.pos], pos: start}
+	case isNameStart(c):
+		for p.pos < len(p.src) && (isNameStart(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenName, val: p.src[start:p.pos], pos: start}
+	default:
+		return &SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+	}
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -2,8 +2,15 @@
 package main
 
 import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"./feedconsumer"
 	"./rssreader"
+	"./rssreader/converters"
 	"./service"
 	"./store"
 )
@@ -11,11 +18,238 @@ import (
 const servicePort = 8052
 
 func main() {
-	feedStore := store.NewFeedStore()
-	articleStore := store.NewArticleStore()
-	feed := rssreader.NewFeed()
-	consumer := feedconsumer.NewFeedConsumer(feed, articleStore)
+	feedStore, articleStore, err := store.New(store.Config{
+		Backend: store.Backend(os.Getenv("ZNEWS_STORE")),
+		WALPath: os.Getenv("ZNEWS_WAL_PATH"),
+	},
+		store.WithCategoryNormalization(categoryNormalization(os.Getenv("ZNEWS_NORMALIZE_CATEGORIES"))),
+		store.WithIDScheme(idScheme(os.Getenv("ZNEWS_ARTICLE_IDS"))),
+		store.WithTombstoneRetention(time.Duration(envInt("ZNEWS_TOMBSTONE_RETENTION", 0))*time.Second),
+		store.WithCompareFields(compareFields(os.Getenv("ZNEWS_COMPARE_FIELDS"))...),
+		store.WithIDTieBreak(envBool("ZNEWS_ID_TIE_BREAK", false)),
+		store.WithEvictionPolicy(evictionPolicy(os.Getenv("ZNEWS_EVICTION"))),
+	)
+	if err != nil {
+		log.Fatalf("could not create stores: %v", err)
+	}
+	readerOpts := []rssreader.Option{
+		rssreader.WithMaxBodyLength(envInt("ZNEWS_MAX_BODY_LENGTH", 0)),
+		rssreader.WithMaxCategories(envInt("ZNEWS_MAX_CATEGORIES", 0)),
+		rssreader.WithInvalidItemPolicy(invalidItemPolicy(os.Getenv("ZNEWS_INVALID_ITEMS"))),
+		rssreader.WithDuplicateEnclosures(envBool("ZNEWS_KEEP_DUPLICATE_ENCLOSURES", false)),
+		rssreader.WithRetries(
+			envInt("ZNEWS_FEED_RETRIES", 0),
+			time.Duration(envInt("ZNEWS_FEED_RETRY_DELAY", 1))*time.Second,
+		),
+		rssreader.WithTimeout(time.Duration(envInt("ZNEWS_FEED_TIMEOUT", 30)) * time.Second),
+		rssreader.WithMaxPerHost(envInt("ZNEWS_FEED_MAX_PER_HOST", 0)),
+		rssreader.WithHostDelay(time.Duration(envInt("ZNEWS_FEED_HOST_DELAY", 0)) * time.Millisecond),
+		rssreader.WithRobots(envBool("ZNEWS_FEED_ROBOTS", false)),
+		rssreader.WithMaxPages(envInt("ZNEWS_FEED_MAX_PAGES", 0)),
+	}
+	if envBool("ZNEWS_STRIP_TRACKING_PARAMS", false) {
+		readerOpts = append(readerOpts, rssreader.WithStripParams(stripParams(os.Getenv("ZNEWS_TRACKING_PARAMS"))...))
+	}
+	feed := rssreader.NewFeed(readerOpts...)
+	consumerOpts := []feedconsumer.Option{
+		feedconsumer.WithFeedStore(feedStore),
+		feedconsumer.WithLoadRecorder(feedStore),
+		feedconsumer.WithBatchIDs(envBool("ZNEWS_BATCH_IDS", false)),
+		feedconsumer.WithMaxArticleAge(time.Duration(envInt("ZNEWS_MAX_ARTICLE_AGE", 0)) * time.Hour),
+		feedconsumer.WithMaxIngest(envInt("ZNEWS_MAX_INGEST", 0)),
+	}
+	if tolerance := envInt("ZNEWS_FUTURE_TOLERANCE", -1); tolerance >= 0 {
+		consumerOpts = append(consumerOpts, feedconsumer.WithFutureTolerance(
+			time.Duration(tolerance)*time.Second,
+			futurePolicy(os.Getenv("ZNEWS_FUTURE_POLICY")),
+		))
+	}
+	consumer := feedconsumer.NewFeedConsumer(feed, articleStore, consumerOpts...)
 
-	s := service.NewService(consumer, feedStore, articleStore)
+	s := service.NewService(consumer, feed, feedStore, articleStore,
+		service.WithDefaultPageSize(envInt("ZNEWS_DEFAULT_PAGE_SIZE", 20)),
+		service.WithMaxCategoryFilters(envInt("ZNEWS_MAX_CATEGORY_FILTERS", 50)),
+		service.WithAdminToken(os.Getenv("ZNEWS_ADMIN_TOKEN")),
+		service.WithMaxFullTextLength(envInt("ZNEWS_MAX_BODY_LENGTH", 0)),
+		service.WithContentRefresh(
+			envInt("ZNEWS_CONTENT_REFRESH_CONCURRENCY", 2),
+			time.Duration(envInt("ZNEWS_CONTENT_REFRESH_TIMEOUT", 30))*time.Second,
+		),
+		service.WithLoadQueue(envInt("ZNEWS_LOAD_QUEUE_SIZE", 0), envInt("ZNEWS_LOAD_WORKERS", 1)),
+		service.WithHTTPSOnly(envBool("ZNEWS_HTTPS_ONLY", false)),
+		service.WithBaseURL(os.Getenv("ZNEWS_BASE_URL")),
+		service.WithCacheControl(cacheControl(os.Getenv("ZNEWS_CACHE_CONTROL"))),
+		service.WithRefreshInterval(
+			time.Duration(envInt("ZNEWS_REFRESH_INTERVAL", 0))*time.Second,
+			time.Duration(envInt("ZNEWS_REFRESH_JITTER", 0))*time.Second,
+		),
+		service.WithUI(envBool("ZNEWS_UI", false)),
+	)
 	s.ServeForever(servicePort)
 }
+
+// envInt reads an integer from the provided environment variable, returning the default value if
+// it is not set.
+func envInt(name string, def int) int {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("invalid value for %s: %v", name, err)
+	}
+	return n
+}
+
+// envBool reads a boolean from the provided environment variable, returning the default value if
+// it is not set.
+func envBool(name string, def bool) bool {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("invalid value for %s: %v", name, err)
+	}
+	return b
+}
+
+// categoryNormalization parses the normalization applied to article categories, defaulting to
+// keeping them as they are.
+func categoryNormalization(v string) store.CategoryNormalization {
+	switch v {
+	case "", "none":
+		return store.NormalizeNone
+	case "lowercase":
+		return store.NormalizeLowercase
+	case "slug":
+		return store.NormalizeSlug
+	}
+	log.Fatalf("invalid value for ZNEWS_NORMALIZE_CATEGORIES: %q", v)
+	return store.NormalizeNone
+}
+
+// idScheme parses how article IDs are generated, defaulting to UUIDs.
+func idScheme(v string) store.IDScheme {
+	switch v {
+	case "", "uuid":
+		return store.IDSchemeUUID
+	case "hash":
+		return store.IDSchemeHash
+	}
+	log.Fatalf("invalid value for ZNEWS_ARTICLE_IDS: %q", v)
+	return store.IDSchemeUUID
+}
+
+// compareFields parses the comma separated article fields compared to detect changes when reloading
+// feeds, defaulting to all of them but the categories.
+func compareFields(v string) []store.CompareField {
+	if v == "" {
+		return []store.CompareField{store.CompareTitle, store.CompareDescription, store.CompareContent}
+	}
+	var fields []store.CompareField
+	for _, name := range strings.Split(v, ",") {
+		switch field := store.CompareField(strings.TrimSpace(name)); field {
+		case store.CompareTitle, store.CompareDescription, store.CompareContent, store.CompareCategories:
+			fields = append(fields, field)
+		default:
+			log.Fatalf("invalid value for ZNEWS_COMPARE_FIELDS: %q", name)
+		}
+	}
+	return fields
+}
+
+// stripParams parses the comma separated query parameters stripped from article links, where none
+// means the default tracking parameters.
+func stripParams(v string) []string {
+	var params []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// invalidItemPolicy parses the policy applied to feed items lacking both an identifier and a title,
+// defaulting to keeping them.
+func invalidItemPolicy(v string) converters.InvalidItemPolicy {
+	switch v {
+	case "", "keep":
+		return converters.KeepInvalid
+	case "drop":
+		return converters.DropInvalid
+	}
+	log.Fatalf("invalid value for ZNEWS_INVALID_ITEMS: %q", v)
+	return converters.KeepInvalid
+}
+
+// futurePolicy parses the policy applied to articles dated too far into the future, defaulting to
+// clamping them.
+func futurePolicy(v string) feedconsumer.FuturePolicy {
+	switch v {
+	case "", "clamp":
+		return feedconsumer.ClampFuture
+	case "drop":
+		return feedconsumer.DropFuture
+	}
+	log.Fatalf("invalid value for ZNEWS_FUTURE_POLICY: %q", v)
+	return feedconsumer.ClampFuture
+}
+
+// evictionPolicy parses the store eviction policy, given as comma separated kind=value rules such as
+// "age=720,count=10000,feed=500,category=200", where age and ttl are in hours. Returns nil, evicting
+// nothing, when empty.
+func evictionPolicy(v string) store.EvictionPolicy {
+	var policies []store.EvictionPolicy
+	for _, rule := range strings.Split(v, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		i := strings.Index(rule, "=")
+		if i <= 0 {
+			log.Fatalf("invalid value for ZNEWS_EVICTION: %q", rule)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(rule[i+1:]))
+		if err != nil || n < 0 {
+			log.Fatalf("invalid value for ZNEWS_EVICTION: %q", rule)
+		}
+		switch strings.TrimSpace(rule[:i]) {
+		case "age":
+			policies = append(policies, store.MaxAge(time.Duration(n)*time.Hour))
+		case "count":
+			policies = append(policies, store.MaxCount(n))
+		case "feed":
+			policies = append(policies, store.MaxPerFeed(n))
+		case "category":
+			policies = append(policies, store.MaxPerCategory(n))
+		case "ttl":
+			policies = append(policies, store.MaxIngestAge(time.Duration(n)*time.Hour))
+		default:
+			log.Fatalf("invalid value for ZNEWS_EVICTION: %q", rule)
+		}
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	return store.Policies(policies...)
+}
+
+// cacheControl parses the Cache-Control header values of the routes, given as semicolon separated
+// route=value pairs such as "/articles/:id=max-age=86400;/articles=no-cache".
+func cacheControl(v string) map[string]string {
+	rules := map[string]string{}
+	for _, rule := range strings.Split(v, ";") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		i := strings.Index(rule, "=")
+		if i <= 0 || strings.TrimSpace(rule[i+1:]) == "" {
+			log.Fatalf("invalid value for ZNEWS_CACHE_CONTROL: %q", rule)
+		}
+		rules[strings.TrimSpace(rule[:i])] = strings.TrimSpace(rule[i+1:])
+	}
+	return rules
+}
@@ -4,6 +4,9 @@ package converters
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
 
 	"../../types"
 
@@ -12,15 +15,120 @@ import (
 
 const dateFormat = "Mon, 02 Jan 2006 15:04:05 MST"
 
+// InvalidItemPolicy describes how items lacking both an identifier, either a GUID or a link, and a
+// title are handled, as they can't be told apart nor shown meaningfully.
+type InvalidItemPolicy int
+
+const (
+	// KeepInvalid converts invalid items like any other.
+	KeepInvalid InvalidItemPolicy = iota
+	// DropInvalid skips invalid items, which are not converted.
+	DropInvalid
+)
+
+// DefaultTrackingParams are the query parameters stripped from links when no parameters are provided
+// to WithStripParams. Parameters ending in "*" match any parameter with that prefix.
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+// options holds the settings applied when converting items.
+type options struct {
+	maxBodyLength     int
+	maxCategories     int
+	invalidItemPolicy InvalidItemPolicy
+	baseURL           *url.URL
+	// keepDuplicateEnclosures is inverted so that the zero value removes duplicates by default.
+	keepDuplicateEnclosures bool
+	stripParams             []string
+	permaLinks              map[string]bool
+}
+
+// Option configures how items are converted into articles.
+type Option func(*options)
+
+// WithMaxBodyLength limits the Content and FullText of converted articles to the provided number of
+// bytes, truncating them on a rune boundary and flagging the article as truncated. Zero means
+// unlimited.
+func WithMaxBodyLength(n int) Option {
+	return func(o *options) {
+		o.maxBodyLength = n
+	}
+}
+
+// WithMaxCategories limits the number of categories of converted articles, keeping the first ones.
+// Categories are trimmed, and empty and duplicated ones are discarded before applying the limit.
+// Zero means unlimited.
+func WithMaxCategories(n int) Option {
+	return func(o *options) {
+		o.maxCategories = n
+	}
+}
+
+// WithInvalidItemPolicy sets how items lacking both an identifier and a title are handled. They
+// are kept by default.
+func WithInvalidItemPolicy(p InvalidItemPolicy) Option {
+	return func(o *options) {
+		o.invalidItemPolicy = p
+	}
+}
+
+// WithBaseURL resolves the relative links and enclosure URLs of converted articles against the
+// provided base, such as the link of the channel or the address of the feed. Absolute URLs are kept
+// unchanged, and an invalid base is ignored.
+func WithBaseURL(base string) Option {
+	return func(o *options) {
+		if u, err := url.Parse(strings.TrimSpace(base)); err == nil && u.IsAbs() {
+			o.baseURL = u
+		}
+	}
+}
+
+// WithDuplicateEnclosures sets whether enclosures repeating the URL of a previous enclosure of the
+// same item are kept. They are removed by default, keeping the first occurrence of each URL.
+func WithDuplicateEnclosures(keep bool) Option {
+	return func(o *options) {
+		o.keepDuplicateEnclosures = keep
+	}
+}
+
+// WithStripParams removes the provided query parameters, such as tracking ones, from the links of
+// converted articles, keeping the rest of the query as it is. Parameters ending in "*" match any
+// parameter with that prefix. When no parameters are provided, DefaultTrackingParams are removed.
+func WithStripParams(params ...string) Option {
+	return func(o *options) {
+		if len(params) == 0 {
+			params = DefaultTrackingParams
+		}
+		o.stripParams = params
+	}
+}
+
+// WithPermaLinkGUIDs sets the GUIDs that are permalinks, marked with isPermaLink="true" in the feed,
+// which are used as the link of the items that have no link of their own.
+func WithPermaLinkGUIDs(guids ...string) Option {
+	return func(o *options) {
+		o.permaLinks = make(map[string]bool, len(guids))
+		for _, guid := range guids {
+			o.permaLinks[guid] = true
+		}
+	}
+}
+
 // RSSToNativeArticles converts a slice of items provided by the rss library into the internal
-// representation of an article.
-func RSSToNativeArticles(is []rss.Item) ([]*types.Article, error) {
+// representation of an article. Items skipped by the invalid item policy are not returned.
+func RSSToNativeArticles(is []rss.Item, opts ...Option) ([]*types.Article, error) {
 	if len(is) == 0 {
 		return nil, nil
 	}
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
 	articles := make([]*types.Article, 0, len(is))
 	for _, i := range is {
-		a, err := rssToNativeArticle(i)
+		if o.invalidItemPolicy == DropInvalid && !valid(i) {
+			continue
+		}
+		a, err := rssToNativeArticle(i, o)
 		if err != nil {
 			return nil, err // The error returned here will have some format already.
 		}
@@ -29,37 +137,161 @@ func RSSToNativeArticles(is []rss.Item) ([]*types.Article, error) {
 	return articles, nil
 }
 
-func rssToNativeArticle(i rss.Item) (*types.Article, error) {
+func rssToNativeArticle(i rss.Item, o *options) (*types.Article, error) {
 	publishDate, err := i.PubDate.ParseWithFormat(dateFormat)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse publish date: %v", err)
 	}
+	content, contentTruncated := Truncate(i.Content, o.maxBodyLength)
+	fullText, fullTextTruncated := Truncate(i.FullText, o.maxBodyLength)
+	enclosures := rssToNativeEnclosures(i.Enclosure, o)
+	link := i.Link
+	if strings.TrimSpace(link) == "" && o.permaLinks[i.GUID] {
+		link = i.GUID
+	}
 	return &types.Article{
 		GUID:        i.GUID,
 		Title:       i.Title,
-		Link:        i.Link,
+		Link:        stripParams(resolve(o.baseURL, link), o.stripParams),
 		Comments:    i.Comments,
 		PublishDate: publishDate,
-		Categories:  i.Category,
-		Enclosures:  rssToNativeEnclosures(i.Enclosure),
+		Categories:  limitCategories(i.Category, o.maxCategories),
+		Enclosures:  enclosures,
 		Description: i.Description,
 		Author:      i.Author,
-		Content:     i.Content,
-		FullText:    i.FullText,
+		Content:     content,
+		FullText:    fullText,
+		Truncated:   contentTruncated || fullTextTruncated,
+		ImageURL:    imageURL(enclosures),
 	}, nil
 }
 
-func rssToNativeEnclosure(ie rss.ItemEnclosure) *types.Enclosure {
+// valid returns whether the item has an identifier, either a GUID or a link, or a title.
+func valid(i rss.Item) bool {
+	return strings.TrimSpace(i.GUID) != "" || strings.TrimSpace(i.Link) != "" || strings.TrimSpace(i.Title) != ""
+}
+
+// limitCategories returns up to max distinct categories, trimmed and skipping empty ones. The
+// categories are returned as they are when there is no limit.
+func limitCategories(categories []string, max int) []string {
+	if max <= 0 {
+		return categories
+	}
+	res := make([]string, 0, max)
+	seen := make(map[string]struct{}, max)
+	for _, c := range categories {
+		c = strings.TrimSpace(c)
+		if _, ok := seen[c]; ok || c == "" {
+			continue
+		}
+		seen[c] = struct{}{}
+		res = append(res, c)
+		if len(res) == max {
+			break
+		}
+	}
+	return res
+}
+
+// resolve returns the reference resolved against the base when it is a relative URL. Absolute,
+// empty and invalid references are returned as they are, as well as all of them without a base.
+func resolve(base *url.URL, ref string) string {
+	if base == nil || strings.TrimSpace(ref) == "" {
+		return ref
+	}
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// stripParams removes the query parameters matching the provided ones from the link, keeping the
+// order and encoding of the rest. Links that can't be parsed are returned unchanged.
+func stripParams(link string, params []string) string {
+	if len(params) == 0 || !strings.Contains(link, "?") {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.RawQuery == "" {
+		return link
+	}
+	var kept []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		name := pair
+		if i := strings.Index(pair, "="); i >= 0 {
+			name = pair[:i]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !matchesParam(name, params) {
+			kept = append(kept, pair)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String()
+}
+
+// matchesParam returns whether the query parameter name matches any of the provided parameters,
+// which match by prefix when ending in "*".
+func matchesParam(name string, params []string) bool {
+	for _, p := range params {
+		if prefix := strings.TrimSuffix(p, "*"); prefix != p {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Truncate cuts the provided string to at most max bytes without splitting a rune, returning
+// whether it was truncated. A max of zero means no limit.
+func Truncate(s string, max int) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	end := max
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end], true
+}
+
+func rssToNativeEnclosure(ie rss.ItemEnclosure, base *url.URL) *types.Enclosure {
 	return &types.Enclosure{
-		URL:  ie.URL,
+		URL:  resolve(base, ie.URL),
 		Type: ie.Type,
 	}
 }
 
-func rssToNativeEnclosures(ies []rss.ItemEnclosure) []*types.Enclosure {
+// imageURL returns the URL of the first enclosure whose type is an image, if any.
+func imageURL(enclosures []*types.Enclosure) string {
+	for _, e := range enclosures {
+		if strings.HasPrefix(strings.ToLower(e.Type), "image/") {
+			return e.URL
+		}
+	}
+	return ""
+}
+
+// rssToNativeEnclosures converts the enclosures of an item, removing the ones repeating a URL unless
+// duplicates are kept. URLs are compared once resolved against the base.
+func rssToNativeEnclosures(ies []rss.ItemEnclosure, o *options) []*types.Enclosure {
 	enclosures := make([]*types.Enclosure, 0, len(ies))
+	seen := make(map[string]struct{}, len(ies))
 	for _, ie := range ies {
-		enclosures = append(enclosures, rssToNativeEnclosure(ie))
+		e := rssToNativeEnclosure(ie, o.baseURL)
+		if !o.keepDuplicateEnclosures {
+			if _, ok := seen[e.URL]; ok {
+				continue
+			}
+			seen[e.URL] = struct{}{}
+		}
+		enclosures = append(enclosures, e)
 	}
 	return enclosures
 }
@@ -1,6 +1,7 @@
 package converters
 
 import (
+	"fmt"
 	"testing"
 	// "time"
 
@@ -77,6 +78,29 @@ func TestRSSToNativeArticles(t *testing.T) {
 		a.Equal("type2", articles[0].Enclosures[1].Type)
 	})
 
+	t.Run("image URL from the first image enclosure", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{
+			rss.Item{
+				PubDate: "Tue, 12 Jan 2021 00:05:18 GMT",
+				Enclosure: []rss.ItemEnclosure{
+					rss.ItemEnclosure{URL: "audio", Type: "audio/mpeg"},
+					rss.ItemEnclosure{URL: "image", Type: "Image/JPEG"},
+					rss.ItemEnclosure{URL: "other_image", Type: "image/png"},
+				},
+			},
+			rss.Item{
+				PubDate:   "Tue, 12 Jan 2021 00:05:18 GMT",
+				Enclosure: []rss.ItemEnclosure{rss.ItemEnclosure{URL: "audio", Type: "audio/mpeg"}},
+			},
+		})
+		r.NoError(err)
+		r.Len(articles, 2, "unexpected number of articles")
+		a.Equal("image", articles[0].ImageURL)
+		a.Empty(articles[1].ImageURL)
+	})
+
 	t.Run("convert all fields correctly", func(t *testing.T) {
 		r := require.New(t)
 		a := assert.New(t)
@@ -111,3 +135,288 @@ func TestRSSToNativeArticles(t *testing.T) {
 		a.Equal("full_text", articles[0].FullText)
 	})
 }
+
+func TestRSSToNativeArticlesMaxBodyLength(t *testing.T) {
+	item := rss.Item{
+		PubDate:  "Tue, 12 Jan 2021 00:05:18 GMT",
+		Content:  "content_too_long",
+		FullText: "full_text",
+	}
+
+	t.Run("zero keeps the full body", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithMaxBodyLength(0))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("content_too_long", articles[0].Content)
+		a.Equal("full_text", articles[0].FullText)
+		a.False(articles[0].Truncated)
+	})
+
+	t.Run("truncates content and full text", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithMaxBodyLength(7))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("content", articles[0].Content)
+		a.Equal("full_te", articles[0].FullText)
+		a.True(articles[0].Truncated)
+	})
+
+	t.Run("bodies within the limit are not flagged", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithMaxBodyLength(16))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("content_too_long", articles[0].Content)
+		a.False(articles[0].Truncated)
+	})
+
+	t.Run("truncates on a rune boundary", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		// Each of the runes below is encoded in two bytes.
+		articles, err := RSSToNativeArticles([]rss.Item{rss.Item{
+			PubDate: "Tue, 12 Jan 2021 00:05:18 GMT",
+			Content: "éééé",
+		}}, WithMaxBodyLength(5))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("éé", articles[0].Content)
+		a.Len(articles[0].Content, 4)
+		a.True(articles[0].Truncated)
+	})
+}
+
+func TestRSSToNativeArticlesInvalidItemPolicy(t *testing.T) {
+	items := []rss.Item{
+		rss.Item{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", Description: "no identifying fields"},
+		rss.Item{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", GUID: " ", Link: "", Title: "\t"},
+		rss.Item{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", GUID: "guid"},
+		rss.Item{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", Link: "link"},
+		rss.Item{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", Title: "title"},
+	}
+
+	t.Run("invalid items are kept by default", func(t *testing.T) {
+		r := require.New(t)
+		articles, err := RSSToNativeArticles(items)
+		r.NoError(err)
+		r.Len(articles, 5, "unexpected number of articles")
+	})
+
+	t.Run("drops items lacking all identifying fields", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles(items, WithInvalidItemPolicy(DropInvalid))
+		r.NoError(err)
+		r.Len(articles, 3, "unexpected number of articles")
+		a.Equal("guid", articles[0].GUID)
+		a.Equal("link", articles[1].Link)
+		a.Equal("title", articles[2].Title)
+	})
+}
+
+func TestRSSToNativeArticlesMaxCategories(t *testing.T) {
+	categories := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		categories = append(categories, fmt.Sprintf("category_%d", i))
+	}
+	item := rss.Item{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", Category: categories}
+
+	t.Run("keeps the first categories up to the limit", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithMaxCategories(5))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal([]string{"category_0", "category_1", "category_2", "category_3", "category_4"}, articles[0].Categories)
+	})
+
+	t.Run("zero keeps all categories", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithMaxCategories(0))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Len(articles[0].Categories, 50)
+	})
+
+	t.Run("empty and duplicated categories don't count", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{
+			rss.Item{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", Category: []string{"a", " ", "a", " b ", "c", "d"}},
+		}, WithMaxCategories(3))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal([]string{"a", "b", "c"}, articles[0].Categories)
+	})
+}
+
+func TestRSSToNativeArticlesPermaLinkGUIDs(t *testing.T) {
+	const guid = "https://example.com/story/123"
+
+	t.Run("permalink GUID is the link when missing", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{
+			rss.Item{GUID: guid, PubDate: "Tue, 12 Jan 2021 00:05:18 GMT"},
+		}, WithPermaLinkGUIDs(guid))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal(guid, articles[0].Link)
+	})
+
+	t.Run("explicit link is kept", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{
+			rss.Item{GUID: guid, Link: "https://example.com/other", PubDate: "Tue, 12 Jan 2021 00:05:18 GMT"},
+		}, WithPermaLinkGUIDs(guid))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("https://example.com/other", articles[0].Link)
+	})
+
+	t.Run("GUIDs not marked as permalinks are not links", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{
+			rss.Item{GUID: guid, PubDate: "Tue, 12 Jan 2021 00:05:18 GMT"},
+		})
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Empty(articles[0].Link)
+	})
+}
+
+func TestRSSToNativeArticlesBaseURL(t *testing.T) {
+	item := rss.Item{
+		PubDate:   "Tue, 12 Jan 2021 00:05:18 GMT",
+		Link:      "/story/123",
+		Enclosure: []rss.ItemEnclosure{{URL: "images/1.jpg"}, {URL: "https://cdn.example.com/2.jpg"}},
+	}
+
+	t.Run("resolves relative URLs against the base", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithBaseURL("https://example.com/news/feed.xml"))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("https://example.com/story/123", articles[0].Link)
+		r.Len(articles[0].Enclosures, 2, "unexpected number of enclosures")
+		a.Equal("https://example.com/news/images/1.jpg", articles[0].Enclosures[0].URL)
+		a.Equal("https://cdn.example.com/2.jpg", articles[0].Enclosures[1].URL)
+	})
+
+	t.Run("keeps absolute URLs", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{
+			{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", Link: "http://other.com/story"},
+		}, WithBaseURL("https://example.com"))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("http://other.com/story", articles[0].Link)
+	})
+
+	t.Run("keeps relative URLs without a valid base", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		for _, opts := range [][]Option{nil, {WithBaseURL("/relative")}} {
+			articles, err := RSSToNativeArticles([]rss.Item{item}, opts...)
+			r.NoError(err)
+			r.Len(articles, 1, "unexpected number of articles")
+			a.Equal("/story/123", articles[0].Link)
+		}
+	})
+}
+
+func TestRSSToNativeArticlesStripParams(t *testing.T) {
+	item := rss.Item{
+		PubDate: "Tue, 12 Jan 2021 00:05:18 GMT",
+		Link:    "https://example.com/story?id=123&utm_source=rss&utm_medium=feed&page=2&fbclid=abc&gclid=def#comments",
+	}
+
+	t.Run("removes the default tracking parameters", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithStripParams())
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("https://example.com/story?id=123&page=2#comments", articles[0].Link)
+	})
+
+	t.Run("removes the provided parameters", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithStripParams("page", "utm_*"))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("https://example.com/story?id=123&fbclid=abc&gclid=def#comments", articles[0].Link)
+	})
+
+	t.Run("removes the query when only tracking parameters are present", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{
+			{PubDate: "Tue, 12 Jan 2021 00:05:18 GMT", Link: "https://example.com/story?utm_source=rss"},
+		}, WithStripParams())
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("https://example.com/story", articles[0].Link)
+	})
+
+	t.Run("keeps links unchanged by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item})
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal(item.Link, articles[0].Link)
+	})
+}
+
+func TestRSSToNativeArticlesDuplicateEnclosures(t *testing.T) {
+	item := rss.Item{
+		PubDate: "Tue, 12 Jan 2021 00:05:18 GMT",
+		Enclosure: []rss.ItemEnclosure{
+			{URL: "https://example.com/1.jpg", Type: "image/jpeg"},
+			{URL: "https://example.com/2.mp3", Type: "audio/mpeg"},
+			{URL: "https://example.com/1.jpg", Type: "image/png"},
+			{URL: "/1.jpg", Type: "image/jpeg"},
+		},
+	}
+
+	t.Run("duplicates are removed by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item})
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		r.Len(articles[0].Enclosures, 3, "unexpected number of enclosures")
+		a.Equal("https://example.com/1.jpg", articles[0].Enclosures[0].URL)
+		a.Equal("image/jpeg", articles[0].Enclosures[0].Type, "the first occurrence must be kept")
+		a.Equal("https://example.com/2.mp3", articles[0].Enclosures[1].URL)
+		a.Equal("/1.jpg", articles[0].Enclosures[2].URL)
+	})
+
+	t.Run("resolved URLs are compared", func(t *testing.T) {
+		r := require.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithBaseURL("https://example.com/feed"))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		assert.Len(t, articles[0].Enclosures, 2)
+	})
+
+	t.Run("duplicates can be kept", func(t *testing.T) {
+		r := require.New(t)
+		articles, err := RSSToNativeArticles([]rss.Item{item}, WithDuplicateEnclosures(true))
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		assert.Len(t, articles[0].Enclosures, 4)
+	})
+}
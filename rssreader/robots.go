@@ -0,0 +1,167 @@
+package rssreader
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// robotsUserAgent is the user agent whose rules are followed in robots.txt files, falling back to
+// the rules for all user agents. It is the one sent by the reader.
+const robotsUserAgent = userAgent
+
+// robotsCacheTTL is the time robots.txt files are cached for each host.
+const robotsCacheTTL = time.Hour
+
+// maxRobotsSize is the maximum size in bytes read from robots.txt files.
+const maxRobotsSize = 512 << 10
+
+// ErrDisallowedByRobots is returned when reading a feed address disallowed by the robots.txt file of
+// its host.
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// robotsRules holds the rules of a robots.txt file that apply to the reader.
+type robotsRules struct {
+	allow     []string
+	disallow  []string
+	fetchedAt time.Time
+}
+
+// allowed reports whether the provided path is allowed, which is decided by the longest matching
+// rule, favouring allow rules when equally long.
+func (r *robotsRules) allowed(path string) bool {
+	longest := func(rules []string) int {
+		n := -1
+		for _, rule := range rules {
+			if strings.HasPrefix(path, rule) && len(rule) > n {
+				n = len(rule)
+			}
+		}
+		return n
+	}
+	disallow := longest(r.disallow)
+	return disallow < 0 || longest(r.allow) >= disallow
+}
+
+// WithRobots makes the reader check the robots.txt file of the host of each feed address before
+// reading it, failing with ErrDisallowedByRobots if the address is disallowed. The files are cached
+// for an hour for each host. Hosts without a robots.txt file, or whose file can't be read, allow
+// every address.
+func WithRobots(enabled bool) Option {
+	return func(rssf *Feed) {
+		rssf.robots = enabled
+	}
+}
+
+// robotsAllowed reports whether the robots.txt file of the host of the provided address allows
+// reading it.
+func (rssf *Feed) robotsAllowed(client *http.Client, address string) bool {
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		// Invalid addresses fail when fetched.
+		return true
+	}
+	origin := u.Scheme + "://" + u.Host
+	rssf.mu.Lock()
+	rules, ok := rssf.robotsCache[origin]
+	rssf.mu.Unlock()
+	if !ok || time.Since(rules.fetchedAt) > robotsCacheTTL {
+		rules = fetchRobots(client, origin+"/robots.txt")
+		rssf.mu.Lock()
+		rssf.robotsCache[origin] = rules
+		rssf.mu.Unlock()
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return rules.allowed(path)
+}
+
+// fetchRobots fetches and parses the robots.txt file in the provided address. Files that can't be
+// fetched have no rules.
+func fetchRobots(client *http.Client, address string) *robotsRules {
+	rules := &robotsRules{fetchedAt: time.Now()}
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return rules
+	}
+	req.Header.Set("User-Agent", userAgent)
+	res, err := client.Do(req)
+	if err != nil {
+		return rules
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return rules
+	}
+	return parseRobots(io.LimitReader(res.Body, maxRobotsSize), rules)
+}
+
+// parseRobots adds the rules of the group of the reader user agent in the provided robots.txt file
+// to the rules, or the ones of the group for all user agents if it has no group.
+func parseRobots(r io.Reader, rules *robotsRules) *robotsRules {
+	type group struct {
+		allow, disallow []string
+	}
+	groups := map[string]*group{}
+	var current []*group
+	inRules := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "user-agent":
+			// Consecutive user agents share the rules that follow them.
+			if inRules {
+				current = nil
+				inRules = false
+			}
+			agent := strings.ToLower(value)
+			g, ok := groups[agent]
+			if !ok {
+				g = &group{}
+				groups[agent] = g
+			}
+			current = append(current, g)
+		case "allow", "disallow":
+			inRules = true
+			// An empty disallow rule allows everything.
+			if value == "" {
+				continue
+			}
+			for _, g := range current {
+				if key == "allow" {
+					g.allow = append(g.allow, value)
+				} else {
+					g.disallow = append(g.disallow, value)
+				}
+			}
+		}
+	}
+	g, ok := groups[robotsUserAgent]
+	if !ok {
+		g, ok = groups["*"]
+	}
+	if ok {
+		rules.allow = g.allow
+		rules.disallow = g.disallow
+	}
+	return rules
+}
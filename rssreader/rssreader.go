@@ -3,36 +3,452 @@
 package rssreader
 
 import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
 	"../types"
 	"./converters"
 
 	"github.com/ungerik/go-rss"
 )
 
+// maxRedirects is the maximum number of redirects followed when reading a feed.
+const maxRedirects = 10
+
+// defaultTimeout is the time allowed for reading a feed when no timeout is configured.
+const defaultTimeout = 30 * time.Second
+
+// maxFeedSize is the maximum size in bytes read from a feed address, beyond which the feed is cut.
+const maxFeedSize = 10 << 20
+
+// userAgent is the user agent sent when reading feeds and their robots.txt files.
+const userAgent = "znews"
+
+// ErrEmptyFeed is returned when a feed address responds successfully without any content.
+var ErrEmptyFeed = errors.New("empty feed")
+
 // Feed provides the functionality required for consuming articles from RSS feeds.
-type Feed struct{}
+type Feed struct {
+	convertOpts []converters.Option
+	timeout     time.Duration
+	retries     int
+	retryDelay  time.Duration
+	maxPerHost  int
+	hostDelay   time.Duration
+	robots      bool
+	maxPages    int
+
+	mu          sync.Mutex
+	hosts       map[string]chan struct{}
+	next        map[string]time.Time
+	robotsCache map[string]*robotsRules
+}
+
+// Option configures optional behaviour of a Feed.
+type Option func(*Feed)
+
+// WithMaxBodyLength limits the size in bytes of the content and full text of the articles read.
+// Zero means unlimited.
+func WithMaxBodyLength(n int) Option {
+	return func(rssf *Feed) {
+		rssf.convertOpts = append(rssf.convertOpts, converters.WithMaxBodyLength(n))
+	}
+}
+
+// WithMaxCategories limits the number of categories kept for each article read. Zero means
+// unlimited.
+func WithMaxCategories(n int) Option {
+	return func(rssf *Feed) {
+		rssf.convertOpts = append(rssf.convertOpts, converters.WithMaxCategories(n))
+	}
+}
+
+// WithInvalidItemPolicy sets how items lacking both an identifier and a title are handled. Skipped
+// items are counted in the channel read.
+func WithInvalidItemPolicy(p converters.InvalidItemPolicy) Option {
+	return func(rssf *Feed) {
+		rssf.convertOpts = append(rssf.convertOpts, converters.WithInvalidItemPolicy(p))
+	}
+}
+
+// WithDuplicateEnclosures sets whether enclosures repeating a URL already enclosed in the same item
+// are kept. They are removed by default.
+func WithDuplicateEnclosures(keep bool) Option {
+	return func(rssf *Feed) {
+		rssf.convertOpts = append(rssf.convertOpts, converters.WithDuplicateEnclosures(keep))
+	}
+}
+
+// WithStripParams removes the provided query parameters, such as tracking ones, from the links of
+// the articles read. When no parameters are provided, converters.DefaultTrackingParams are removed.
+func WithStripParams(params ...string) Option {
+	return func(rssf *Feed) {
+		rssf.convertOpts = append(rssf.convertOpts, converters.WithStripParams(params...))
+	}
+}
+
+// WithTimeout sets the default time allowed for reading a feed, used when no timeout is provided
+// for the read.
+func WithTimeout(d time.Duration) Option {
+	return func(rssf *Feed) {
+		rssf.timeout = d
+	}
+}
+
+// WithRetries retries reading a feed up to the provided number of times when it fails with a
+// transient error, which are network errors and server errors or rate limiting responses, waiting
+// the provided delay between attempts. The attempts made are reported in the channel read.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(rssf *Feed) {
+		rssf.retries = retries
+		rssf.retryDelay = delay
+	}
+}
+
+// WithMaxPerHost limits the number of feeds read at the same time from each host, identified by the
+// hostname of their address, so that reading many feeds from a host doesn't get rate limited by it.
+// Reads exceeding the limit wait for a previous one to finish. Zero means unlimited.
+func WithMaxPerHost(n int) Option {
+	return func(rssf *Feed) {
+		rssf.maxPerHost = n
+	}
+}
+
+// WithHostDelay waits at least the provided delay between the start of consecutive reads from the
+// same host, identified by the hostname of their address, whether the reads are sequential or
+// concurrent. Zero means no delay.
+func WithHostDelay(d time.Duration) Option {
+	return func(rssf *Feed) {
+		rssf.hostDelay = d
+	}
+}
+
+// WithMaxPages sets the maximum number of pages read when paginating, following the atom:link
+// elements with rel="next" of each page and concatenating their items. Only reads with Paginate set
+// in their options follow the links. Zero or one reads only the first page.
+func WithMaxPages(n int) Option {
+	return func(rssf *Feed) {
+		rssf.maxPages = n
+	}
+}
 
 // NewFeed returns a new feed for the provided RSS feed address.
-func NewFeed() *Feed {
-	return &Feed{}
+func NewFeed(opts ...Option) *Feed {
+	rssf := &Feed{
+		timeout:     defaultTimeout,
+		hosts:       map[string]chan struct{}{},
+		next:        map[string]time.Time{},
+		robotsCache: map[string]*robotsRules{},
+	}
+	for _, opt := range opts {
+		opt(rssf)
+	}
+	return rssf
 }
 
-// Load reads the feed configured on instantiation and returns a slice of articles.
-func (rssf *Feed) Load(address string) ([]*types.Article, error) {
-	res, err := rss.Read(address, false)
+// Read fetches the feed in the provided address and returns its channel information together with
+// the converted articles. Redirects are followed and the address that was finally read is reported
+// in the channel, which is flagged as moved when all redirects followed were permanent. If
+// credentials are provided, they are sent using HTTP Basic Auth. The timeout provided in the options
+// takes precedence over the default one, and applies to each attempt when retrying. When paginating,
+// the articles of the following pages are added after the ones of the first page.
+func (rssf *Feed) Read(address string, opts types.ReadOptions) (*types.Channel, error) {
+	res, body, permanent, attempts, err := rssf.get(address, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Empty responses are reported distinctly, since parsing them fails with a cryptic error.
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, ErrEmptyFeed
+	}
+	channel, err := rssf.parse(res, body, permanent, attempts)
 	if err != nil {
 		return nil, err
 	}
+	if opts.Paginate {
+		rssf.readPages(channel, res.Request.URL, body, opts)
+	}
+	return channel, nil
+}
+
+// readPages reads the pages following the first one of a feed, linked through rel="next", adding
+// their articles to the channel read from the first page until reaching the maximum number of pages.
+// Pages that fail to be read or parsed end the pagination, keeping the articles read so far, since
+// the following pages only hold older items.
+func (rssf *Feed) readPages(channel *types.Channel, address *url.URL, body []byte, opts types.ReadOptions) {
+	visited := map[string]bool{address.String(): true}
+	for page := 1; page < rssf.maxPages; page++ {
+		next := channelLinks(body)["next"]
+		if next == "" {
+			return
+		}
+		nextURL, err := address.Parse(next)
+		if err != nil || visited[nextURL.String()] {
+			return
+		}
+		visited[nextURL.String()] = true
+		res, nextBody, _, attempts, err := rssf.get(nextURL.String(), opts)
+		if err != nil || len(bytes.TrimSpace(nextBody)) == 0 {
+			return
+		}
+		nextChannel, err := rssf.parse(res, nextBody, false, attempts)
+		if err != nil {
+			return
+		}
+		channel.Articles = append(channel.Articles, nextChannel.Articles...)
+		channel.Skipped += nextChannel.Skipped
+		channel.Attempts += nextChannel.Attempts
+		address, body = res.Request.URL, nextBody
+	}
+}
+
+// Parse converts the feed document in the provided body, such as content pushed by a WebSub hub,
+// the same way as Read does, resolving relative links against the provided address.
+func (rssf *Feed) Parse(address string, body []byte) (*types.Channel, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, ErrEmptyFeed
+	}
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	return rssf.parse(&http.Response{Request: req}, body, false, 0)
+}
+
+// parse converts the feed document in the body of the provided response into a channel.
+func (rssf *Feed) parse(res *http.Response, body []byte, permanent bool, attempts int) (*types.Channel, error) {
+	res.Body = io.NopCloser(bytes.NewReader(body))
 
 	channel, err := rss.Regular(res)
 	if err != nil {
 		return nil, err
 	}
 
-	articles, err := converters.RSSToNativeArticles(channel.Item)
+	// Relative links are resolved against the link of the channel, itself resolved against the address
+	// read, which is used instead when the channel has no link.
+	base := res.Request.URL.String()
+	if channel.Link != "" {
+		if link, err := res.Request.URL.Parse(strings.TrimSpace(channel.Link)); err == nil {
+			base = link.String()
+		}
+	}
+	convertOpts := append(rssf.convertOpts[:len(rssf.convertOpts):len(rssf.convertOpts)],
+		converters.WithBaseURL(base),
+		converters.WithPermaLinkGUIDs(permaLinkGUIDs(body)...),
+	)
+	articles, err := converters.RSSToNativeArticles(channel.Item, convertOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return articles, nil
+	links := channelLinks(body)
+	hub, topic := links["hub"], links["self"]
+	if hub != "" && topic == "" {
+		topic = res.Request.URL.String()
+	}
+	return &types.Channel{
+		Title:    channel.Title,
+		Address:  res.Request.URL.String(),
+		Moved:    permanent,
+		Articles: articles,
+		// Items are only left out of the conversion when skipped as invalid.
+		Skipped:  len(channel.Item) - len(articles),
+		Attempts: attempts,
+		Hub:      hub,
+		Topic:    topic,
+	}, nil
+}
+
+// linkDocument holds the links of the channel of an rss document, where feeds declare their WebSub
+// hub, the address they are published at and their next page, usually as atom:link elements.
+type linkDocument struct {
+	Links []struct {
+		Rel  string `xml:"rel,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"channel>link"`
+}
+
+// channelLinks returns the first address linked by the channel of the rss document for each
+// relation, such as "hub", "self" or "next", in lowercase.
+func channelLinks(body []byte) map[string]string {
+	var doc linkDocument
+	links := map[string]string{}
+	// Like with the permalinks, errors here only lose the links.
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return links
+	}
+	for _, link := range doc.Links {
+		rel := strings.ToLower(strings.TrimSpace(link.Rel))
+		href := strings.TrimSpace(link.Href)
+		if rel == "" || href == "" {
+			continue
+		}
+		if _, ok := links[rel]; !ok {
+			links[rel] = href
+		}
+	}
+	return links
+}
+
+// permaLinkDocument holds the GUIDs of the items of an rss document, which the rss library reads
+// without their isPermaLink attribute.
+type permaLinkDocument struct {
+	Items []struct {
+		GUID struct {
+			Value       string `xml:",chardata"`
+			IsPermaLink string `xml:"isPermaLink,attr"`
+		} `xml:"guid"`
+	} `xml:"channel>item"`
+}
+
+// permaLinkGUIDs returns the GUIDs of the items in the rss document that are marked as permalinks.
+// Only GUIDs explicitly marked are returned, since many feeds omit the attribute for GUIDs that are
+// not URLs even though the specification defaults it to true.
+func permaLinkGUIDs(body []byte) []string {
+	var doc permaLinkDocument
+	// The document was already parsed by the rss library, so errors here only lose the permalinks.
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+	var guids []string
+	for _, item := range doc.Items {
+		if strings.EqualFold(strings.TrimSpace(item.GUID.IsPermaLink), "true") {
+			guids = append(guids, item.GUID.Value)
+		}
+	}
+	return guids
+}
+
+// ReadRaw fetches the feed in the provided address the same way as Read, returning its body as it
+// was received together with its content type, without parsing it.
+func (rssf *Feed) ReadRaw(address string, opts types.ReadOptions) (*types.RawFeed, error) {
+	res, body, _, _, err := rssf.get(address, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &types.RawFeed{
+		ContentType: res.Header.Get("Content-Type"),
+		Body:        body,
+	}, nil
+}
+
+// get requests the feed in the provided address, following redirects and retrying transient errors.
+// Returns the response along with its body, whether all redirects followed were permanent and the
+// attempts made.
+func (rssf *Feed) get(address string, opts types.ReadOptions) (*http.Response, []byte, bool, int, error) {
+	timeout := rssf.timeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	permanent := false
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("stopped after too many redirects")
+			}
+			status := req.Response.StatusCode
+			isPermanent := status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+			permanent = isPermanent && (len(via) == 1 || permanent)
+			return nil
+		},
+	}
+	if rssf.robots && !rssf.robotsAllowed(client, address) {
+		return nil, nil, false, 0, ErrDisallowedByRobots
+	}
+	var res *http.Response
+	var body []byte
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		permanent = false
+		var transient bool
+		release := rssf.acquire(address)
+		res, body, transient, err = fetch(client, address, opts)
+		release()
+		if err == nil || !transient || attempts > rssf.retries {
+			break
+		}
+		time.Sleep(rssf.retryDelay)
+	}
+	return res, body, permanent, attempts, err
+}
+
+// acquire waits until a read of the provided address is allowed by the limit of concurrent reads and
+// the delay between reads per host, returning the function that must be called once the read
+// finishes.
+func (rssf *Feed) acquire(address string) func() {
+	release := func() {}
+	if rssf.maxPerHost <= 0 && rssf.hostDelay <= 0 {
+		return release
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		// Invalid addresses fail when fetched, so they are not limited.
+		return release
+	}
+	host := u.Hostname()
+	if rssf.maxPerHost > 0 {
+		rssf.mu.Lock()
+		sem, ok := rssf.hosts[host]
+		if !ok {
+			sem = make(chan struct{}, rssf.maxPerHost)
+			rssf.hosts[host] = sem
+		}
+		rssf.mu.Unlock()
+		sem <- struct{}{}
+		release = func() {
+			<-sem
+		}
+	}
+	if rssf.hostDelay > 0 {
+		// Each read reserves the earliest start allowed for the host, so concurrent reads are spaced too.
+		rssf.mu.Lock()
+		start := time.Now()
+		if next := rssf.next[host]; next.After(start) {
+			start = next
+		}
+		rssf.next[host] = start.Add(rssf.hostDelay)
+		rssf.mu.Unlock()
+		time.Sleep(time.Until(start))
+	}
+	return release
+}
+
+// fetch requests the feed in the provided address, returning the response along with its body.
+// Errors are flagged as transient when retrying the request could succeed.
+func fetch(client *http.Client, address string, opts types.ReadOptions) (*http.Response, []byte, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if opts.Credentials != nil {
+		req.SetBasicAuth(opts.Credentials.Username, opts.Credentials.Password)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, true, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		transient := res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusTooManyRequests
+		return nil, nil, transient, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	// Feeds exceeding the maximum size are cut, so a misbehaving address can't exhaust memory.
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxFeedSize))
+	res.Body.Close()
+	if err != nil {
+		return nil, nil, true, err
+	}
+	return res, body, false, nil
 }
@@ -0,0 +1,677 @@
+package rssreader
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../types"
+	"./converters"
+)
+
+const testFeedBody = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Fixture News</title>
+    <item>
+      <guid>guid_1</guid>
+      <title>title_1</title>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate>
+    </item>
+  </channel>
+</rss>`
+
+// newFeedServer returns a test server serving the fixture feed under /feed, and under /private for
+// requests authenticated as user:pass, an empty body under /empty, redirecting the provided paths to their targets with the
+// given status codes.
+func newFeedServer(redirects map[string]redirect) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, testFeedBody)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, " \n\t")
+	})
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="feed"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, testFeedBody)
+	})
+	for path, rd := range redirects {
+		rd := rd
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, rd.target, rd.status)
+		})
+	}
+	return httptest.NewServer(mux)
+}
+
+type redirect struct {
+	target string
+	status int
+}
+
+func TestRead(t *testing.T) {
+	server := newFeedServer(map[string]redirect{
+		"/moved":     redirect{target: "/feed", status: http.StatusMovedPermanently},
+		"/temporary": redirect{target: "/feed", status: http.StatusFound},
+		"/chained":   redirect{target: "/temporary", status: http.StatusMovedPermanently},
+	})
+	defer server.Close()
+	feed := NewFeed()
+
+	t.Run("reads channel and articles", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := feed.Read(server.URL+"/feed", types.ReadOptions{})
+		r.NoError(err)
+		a.Equal("Fixture News", channel.Title)
+		a.Equal(server.URL+"/feed", channel.Address)
+		a.False(channel.Moved)
+		r.Len(channel.Articles, 1, "unexpected number of articles")
+		a.Equal("guid_1", channel.Articles[0].GUID)
+	})
+
+	t.Run("reports final address of a permanent redirect", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := feed.Read(server.URL+"/moved", types.ReadOptions{})
+		r.NoError(err)
+		a.Equal(server.URL+"/feed", channel.Address)
+		a.True(channel.Moved)
+		a.Len(channel.Articles, 1, "unexpected number of articles")
+	})
+
+	t.Run("reports final address of a temporary redirect", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := feed.Read(server.URL+"/temporary", types.ReadOptions{})
+		r.NoError(err)
+		a.Equal(server.URL+"/feed", channel.Address)
+		a.False(channel.Moved)
+	})
+
+	t.Run("is not moved if any redirect is temporary", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := feed.Read(server.URL+"/chained", types.ReadOptions{})
+		r.NoError(err)
+		a.Equal(server.URL+"/feed", channel.Address)
+		a.False(channel.Moved)
+	})
+
+	t.Run("errors for unexpected status code", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := feed.Read(server.URL+"/missing", types.ReadOptions{})
+		r.Nil(channel)
+		r.Error(err)
+		a.Contains(err.Error(), "unexpected status code 404")
+	})
+}
+
+func TestReadCredentials(t *testing.T) {
+	server := newFeedServer(nil)
+	defer server.Close()
+	feed := NewFeed()
+
+	t.Run("reads feed with credentials", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := feed.Read(server.URL+"/private", types.ReadOptions{Credentials: &types.Credentials{Username: "user", Password: "pass"}})
+		r.NoError(err)
+		a.Equal("Fixture News", channel.Title)
+		a.Len(channel.Articles, 1, "unexpected number of articles")
+	})
+
+	t.Run("fails without credentials", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := feed.Read(server.URL+"/private", types.ReadOptions{})
+		r.Error(err)
+		a.Contains(err.Error(), "unexpected status code 401")
+	})
+
+	t.Run("fails with wrong credentials", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := feed.Read(server.URL+"/private", types.ReadOptions{Credentials: &types.Credentials{Username: "user", Password: "wrong"}})
+		r.Error(err)
+		a.Contains(err.Error(), "unexpected status code 401")
+	})
+}
+
+func TestReadEmptyFeed(t *testing.T) {
+	server := newFeedServer(nil)
+	defer server.Close()
+	r := require.New(t)
+	a := assert.New(t)
+	_, err := NewFeed().Read(server.URL+"/empty", types.ReadOptions{})
+	r.Error(err)
+	a.True(errors.Is(err, ErrEmptyFeed), "unexpected error: %v", err)
+}
+
+func TestReadRaw(t *testing.T) {
+	server := newFeedServer(nil)
+	defer server.Close()
+
+	t.Run("returns the body as received", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		raw, err := NewFeed().ReadRaw(server.URL+"/feed", types.ReadOptions{})
+		r.NoError(err)
+		a.Equal("application/rss+xml", raw.ContentType)
+		a.Equal(testFeedBody, string(raw.Body))
+	})
+
+	t.Run("errors for unexpected status code", func(t *testing.T) {
+		r := require.New(t)
+		_, err := NewFeed().ReadRaw(server.URL+"/private", types.ReadOptions{})
+		r.Error(err)
+	})
+}
+
+func TestReadTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, testFeedBody)
+	}))
+	defer server.Close()
+	feed := NewFeed(WithTimeout(50 * time.Millisecond))
+
+	t.Run("fails when the feed timeout is exceeded", func(t *testing.T) {
+		r := require.New(t)
+		_, err := feed.Read(server.URL+"/slow", types.ReadOptions{Timeout: 20 * time.Millisecond})
+		r.Error(err)
+	})
+
+	t.Run("fails when the default timeout is exceeded", func(t *testing.T) {
+		r := require.New(t)
+		_, err := feed.Read(server.URL+"/slow", types.ReadOptions{})
+		r.Error(err)
+	})
+
+	t.Run("feed timeout takes precedence over the default one", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := feed.Read(server.URL+"/slow", types.ReadOptions{Timeout: 2 * time.Second})
+		r.NoError(err)
+		a.Equal("Fixture News", channel.Title)
+	})
+
+	t.Run("fast feeds load within the default timeout", func(t *testing.T) {
+		r := require.New(t)
+		_, err := feed.Read(server.URL+"/fast", types.ReadOptions{})
+		r.NoError(err)
+	})
+}
+
+func TestReadInvalidItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Fixture News</title>
+    <item>
+      <guid>guid_1</guid>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate>
+    </item>
+    <item>
+      <description>no identifying fields</description>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate>
+    </item>
+  </channel>
+</rss>`)
+	}))
+	defer server.Close()
+
+	t.Run("counts dropped items as skipped", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := NewFeed(WithInvalidItemPolicy(converters.DropInvalid)).Read(server.URL, types.ReadOptions{})
+		r.NoError(err)
+		r.Len(channel.Articles, 1, "unexpected number of articles")
+		a.Equal("guid_1", channel.Articles[0].GUID)
+		a.Equal(1, channel.Skipped)
+	})
+
+	t.Run("keeps invalid items by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := NewFeed().Read(server.URL, types.ReadOptions{})
+		r.NoError(err)
+		a.Len(channel.Articles, 2, "unexpected number of articles")
+		a.Equal(0, channel.Skipped)
+	})
+}
+
+func TestReadRetries(t *testing.T) {
+	// newFlakyServer returns a test server failing the first requests with the provided status code.
+	newFlakyServer := func(failures int, status int) (*httptest.Server, *int32) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if int(atomic.AddInt32(&requests, 1)) <= failures {
+				w.WriteHeader(status)
+				return
+			}
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, testFeedBody)
+		}))
+		return server, &requests
+	}
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		server, requests := newFlakyServer(1, http.StatusServiceUnavailable)
+		defer server.Close()
+		channel, err := NewFeed(WithRetries(2, time.Millisecond)).Read(server.URL, types.ReadOptions{})
+		r.NoError(err)
+		a.Equal(2, channel.Attempts)
+		a.Equal(int32(2), atomic.LoadInt32(requests))
+	})
+
+	t.Run("fails when the retries are exhausted", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		server, requests := newFlakyServer(3, http.StatusTooManyRequests)
+		defer server.Close()
+		_, err := NewFeed(WithRetries(2, time.Millisecond)).Read(server.URL, types.ReadOptions{})
+		r.Error(err)
+		a.Equal(int32(3), atomic.LoadInt32(requests))
+	})
+
+	t.Run("doesn't retry permanent failures", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		server, requests := newFlakyServer(1, http.StatusNotFound)
+		defer server.Close()
+		_, err := NewFeed(WithRetries(2, time.Millisecond)).Read(server.URL, types.ReadOptions{})
+		r.Error(err)
+		a.Equal(int32(1), atomic.LoadInt32(requests))
+	})
+
+	t.Run("doesn't retry by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		server, requests := newFlakyServer(1, http.StatusInternalServerError)
+		defer server.Close()
+		_, err := NewFeed().Read(server.URL, types.ReadOptions{})
+		r.Error(err)
+		a.Equal(int32(1), atomic.LoadInt32(requests))
+	})
+}
+
+func TestReadMaxPerHost(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	var current, max int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, testFeedBody)
+	}))
+	defer server.Close()
+	rssf := NewFeed(WithMaxPerHost(2))
+
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		i := i
+		go func() {
+			_, err := rssf.Read(fmt.Sprintf("%s/section_%d", server.URL, i), types.ReadOptions{})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 6; i++ {
+		r.NoError(<-errs)
+	}
+	a.Equal(int32(2), atomic.LoadInt32(&max))
+
+	// The same server reached through another hostname counts as a different host.
+	atomic.StoreInt32(&max, 0)
+	rssf = NewFeed(WithMaxPerHost(1))
+	otherHost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	for _, address := range []string{server.URL, otherHost} {
+		address := address
+		go func() {
+			_, err := rssf.Read(address, types.ReadOptions{})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		r.NoError(<-errs)
+	}
+	a.Equal(int32(2), atomic.LoadInt32(&max))
+}
+
+func TestReadRelativeLinks(t *testing.T) {
+	feedWithLink := func(channelLink string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Relative News</title>` + channelLink +
+			`<item><guid>guid_1</guid><link>/story/1</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item></channel></rss>`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feeds/with-link", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, feedWithLink("<link>https://example.com/news/</link>"))
+	})
+	mux.HandleFunc("/feeds/without-link", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, feedWithLink(""))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("resolves against the channel link", func(t *testing.T) {
+		r := require.New(t)
+		channel, err := NewFeed().Read(server.URL+"/feeds/with-link", types.ReadOptions{})
+		r.NoError(err)
+		r.Len(channel.Articles, 1, "unexpected number of articles")
+		assert.Equal(t, "https://example.com/story/1", channel.Articles[0].Link)
+	})
+
+	t.Run("resolves against the feed address", func(t *testing.T) {
+		r := require.New(t)
+		channel, err := NewFeed().Read(server.URL+"/feeds/without-link", types.ReadOptions{})
+		r.NoError(err)
+		r.Len(channel.Articles, 1, "unexpected number of articles")
+		assert.Equal(t, server.URL+"/story/1", channel.Articles[0].Link)
+	})
+}
+
+func TestReadPermaLinkGUIDs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>PermaLink News</title>`+
+			`<item><guid isPermaLink="true">https://example.com/story/1</guid><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`+
+			`<item><guid isPermaLink="false">https://example.com/story/2</guid><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`+
+			`<item><guid isPermaLink="true">https://example.com/story/3</guid><link>https://example.com/three</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`+
+			`</channel></rss>`)
+	}))
+	defer server.Close()
+
+	r := require.New(t)
+	a := assert.New(t)
+	channel, err := NewFeed().Read(server.URL, types.ReadOptions{})
+	r.NoError(err)
+	r.Len(channel.Articles, 3, "unexpected number of articles")
+	a.Equal("https://example.com/story/1", channel.Articles[0].Link)
+	a.Empty(channel.Articles[1].Link)
+	a.Equal("https://example.com/three", channel.Articles[2].Link)
+}
+
+func TestReadWebSubHub(t *testing.T) {
+	hub := `<atom:link xmlns:atom="http://www.w3.org/2005/Atom" rel="hub" href="https://hub.example.com/"/>`
+	self := `<atom:link xmlns:atom="http://www.w3.org/2005/Atom" rel="self" href="https://example.com/feed.xml"/>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hub":
+			fmt.Fprint(w, strings.Replace(testFeedBody, "<channel>", "<channel>"+self+hub, 1))
+		case "/hub-without-self":
+			fmt.Fprint(w, strings.Replace(testFeedBody, "<channel>", "<channel>"+hub, 1))
+		default:
+			fmt.Fprint(w, testFeedBody)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("hub and topic are discovered", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := NewFeed().Read(server.URL+"/hub", types.ReadOptions{})
+		r.NoError(err)
+		a.Equal("https://hub.example.com/", channel.Hub)
+		a.Equal("https://example.com/feed.xml", channel.Topic)
+	})
+
+	t.Run("feeds without hub", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := NewFeed().Read(server.URL, types.ReadOptions{})
+		r.NoError(err)
+		a.Empty(channel.Hub)
+		a.Empty(channel.Topic)
+	})
+
+	t.Run("topic defaults to the address read", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := NewFeed().Read(server.URL+"/hub-without-self", types.ReadOptions{})
+		r.NoError(err)
+		a.Equal("https://hub.example.com/", channel.Hub)
+		a.Equal(server.URL+"/hub-without-self", channel.Topic)
+	})
+}
+
+func TestReadPages(t *testing.T) {
+	page := func(next string, items ...string) string {
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Paged News</title>`)
+		if next != "" {
+			fmt.Fprintf(&b, `<atom:link xmlns:atom="http://www.w3.org/2005/Atom" rel="next" href="%s"/>`, next)
+		}
+		for _, item := range items {
+			fmt.Fprintf(&b, `<item><guid>%s</guid><title>%s</title><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`, item, item)
+		}
+		b.WriteString(`</channel></rss>`)
+		return b.String()
+	}
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprint(w, page("?page=2", "item_1", "item_2"))
+		case "2":
+			fmt.Fprint(w, page("?page=3", "item_3"))
+		default:
+			// Pages linking back to a visited page must not loop.
+			fmt.Fprint(w, page("?page=2", "item_4"))
+		}
+	}))
+	defer server.Close()
+	read := func(r *require.Assertions, feed *Feed, paginate bool) []string {
+		mu.Lock()
+		requests = 0
+		mu.Unlock()
+		channel, err := feed.Read(server.URL, types.ReadOptions{Paginate: paginate})
+		r.NoError(err)
+		guids := []string{}
+		for _, article := range channel.Articles {
+			guids = append(guids, article.GUID)
+		}
+		return guids
+	}
+
+	t.Run("items from both pages are returned", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		a.Equal([]string{"item_1", "item_2", "item_3"}, read(r, NewFeed(WithMaxPages(2)), true))
+		a.Equal(2, requests)
+	})
+
+	t.Run("pages are followed until no new page is linked", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		a.Equal([]string{"item_1", "item_2", "item_3", "item_4"}, read(r, NewFeed(WithMaxPages(10)), true))
+		a.Equal(3, requests)
+	})
+
+	t.Run("only the first page is read without paginating", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		a.Equal([]string{"item_1", "item_2"}, read(r, NewFeed(WithMaxPages(2)), false))
+		a.Equal([]string{"item_1", "item_2"}, read(r, NewFeed(), true))
+		a.Equal(1, requests)
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("converts the provided body", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		channel, err := NewFeed().Parse("https://example.com/feed.xml", []byte(testFeedBody))
+		r.NoError(err)
+		a.Equal("https://example.com/feed.xml", channel.Address)
+		a.NotEmpty(channel.Articles)
+		a.False(channel.Moved)
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		_, err := NewFeed().Parse("https://example.com/feed.xml", []byte("  "))
+		assert.Equal(t, ErrEmptyFeed, err)
+	})
+}
+
+func TestReadHostDelay(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, testFeedBody)
+	}))
+	defer server.Close()
+	delay := 50 * time.Millisecond
+
+	t.Run("sequential reads", func(t *testing.T) {
+		r := require.New(t)
+		starts = nil
+		rssf := NewFeed(WithHostDelay(delay))
+		for _, path := range []string{"/first", "/second"} {
+			_, err := rssf.Read(server.URL+path, types.ReadOptions{})
+			r.NoError(err)
+		}
+		r.Len(starts, 2, "unexpected number of requests")
+		assert.GreaterOrEqual(t, starts[1].Sub(starts[0]), delay)
+	})
+
+	t.Run("concurrent reads", func(t *testing.T) {
+		r := require.New(t)
+		starts = nil
+		rssf := NewFeed(WithHostDelay(delay))
+		errs := make(chan error, 2)
+		for _, path := range []string{"/first", "/second"} {
+			path := path
+			go func() {
+				_, err := rssf.Read(server.URL+path, types.ReadOptions{})
+				errs <- err
+			}()
+		}
+		r.NoError(<-errs)
+		r.NoError(<-errs)
+		mu.Lock()
+		defer mu.Unlock()
+		r.Len(starts, 2, "unexpected number of requests")
+		gap := starts[1].Sub(starts[0])
+		if gap < 0 {
+			gap = -gap
+		}
+		// Requests are received slightly after the reads start, so some slack is allowed.
+		assert.GreaterOrEqual(t, gap, delay-5*time.Millisecond)
+	})
+}
+
+func TestReadRobots(t *testing.T) {
+	var feedRequests int32
+	var mu sync.Mutex
+	userAgents := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgents[r.URL.Path] = r.UserAgent()
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\nAllow: /private/public\n")
+		default:
+			atomic.AddInt32(&feedRequests, 1)
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, testFeedBody)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("fails for disallowed addresses", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		atomic.StoreInt32(&feedRequests, 0)
+		_, err := NewFeed(WithRobots(true)).Read(server.URL+"/private/feed", types.ReadOptions{})
+		r.Error(err)
+		a.True(errors.Is(err, ErrDisallowedByRobots), "unexpected error: %v", err)
+		a.Equal(int32(0), atomic.LoadInt32(&feedRequests), "disallowed addresses must not be fetched")
+	})
+
+	t.Run("reads allowed addresses", func(t *testing.T) {
+		r := require.New(t)
+		rssf := NewFeed(WithRobots(true))
+		_, err := rssf.Read(server.URL+"/feed", types.ReadOptions{})
+		r.NoError(err)
+		_, err = rssf.Read(server.URL+"/private/public", types.ReadOptions{})
+		r.NoError(err)
+	})
+
+	t.Run("identifies with the reader user agent", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := NewFeed(WithRobots(true)).Read(server.URL+"/feed", types.ReadOptions{})
+		r.NoError(err)
+		mu.Lock()
+		defer mu.Unlock()
+		a.Equal(robotsUserAgent, userAgents["/robots.txt"])
+		a.Equal(robotsUserAgent, userAgents["/feed"])
+	})
+
+	t.Run("ignores robots.txt by default", func(t *testing.T) {
+		r := require.New(t)
+		_, err := NewFeed().Read(server.URL+"/private/feed", types.ReadOptions{})
+		r.NoError(err)
+	})
+}
+
+func TestParseRobots(t *testing.T) {
+	a := assert.New(t)
+	robots := `# comment
+User-agent: other
+Disallow: /
+
+User-agent: znews
+User-agent: another
+Disallow: /feeds/ # no feeds
+Allow: /feeds/open
+
+User-agent: *
+Disallow:
+`
+	rules := parseRobots(strings.NewReader(robots), &robotsRules{})
+	a.True(rules.allowed("/news"))
+	a.False(rules.allowed("/feeds/closed"))
+	a.True(rules.allowed("/feeds/open/rss"))
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminPath is the path the administrative endpoints are served under when enabled.
+const adminPath = "/admin"
+
+// WithAdminToken enables the administrative endpoints under /admin, which require the provided
+// token to be sent as a bearer token. They are not served without a token.
+func WithAdminToken(token string) Option {
+	return func(s *Service) {
+		s.adminToken = token
+	}
+}
+
+// setupAdminRoutes adds the administrative endpoints to the router, guarded by the admin token.
+func (s *Service) setupAdminRoutes(r *gin.Engine) {
+	admin := r.Group(adminPath, s.requireAdmin)
+	admin.POST("/reindex", s.reindexArticles)
+	admin.POST("/feeds/rename-category", s.renameFeedCategory)
+	admin.POST("/articles/reset", s.resetArticles)
+	admin.POST("/articles/refresh-content", s.refreshArticlesContent)
+}
+
+// requireAdmin rejects the requests not authenticated with the admin token.
+func (s *Service) requireAdmin(c *gin.Context) {
+	header := c.GetHeader("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "unauthorized",
+		})
+		return
+	}
+	c.Next()
+}
+
+// RenameCategoryArgs represents the arguments in a rename feed category request.
+type RenameCategoryArgs struct {
+	Old string `json:"old" binding:"required"`
+	New string `json:"new" binding:"required"`
+}
+
+// renameFeedCategory moves all feeds in a category to another one, returning how many were renamed.
+func (s *Service) renameFeedCategory(c *gin.Context) {
+	var args RenameCategoryArgs
+	if c.BindJSON(&args) != nil || args.Old == args.New {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"renamed": s.feedStore.RenameCategory(args.Old, args.New),
+	})
+}
+
+// reindexArticles repairs the order of the stored articles, returning how many were out of place.
+func (s *Service) reindexArticles(c *gin.Context) {
+	moved, err := s.articleStore.Reindex()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"moved": moved,
+	})
+}
+
+// resetArticles removes all stored articles, along with their state and tombstones, keeping the
+// feeds. Returns how many articles were cleared.
+func (s *Service) resetArticles(c *gin.Context) {
+	cleared, err := s.articleStore.Reset()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"cleared": cleared,
+	})
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"net/http"
+
+	"../types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BulkFeedArgs represents a feed in a bulk create feeds request.
+type BulkFeedArgs struct {
+	Provider string `json:"provider"`
+	Category string `json:"category"`
+	Address  string `json:"address"`
+}
+
+// BulkFeedResult reports the outcome of creating a feed in a bulk create feeds request. Created is
+// set when the feed was created by the request, otherwise Feed is the existing feed with the same
+// address. Entries that could not be created report an Error instead.
+type BulkFeedResult struct {
+	Address string
+	Feed    *types.Feed
+	Created bool
+	Error   string
+}
+
+// bulkFeedKey identifies the feeds that a bulk create feeds request doesn't create twice.
+type bulkFeedKey struct {
+	address  string
+	category string
+}
+
+// createFeedsBulk creates the feeds in the request body, reporting the outcome of each of them in
+// the same order. Addresses are canonicalized, so feeds whose address and category match an existing
+// feed, or a previous entry, are reported as already existing instead of being created again.
+func (s *Service) createFeedsBulk(c *gin.Context) {
+	var args []BulkFeedArgs
+	if c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	feeds, err := s.feedStore.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	existing := map[bulkFeedKey]*types.Feed{}
+	for _, feed := range feeds {
+		if address, ok := canonicalAddress(feed.Address); ok {
+			existing[bulkFeedKey{address: address, category: feed.Category}] = feed
+		}
+	}
+	results := make([]*BulkFeedResult, 0, len(args))
+	for _, arg := range args {
+		result := &BulkFeedResult{Address: arg.Address}
+		results = append(results, result)
+		address, ok := canonicalAddress(arg.Address)
+		switch {
+		case !ok:
+			result.Error = "invalid address"
+			continue
+		case arg.Provider == "":
+			result.Error = "missing provider"
+			continue
+		case s.insecure(address):
+			result.Error = errInsecureFeed.Error()
+			continue
+		}
+		result.Address = address
+		key := bulkFeedKey{address: address, category: arg.Category}
+		if feed, ok := existing[key]; ok {
+			result.Feed = feed
+			continue
+		}
+		feed, err := s.feedStore.Create(&types.Feed{
+			Provider: arg.Provider,
+			Category: arg.Category,
+			Address:  address,
+		})
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		existing[key] = feed
+		result.Feed = feed
+		result.Created = true
+	}
+	c.JSON(http.StatusOK, results)
+}
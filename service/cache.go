@@ -0,0 +1,62 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithCacheControl sets the Cache-Control header of the responses to GET requests for each route,
+// keyed by the path the route is registered with, such as "/articles/:id". Only successful responses
+// get the header, so errors are never cached. Routes without a rule get no header.
+func WithCacheControl(rules map[string]string) Option {
+	return func(s *Service) {
+		s.cacheRules = rules
+	}
+}
+
+// cacheControl is a middleware setting the Cache-Control header configured for the route of the
+// request.
+func (s *Service) cacheControl(c *gin.Context) {
+	value, ok := s.cacheRules[c.FullPath()]
+	if !ok || c.Request.Method != http.MethodGet {
+		c.Next()
+		return
+	}
+	c.Writer = &cacheControlWriter{ResponseWriter: c.Writer, value: value}
+	c.Next()
+}
+
+// cacheControlWriter sets the Cache-Control header once the status of the response is known.
+type cacheControlWriter struct {
+	gin.ResponseWriter
+	value string
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	w.setHeader(code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheControlWriter) Write(data []byte) (int, error) {
+	if !w.Written() {
+		w.setHeader(w.Status())
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheControlWriter) WriteString(s string) (int, error) {
+	if !w.Written() {
+		w.setHeader(w.Status())
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// setHeader sets the header for successful responses, removing it otherwise.
+func (w *cacheControlWriter) setHeader(code int) {
+	if code < http.StatusBadRequest {
+		w.Header().Set("Cache-Control", w.value)
+	} else {
+		w.Header().Del("Cache-Control")
+	}
+}
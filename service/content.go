@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"../rssreader/converters"
+	"../types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// contentTimeout is the maximum time allowed for fetching the full text of an article.
+	contentTimeout = 30 * time.Second
+	// maxContentSize is the maximum size in bytes of the full text fetched for an article.
+	maxContentSize = 5 << 20
+	// defaultContentRefreshConcurrency is the default number of articles whose full text is fetched at
+	// the same time when refreshing the content of many articles.
+	defaultContentRefreshConcurrency = 2
+)
+
+// ContentFetcher describes the functionality needed to fetch the full text of an article from its
+// link, such as a readability extractor or a headless browser service.
+type ContentFetcher interface {
+	Fetch(ctx context.Context, url string) (string, error)
+}
+
+// pageFetcher is implemented by the content fetchers that also report the canonical link declared by
+// the page they fetched, which can't be found in the text they return.
+type pageFetcher interface {
+	FetchPage(ctx context.Context, url string) (text string, canonicalLink string, err error)
+}
+
+// HTTPContentFetcher fetches the full text of articles by requesting their link, returning the text
+// extracted from the page, whose body is read up to the maximum content size.
+type HTTPContentFetcher struct {
+	Client *http.Client
+}
+
+// Fetch requests the provided URL and returns the text of its page.
+func (f *HTTPContentFetcher) Fetch(ctx context.Context, url string) (string, error) {
+	text, _, err := f.FetchPage(ctx, url)
+	return text, err
+}
+
+// FetchPage requests the provided URL and returns the text of its page along with the canonical link
+// it declares, if any.
+func (f *HTTPContentFetcher) FetchPage(ctx context.Context, url string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	res, err := f.Client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxContentSize))
+	if err != nil {
+		return "", "", err
+	}
+	page := string(body)
+	return extractText(page), canonicalLink(page, url), nil
+}
+
+// WithContentFetcher sets the fetcher used for getting the full text of the articles on demand,
+// which requests their link over HTTP by default.
+func WithContentFetcher(f ContentFetcher) Option {
+	return func(s *Service) {
+		s.contentFetcher = f
+	}
+}
+
+// WithMaxFullTextLength limits the size in bytes of the full text fetched for the articles, which is
+// cut on a rune boundary and flagged as truncated like the bodies read from the feeds. Zero means no
+// limit.
+func WithMaxFullTextLength(n int) Option {
+	return func(s *Service) {
+		s.maxFullText = n
+	}
+}
+
+var (
+	// linkTagPattern matches the link tags of an html page.
+	linkTagPattern = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	// attributePattern matches the attributes of an html tag, whose value may be quoted or not.
+	attributePattern = regexp.MustCompile(`(?is)([a-z][a-z0-9-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// canonicalLink returns the canonical URL declared by the html page through a link tag with
+// rel="canonical", resolved against the provided base URL. Returns an empty string if the page
+// declares none, such as when the fetcher returns the extracted text instead of the page.
+func canonicalLink(page string, base string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	for _, tag := range linkTagPattern.FindAllString(page, -1) {
+		attributes := map[string]string{}
+		for _, m := range attributePattern.FindAllStringSubmatch(tag, -1) {
+			attributes[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+		}
+		canonical := false
+		for _, rel := range strings.Fields(attributes["rel"]) {
+			canonical = canonical || strings.EqualFold(rel, "canonical")
+		}
+		href := strings.TrimSpace(attributes["href"])
+		if !canonical || href == "" {
+			continue
+		}
+		link, err := baseURL.Parse(href)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			continue
+		}
+		return link.String()
+	}
+	return ""
+}
+
+var (
+	// hiddenElementPattern matches the comments and the elements of an html page whose content is not
+	// shown as text.
+	hiddenElementPattern = regexp.MustCompile(`(?is)<!--.*?-->|<script\b.*?</script\s*>|<style\b.*?</style\s*>|` +
+		`<noscript\b.*?</noscript\s*>|<template\b.*?</template\s*>|<head\b.*?</head\s*>`)
+	// articleElementPattern matches the content of the article element of an html page.
+	articleElementPattern = regexp.MustCompile(`(?is)<article\b[^>]*>(.*?)</article\s*>`)
+	// bodyElementPattern matches the content of the body element of an html page.
+	bodyElementPattern = regexp.MustCompile(`(?is)<body\b[^>]*>(.*?)(?:</body\s*>|$)`)
+	// lineBreakPattern matches the html tags breaking lines of text, such as the ones of blocks.
+	lineBreakPattern = regexp.MustCompile(`(?i)<(?:br|/?(?:p|div|h[1-6]|li|tr|blockquote|pre|section))\b[^>]*>`)
+	// tagPattern matches any html tag.
+	tagPattern = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// extractText returns the text of the provided html page, taken from its article element when it has
+// one, or from its body otherwise. Tags are removed and entities unescaped, keeping one line of text
+// per paragraph.
+func extractText(page string) string {
+	page = hiddenElementPattern.ReplaceAllString(page, "")
+	if m := articleElementPattern.FindStringSubmatch(page); m != nil {
+		page = m[1]
+	} else if m := bodyElementPattern.FindStringSubmatch(page); m != nil {
+		page = m[1]
+	}
+	page = lineBreakPattern.ReplaceAllString(page, "\n")
+	page = html.UnescapeString(tagPattern.ReplaceAllString(page, ""))
+	var lines []string
+	for _, line := range strings.Split(page, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fetchContent fetches the full text of the article from its link along with the canonical link
+// declared by its page, which is looked for in the full text of fetchers not reporting it.
+func (s *Service) fetchContent(ctx context.Context, article *types.Article) (string, string, error) {
+	if f, ok := s.contentFetcher.(pageFetcher); ok {
+		return f.FetchPage(ctx, article.Link)
+	}
+	fullText, err := s.contentFetcher.Fetch(ctx, article.Link)
+	if err != nil {
+		return "", "", err
+	}
+	return fullText, canonicalLink(fullText, article.Link), nil
+}
+
+// updateFullText stores the full text returned by the fetcher for the article as it is, only cut at
+// the maximum length, along with the canonical link declared by its page.
+func (s *Service) updateFullText(article *types.Article, fullText string, canonical string) (*types.Article, error) {
+	fullText, truncated := converters.Truncate(fullText, s.maxFullText)
+	return s.articleStore.UpdateFullText(article.ID, fullText, canonical, truncated)
+}
+
+// fetchFullText fetches the full text of an article from its link, storing it in the article along
+// with the canonical link declared by the page.
+func (s *Service) fetchFullText(c *gin.Context) {
+	var args GetArticleArgs
+	if c.BindUri(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	article, err := s.articleStore.Get(args.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if article.Link == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "article has no link",
+		})
+		return
+	}
+	fullText, canonical, err := s.fetchContent(c.Request.Context(), article)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("could not fetch content: %v", err),
+		})
+		return
+	}
+	article, err = s.updateFullText(article, fullText, canonical)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, s.linkArticle(article))
+}
+
+// WithContentRefresh sets the number of articles whose full text is fetched at the same time when
+// refreshing the content of many articles, so their sites are not hammered, along with the time
+// allowed for each fetch. A zero timeout leaves each fetch limited only by the fetcher.
+func WithContentRefresh(concurrency int, timeout time.Duration) Option {
+	return func(s *Service) {
+		s.fetchWorkers = concurrency
+		s.fetchTimeout = timeout
+	}
+}
+
+// RefreshContentArgs represents the arguments in a refresh articles content request. Feed limits the
+// refresh to the articles of a feed, and Missing to the articles without full text.
+type RefreshContentArgs struct {
+	Feed    string `form:"feed"`
+	Missing bool   `form:"missing"`
+}
+
+// refreshArticlesContent fetches the full text of the stored articles having a link, returning how
+// many were refreshed and how many failed. The job stops when the request is cancelled.
+func (s *Service) refreshArticlesContent(c *gin.Context) {
+	var args RefreshContentArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	articles, err := s.articleStore.ListFiltered("", 0, types.ArticleFilter{Feed: args.Feed}, types.OrderPublished)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	var selected []*types.Article
+	for _, article := range articles {
+		if article.Link != "" && (!args.Missing || strings.TrimSpace(article.FullText) == "") {
+			selected = append(selected, article)
+		}
+	}
+	refreshed, failed := s.refreshContent(c.Request.Context(), selected)
+	c.JSON(http.StatusOK, gin.H{
+		"refreshed": refreshed,
+		"failed":    failed,
+	})
+}
+
+// refreshContent fetches and stores the full text of the provided articles, running at most the
+// configured number of fetches at the same time. Once the context is cancelled, no more fetches are
+// started and the running ones are cancelled, which are not counted as failed. Returns the number of
+// articles refreshed and failed.
+func (s *Service) refreshContent(ctx context.Context, articles []*types.Article) (int, int) {
+	concurrency := s.fetchWorkers
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var mu sync.Mutex
+	var refreshed, failed int
+	jobs := make(chan *types.Article)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for article := range jobs {
+				// Articles may still be received while the job is being cancelled.
+				if ctx.Err() != nil {
+					continue
+				}
+				err := s.refreshArticleContent(ctx, article)
+				if ctx.Err() != nil {
+					continue
+				}
+				mu.Lock()
+				if err != nil {
+					failed++
+				} else {
+					refreshed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+send:
+	for _, article := range articles {
+		select {
+		case jobs <- article:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return refreshed, failed
+}
+
+// refreshArticleContent fetches and stores the full text of the article, within the configured
+// timeout.
+func (s *Service) refreshArticleContent(ctx context.Context, article *types.Article) error {
+	if s.fetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.fetchTimeout)
+		defer cancel()
+	}
+	fullText, canonical, err := s.fetchContent(ctx, article)
+	if err != nil {
+		return err
+	}
+	_, err = s.updateFullText(article, fullText, canonical)
+	return err
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// enclosureTimeout is the maximum time allowed for fetching an enclosure.
+	enclosureTimeout = 30 * time.Second
+	// maxEnclosureSize is the maximum size in bytes of the enclosures served through the proxy.
+	maxEnclosureSize = 10 << 20
+	// enclosureCacheControl allows clients to cache proxied enclosures, which rarely change.
+	enclosureCacheControl = "public, max-age=86400"
+)
+
+// GetEnclosureArgs represents the arguments in a get enclosure request.
+type GetEnclosureArgs struct {
+	ID    string `uri:"id" binding:"required"`
+	Index int    `uri:"index"`
+}
+
+// getEnclosure streams the content of an article enclosure, so clients can show media whose
+// origin doesn't allow hotlinking.
+func (s *Service) getEnclosure(c *gin.Context) {
+	var args GetEnclosureArgs
+	if c.BindUri(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	article, err := s.articleStore.Get(args.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if args.Index < 0 || args.Index >= len(article.Enclosures) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "enclosure not found",
+		})
+		return
+	}
+	enclosure := article.Enclosures[args.Index]
+
+	res, err := s.enclosureClient.Get(enclosure.URL)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("could not fetch enclosure: %v", err),
+		})
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("could not fetch enclosure: unexpected status code %d", res.StatusCode),
+		})
+		return
+	}
+	if res.ContentLength > s.maxEnclosureSize {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "could not fetch enclosure: maximum size exceeded",
+		})
+		return
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = enclosure.Type
+	}
+	if !isMediaType(contentType) {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("could not fetch enclosure: unsupported content type %q", contentType),
+		})
+		return
+	}
+
+	body := io.Reader(res.Body)
+	size := res.ContentLength
+	if size < 0 {
+		// When the size is unknown, the content is read up to the maximum size to check it fits, so
+		// larger enclosures are not served cut as if they were complete.
+		content, err := io.ReadAll(io.LimitReader(res.Body, s.maxEnclosureSize+1))
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": fmt.Sprintf("could not fetch enclosure: %v", err),
+			})
+			return
+		}
+		if int64(len(content)) > s.maxEnclosureSize {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": "could not fetch enclosure: maximum size exceeded",
+			})
+			return
+		}
+		body = bytes.NewReader(content)
+		size = int64(len(content))
+	}
+
+	// Enclosures are served from the same origin as the UI, so they are downloaded rather than
+	// rendered when opened directly, and their content type is never sniffed.
+	headers := map[string]string{
+		"Cache-Control":          enclosureCacheControl,
+		"Content-Disposition":    "attachment",
+		"X-Content-Type-Options": "nosniff",
+	}
+	for _, h := range []string{"ETag", "Last-Modified"} {
+		if v := res.Header.Get(h); v != "" {
+			headers[h] = v
+		}
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, io.LimitReader(body, size), headers)
+}
+
+// isMediaType returns whether the provided content type is an image, audio or video type.
+func isMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"time"
+
+	"../types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// mimeCSV is the content type of exported articles.
+	mimeCSV = "text/csv"
+	// exportPageSize is the number of articles read from the store at once when exporting, so the
+	// articles are written as they are read instead of being listed all at once.
+	exportPageSize = 100
+)
+
+// exportHeader holds the columns of exported articles.
+var exportHeader = []string{"id", "feedId", "guid", "title", "link", "publishDate", "author", "categories"}
+
+// exportArticles streams the articles matching the listing filters as CSV, in the requested order.
+// Categories are joined by commas, and a snapshot is taken so articles created while exporting are
+// left out.
+func (s *Service) exportArticles(c *gin.Context) {
+	var args ListArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.tooManyCategories(args.Categories) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "too many category filters",
+		})
+		return
+	}
+	order, ok := articleOrder(args)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	filter := types.ArticleFilter{
+		Feed:          args.Feed,
+		Categories:    args.Categories,
+		Labels:        args.Labels,
+		EnclosureType: args.EnclosureType,
+		NoEnclosures:  args.NoEnclosures,
+		HasFullText:   args.HasFullText,
+		HasImage:      args.HasImage,
+		HideFuture:    args.HideFuture,
+		Batch:         args.Batch,
+		Snapshot:      args.Snapshot,
+	}
+	if filter.Snapshot == "" {
+		filter.Snapshot = s.articleStore.Snapshot()
+	}
+	articles, err := s.articleStore.ListFiltered("", exportPageSize, filter, order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", mimeCSV+"; charset=utf-8")
+	c.Header(snapshotHeader, filter.Snapshot)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	w.Write(exportHeader)
+	for len(articles) > 0 {
+		for _, article := range articles {
+			w.Write(exportRow(article))
+		}
+		w.Flush()
+		if w.Error() != nil || len(articles) < exportPageSize {
+			return
+		}
+		c.Writer.Flush()
+		// Errors can no longer be reported once the response started, so the export is cut short.
+		articles, err = s.articleStore.ListFiltered(articles[len(articles)-1].ID, exportPageSize, filter, order)
+		if err != nil {
+			return
+		}
+	}
+	w.Flush()
+}
+
+// exportRow returns the values of the exported columns for the article.
+func exportRow(article *types.Article) []string {
+	return []string{
+		article.ID,
+		article.FeedID,
+		article.GUID,
+		article.Title,
+		article.Link,
+		article.PublishDate.Format(time.RFC3339),
+		article.Author,
+		strings.Join(article.Categories, ","),
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"../graphql"
+	"../types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxGraphQLSize is the maximum size in bytes of the GraphQL requests accepted.
+const maxGraphQLSize = 1 << 20
+
+// GraphQLArgs represents the arguments in a GraphQL request.
+type GraphQLArgs struct {
+	Query     string                 `json:"query" binding:"required"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+func (s *Service) queryGraphQL(c *gin.Context) {
+	var args GraphQLArgs
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxGraphQLSize)
+	if c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	data, err := graphql.Execute(s.graphqlSchema(), args.Query, args.Variables)
+	if err != nil {
+		status := http.StatusOK
+		var syntaxErr *graphql.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"errors": []gin.H{{"message": err.Error()}},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+	})
+}
+
+var enclosureObject = &graphql.Object{
+	Name: "Enclosure",
+	Fields: map[string]*graphql.Field{
+		"url":  enclosureField(func(e *types.Enclosure) interface{} { return e.URL }),
+		"type": enclosureField(func(e *types.Enclosure) interface{} { return e.Type }),
+	},
+}
+
+var articleObject = &graphql.Object{
+	Name: "Article",
+	Fields: map[string]*graphql.Field{
+		"id":          articleField(func(a *types.Article) interface{} { return a.ID }),
+		"feedId":      articleField(func(a *types.Article) interface{} { return a.FeedID }),
+		"provider":    articleField(func(a *types.Article) interface{} { return a.Provider }),
+		"guid":        articleField(func(a *types.Article) interface{} { return a.GUID }),
+		"title":       articleField(func(a *types.Article) interface{} { return a.Title }),
+		"link":        articleField(func(a *types.Article) interface{} { return a.Link }),
+		"comments":    articleField(func(a *types.Article) interface{} { return a.Comments }),
+		"publishDate": articleField(func(a *types.Article) interface{} { return a.PublishDate.Format(time.RFC3339) }),
+		"categories":  articleField(func(a *types.Article) interface{} { return a.Categories }),
+		"description": articleField(func(a *types.Article) interface{} { return a.Description }),
+		"author":      articleField(func(a *types.Article) interface{} { return a.Author }),
+		"content":     articleField(func(a *types.Article) interface{} { return a.Content }),
+		"fullText":    articleField(func(a *types.Article) interface{} { return a.FullText }),
+		"read":        articleField(func(a *types.Article) interface{} { return a.Read }),
+		"starred":     articleField(func(a *types.Article) interface{} { return a.Starred }),
+		"labels":      articleField(func(a *types.Article) interface{} { return a.Labels }),
+		"enclosures": &graphql.Field{
+			Type: enclosureObject,
+			Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+				return source.(*types.Article).Enclosures, nil
+			},
+		},
+	},
+}
+
+var feedObject = &graphql.Object{
+	Name: "Feed",
+	Fields: map[string]*graphql.Field{
+		"id":        feedField(func(f *types.Feed) interface{} { return f.ID }),
+		"provider":  feedField(func(f *types.Feed) interface{} { return f.Provider }),
+		"category":  feedField(func(f *types.Feed) interface{} { return f.Category }),
+		"address":   feedField(func(f *types.Feed) interface{} { return f.Address }),
+		"fallbacks": feedField(func(f *types.Feed) interface{} { return f.Fallbacks }),
+	},
+}
+
+// graphqlSchema returns the root object of the GraphQL schema, resolving against the stores.
+func (s *Service) graphqlSchema() *graphql.Object {
+	return &graphql.Object{
+		Name: "Query",
+		Fields: map[string]*graphql.Field{
+			"articles": &graphql.Field{
+				Type:    articleObject,
+				Resolve: s.resolveArticles,
+			},
+			"feeds": &graphql.Field{
+				Type: feedObject,
+				Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+					return s.feedStore.List()
+				},
+			},
+		},
+	}
+}
+
+// resolveArticles lists articles accepting the filter, cursor and pageSize arguments, where the
+// filter may hold a feed, lists of categories and labels and an enclosure type. Like when listing
+// articles, the default page size is used when none is provided.
+func (s *Service) resolveArticles(source interface{}, args map[string]interface{}) (interface{}, error) {
+	var filter types.ArticleFilter
+	if v, ok := args["filter"]; ok && v != nil {
+		f, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid filter")
+		}
+		var err error
+		if filter.Feed, err = stringArg(f, "feed"); err != nil {
+			return nil, err
+		}
+		if filter.Categories, err = stringsArg(f, "categories"); err != nil {
+			return nil, err
+		}
+		if s.tooManyCategories(filter.Categories) {
+			return nil, errors.New("too many category filters")
+		}
+		if filter.Labels, err = stringsArg(f, "labels"); err != nil {
+			return nil, err
+		}
+		if filter.EnclosureType, err = stringArg(f, "enclosureType"); err != nil {
+			return nil, err
+		}
+	}
+	cursor, err := stringArg(args, "cursor")
+	if err != nil {
+		return nil, err
+	}
+	pageSize, err := intArg(args, "pageSize")
+	if err != nil {
+		return nil, err
+	}
+	// The store lists all articles for a zero page size, which is never requested through GraphQL.
+	if pageSize <= 0 {
+		pageSize = s.defaultPageSize
+	}
+	return s.articleStore.ListFiltered(cursor, pageSize, filter, types.OrderPublished)
+}
+
+func articleField(get func(*types.Article) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+			return get(source.(*types.Article)), nil
+		},
+	}
+}
+
+func enclosureField(get func(*types.Enclosure) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+			return get(source.(*types.Enclosure)), nil
+		},
+	}
+}
+
+func feedField(get func(*types.Feed) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Resolve: func(source interface{}, args map[string]interface{}) (interface{}, error) {
+			return get(source.(*types.Feed)), nil
+		},
+	}
+}
+
+func stringArg(args map[string]interface{}, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", errors.New("invalid " + name)
+}
+
+func stringsArg(args map[string]interface{}, name string) ([]string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		// A single value is accepted in place of a list, as GraphQL input coercion does.
+		return []string{v}, nil
+	case []interface{}:
+		res := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, errors.New("invalid " + name)
+			}
+			res = append(res, s)
+		}
+		return res, nil
+	}
+	return nil, errors.New("invalid " + name)
+}
+
+// intArg reads an integer argument, which is decoded as a float when provided through variables.
+func intArg(args map[string]interface{}, name string) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, errors.New("invalid " + name)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"net/http"
+	"sync"
+
+	"../types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxLoadJobs is the maximum number of finished load jobs kept for their status to be queried, beyond
+// which the oldest ones are forgotten.
+const maxLoadJobs = 1000
+
+// loadRequest is a feed load waiting in the queue.
+type loadRequest struct {
+	job   *types.LoadJob
+	feed  *types.Feed
+	force bool
+}
+
+// loadQueue runs feed loads in the background with a bounded buffer, so bursts of load requests
+// neither fetch all feeds at once nor fail.
+type loadQueue struct {
+	feeder   Feeder
+	requests chan loadRequest
+
+	mu       sync.Mutex
+	jobs     map[string]*types.LoadJob
+	finished []string
+}
+
+// WithLoadQueue makes feed loads asynchronous, queueing up to the provided number of loads which are
+// run by the given number of workers. Loads requested while the queue is full are rejected.
+func WithLoadQueue(size int, workers int) Option {
+	return func(s *Service) {
+		s.loadQueueSize = size
+		s.loadWorkers = workers
+	}
+}
+
+// newLoadQueue returns a queue of the provided size whose loads are run by the given number of
+// workers.
+func newLoadQueue(feeder Feeder, size int, workers int) *loadQueue {
+	q := &loadQueue{
+		feeder:   feeder,
+		requests: make(chan loadRequest, size),
+		jobs:     map[string]*types.LoadJob{},
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// enqueue queues the load of the provided feed, returning its job, or false if the queue is full.
+func (q *loadQueue) enqueue(feed *types.Feed, force bool) (types.LoadJob, bool) {
+	job := &types.LoadJob{ID: uuid.New().String(), FeedID: feed.ID, Status: types.LoadJobQueued}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case q.requests <- loadRequest{job: job, feed: feed, force: force}:
+	default:
+		return types.LoadJob{}, false
+	}
+	q.jobs[job.ID] = job
+	return *job, true
+}
+
+// job returns a copy of the job with the provided ID, or false if it is unknown.
+func (q *loadQueue) job(ID string) (types.LoadJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[ID]
+	if !ok {
+		return types.LoadJob{}, false
+	}
+	return *job, true
+}
+
+// work runs the queued loads until the queue is closed.
+func (q *loadQueue) work() {
+	for req := range q.requests {
+		q.mu.Lock()
+		req.job.Status = types.LoadJobRunning
+		q.mu.Unlock()
+
+		summary, err := q.feeder.Consume(req.feed, req.force)
+
+		q.mu.Lock()
+		if err != nil {
+			req.job.Status = types.LoadJobFailed
+			req.job.Error = err.Error()
+		} else {
+			req.job.Status = types.LoadJobDone
+			req.job.Summary = summary
+		}
+		q.finished = append(q.finished, req.job.ID)
+		if len(q.finished) > maxLoadJobs {
+			delete(q.jobs, q.finished[0])
+			q.finished = q.finished[1:]
+		}
+		q.mu.Unlock()
+	}
+}
+
+// GetLoadJobArgs represents the arguments in a get load job request.
+type GetLoadJobArgs struct {
+	JobID string `uri:"jobId" binding:"required"`
+}
+
+// getLoadJob returns the status of a feed load made through the load queue.
+func (s *Service) getLoadJob(c *gin.Context) {
+	var args GetLoadJobArgs
+	if c.BindUri(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	var job types.LoadJob
+	ok := false
+	if s.loadQueue != nil {
+		job, ok = s.loadQueue.job(args.JobID)
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "job not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"encoding/xml"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"../types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxOPMLSize is the maximum size in bytes of the OPML documents accepted.
+const maxOPMLSize = 5 << 20
+
+// opmlDocument is an OPML document, of which only the outlines are used.
+type opmlDocument struct {
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+// opmlOutline is an outline of an OPML document. Outlines with an xmlUrl are feeds, while the ones
+// without it group their children, such as in categories.
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Category string        `xml:"category,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// SkippedOutline describes an outline of an OPML document that would not be imported, and why.
+type SkippedOutline struct {
+	Text    string
+	Address string
+	Reason  string
+}
+
+// ImportPreview reports the feeds that importing an OPML document would create, and the outlines
+// that would be skipped.
+type ImportPreview struct {
+	Feeds   []*types.Feed
+	Skipped []*SkippedOutline
+}
+
+// canonicalAddress returns the canonical form of a feed address, with a lowercase scheme and host,
+// no default port and no fragment, or false if it is not an absolute HTTP address.
+func canonicalAddress(address string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(address))
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 addresses keep their brackets without a port too.
+		host = "[" + host + "]"
+	}
+	u.Host = host
+	u.Fragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), true
+}
+
+// previewImport returns the feeds the outlines would create, skipping invalid outlines and the
+// addresses already present, either in the provided feeds or earlier in the outlines.
+func previewImport(outlines []opmlOutline, existing []*types.Feed) *ImportPreview {
+	seen := map[string]bool{}
+	for _, feed := range existing {
+		if address, ok := canonicalAddress(feed.Address); ok {
+			seen[address] = true
+		}
+	}
+	preview := &ImportPreview{Feeds: []*types.Feed{}, Skipped: []*SkippedOutline{}}
+	var walk func(outlines []opmlOutline, category string)
+	walk = func(outlines []opmlOutline, category string) {
+		for _, o := range outlines {
+			text := o.Title
+			if text == "" {
+				text = o.Text
+			}
+			if o.XMLURL == "" {
+				if len(o.Outlines) > 0 {
+					walk(o.Outlines, text)
+				} else {
+					preview.Skipped = append(preview.Skipped, &SkippedOutline{Text: text, Reason: "missing xmlUrl"})
+				}
+				continue
+			}
+			address, ok := canonicalAddress(o.XMLURL)
+			switch {
+			case !ok:
+				preview.Skipped = append(preview.Skipped, &SkippedOutline{Text: text, Address: o.XMLURL, Reason: "invalid address"})
+				continue
+			case seen[address]:
+				preview.Skipped = append(preview.Skipped, &SkippedOutline{Text: text, Address: o.XMLURL, Reason: "duplicate address"})
+				continue
+			}
+			seen[address] = true
+			feedCategory := o.Category
+			if feedCategory == "" {
+				feedCategory = category
+			}
+			preview.Feeds = append(preview.Feeds, &types.Feed{
+				Provider: text,
+				Category: feedCategory,
+				Address:  address,
+			})
+		}
+	}
+	walk(outlines, "")
+	return preview
+}
+
+// previewImportFeeds parses the OPML document in the request body and reports the feeds importing it
+// would create, without creating them.
+func (s *Service) previewImportFeeds(c *gin.Context) {
+	var doc opmlDocument
+	if err := xml.NewDecoder(io.LimitReader(c.Request.Body, maxOPMLSize)).Decode(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	feeds, err := s.feedStore.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, previewImport(doc.Outlines, feeds))
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prettyIndent is the indentation used for pretty-printed JSON responses.
+const prettyIndent = "  "
+
+// prettyJSON is a middleware indenting the JSON responses of requests having the pretty query
+// parameter set, which makes them easier to read when debugging. Only JSON responses are buffered,
+// while other responses, such as streamed exports and enclosures, are written as they are.
+func prettyJSON(c *gin.Context) {
+	if pretty, _ := strconv.ParseBool(c.Query("pretty")); !pretty {
+		c.Next()
+		return
+	}
+	w := &bufferedWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+	c.Writer = w.ResponseWriter
+	if !w.buffering() {
+		return
+	}
+
+	body := w.buf.Bytes()
+	if len(body) > 0 {
+		var indented bytes.Buffer
+		if json.Indent(&indented, body, "", prettyIndent) == nil {
+			indented.WriteByte('\n')
+			body = indented.Bytes()
+			w.Header().Del("Content-Length")
+		}
+	}
+	w.ResponseWriter.Write(body)
+}
+
+// bufferedWriter holds the body written to JSON responses, so it can be modified before being sent.
+// The body of other responses is written through.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf     bytes.Buffer
+	decided bool
+	json    bool
+}
+
+// buffering returns whether the body is buffered, which is decided from the content type of the
+// response the first time it is written or flushed.
+func (w *bufferedWriter) buffering() bool {
+	if !w.decided {
+		w.decided = true
+		w.json = strings.HasPrefix(w.Header().Get("Content-Type"), gin.MIMEJSON)
+	}
+	return w.json
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	if !w.buffering() {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	if !w.buffering() {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.buf.WriteString(s)
+}
+
+// Flush sends the body written so far, unless it is buffered.
+func (w *bufferedWriter) Flush() {
+	if !w.buffering() {
+		w.ResponseWriter.Flush()
+	}
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"../types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// mimeRSS is the content type used when rendering articles as an rss feed.
+	mimeRSS = "application/rss+xml"
+	// mimeJSONFeed is the content type used when rendering articles as a JSON Feed.
+	mimeJSONFeed = "application/feed+json"
+	// renderedFeedTitle is the title of the feeds rendered by the service.
+	renderedFeedTitle = "ZNews"
+	// jsonFeedVersion is the version of the JSON Feed specification the service renders.
+	jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+)
+
+// linkArticles returns copies of the articles with their self links set by linkArticle.
+func (s *Service) linkArticles(articles []*types.Article) []*types.Article {
+	res := make([]*types.Article, 0, len(articles))
+	for _, a := range articles {
+		res = append(res, s.linkArticle(a))
+	}
+	return res
+}
+
+// linkArticle returns a copy of the article with its self link set, relative to the base URL of the
+// service when configured, leaving the stored article intact.
+func (s *Service) linkArticle(article *types.Article) *types.Article {
+	if article == nil {
+		return nil
+	}
+	linked := *article
+	linked.SelfURL = s.baseURL + "/articles/" + url.PathEscape(article.ID)
+	return &linked
+}
+
+// articleFeed holds the details of the feed an article was read from, leaving out its credentials.
+type articleFeed struct {
+	ID       string
+	Provider string
+	Category string
+	Address  string
+}
+
+// expandedArticle is an article along with the details of its feed, which is nil for articles not
+// read from a feed or whose feed was deleted.
+type expandedArticle struct {
+	*types.Article
+	Feed *articleFeed `json:"feed"`
+}
+
+// expandFeed returns the article along with the details of its feed, as currently stored.
+func (s *Service) expandFeed(article *types.Article) *expandedArticle {
+	expanded := &expandedArticle{Article: article}
+	if article.FeedID == "" {
+		return expanded
+	}
+	if feed, err := s.feedStore.Get(article.FeedID); err == nil {
+		expanded.Feed = &articleFeed{
+			ID:       feed.ID,
+			Provider: feed.Provider,
+			Category: feed.Category,
+			Address:  feed.Address,
+		}
+	}
+	return expanded
+}
+
+// collapsedArticle is an article standing for all the articles with the same title or canonical
+// link in a list, of which DuplicateCount holds the number of other ones.
+type collapsedArticle struct {
+	*types.Article
+	DuplicateCount int `json:"duplicateCount"`
+}
+
+// collapseDuplicates groups the articles having the same normalized title or the same canonical
+// link, returning the newest article of each group, by publish date, in the position of the first
+// article of the group. Articles without a title nor canonical link are never collapsed.
+func collapseDuplicates(articles []*types.Article) []*collapsedArticle {
+	res := []*collapsedArticle{}
+	groups := map[string]*collapsedArticle{}
+	for _, a := range articles {
+		keys := duplicateKeys(a)
+		var group *collapsedArticle
+		for _, key := range keys {
+			if g, ok := groups[key]; ok {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			group = &collapsedArticle{Article: a}
+			res = append(res, group)
+		} else {
+			group.DuplicateCount++
+			if a.PublishDate.After(group.PublishDate) {
+				group.Article = a
+			}
+		}
+		// Articles joining a group by one key make the group reachable by their other key too.
+		for _, key := range keys {
+			if _, ok := groups[key]; !ok {
+				groups[key] = group
+			}
+		}
+	}
+	return res
+}
+
+// duplicateKeys returns the keys identifying the duplicates of the article: its title, ignoring case
+// and spacing, and its canonical link, when present.
+func duplicateKeys(a *types.Article) []string {
+	var keys []string
+	if title := strings.ToLower(strings.Join(strings.Fields(a.Title), " ")); title != "" {
+		keys = append(keys, "title:"+title)
+	}
+	if a.CanonicalLink != "" {
+		keys = append(keys, "link:"+a.CanonicalLink)
+	}
+	return keys
+}
+
+// truncateArticles returns the articles with their description and content truncated to at most max
+// characters by truncateArticle.
+func truncateArticles(articles []*types.Article, max int) []*types.Article {
+	if max <= 0 {
+		return articles
+	}
+	res := make([]*types.Article, 0, len(articles))
+	for _, a := range articles {
+		res = append(res, truncateArticle(a, max))
+	}
+	return res
+}
+
+// truncateArticle returns a copy of the article with its description and content truncated to at
+// most max characters, leaving the stored article intact. Zero means no limit.
+func truncateArticle(article *types.Article, max int) *types.Article {
+	if article == nil || max <= 0 {
+		return article
+	}
+	truncated := *article
+	truncated.Description = truncateRunes(article.Description, max)
+	truncated.Content = truncateRunes(article.Content, max)
+	return &truncated
+}
+
+// truncateRunes cuts the provided string to at most max runes.
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	n := 0
+	for i := range s {
+		if n == max {
+			return s[:i]
+		}
+		n++
+	}
+	return s
+}
+
+// renderArticles writes the provided articles in the format requested through the Accept header,
+// falling back to plain JSON when the header is absent or no supported format is accepted.
+func renderArticles(c *gin.Context, articles []*types.Article) {
+	switch c.NegotiateFormat(gin.MIMEJSON, mimeRSS, mimeJSONFeed) {
+	case mimeRSS:
+		data, err := xml.Marshal(toRSSDocument(articles))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Data(http.StatusOK, mimeRSS+"; charset=utf-8", append([]byte(xml.Header), data...))
+	case mimeJSONFeed:
+		data, err := json.Marshal(toJSONFeed(articles))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Data(http.StatusOK, mimeJSONFeed+"; charset=utf-8", data)
+	default:
+		c.JSON(http.StatusOK, articles)
+	}
+}
+
+// renderCollapsedArticles writes the collapsed articles in the format requested like renderArticles,
+// where only JSON holds their duplicate counts, since the feed formats have no place for them.
+func renderCollapsedArticles(c *gin.Context, collapsed []*collapsedArticle) {
+	if c.NegotiateFormat(gin.MIMEJSON, mimeRSS, mimeJSONFeed) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, collapsed)
+		return
+	}
+	articles := make([]*types.Article, 0, len(collapsed))
+	for _, group := range collapsed {
+		articles = append(articles, group.Article)
+	}
+	renderArticles(c, articles)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	GUID        string         `xml:"guid,omitempty"`
+	Title       string         `xml:"title,omitempty"`
+	Link        string         `xml:"link,omitempty"`
+	Comments    string         `xml:"comments,omitempty"`
+	PubDate     string         `xml:"pubDate,omitempty"`
+	Categories  []string       `xml:"category"`
+	Enclosures  []rssEnclosure `xml:"enclosure"`
+	Description string         `xml:"description,omitempty"`
+	Author      string         `xml:"author,omitempty"`
+}
+
+type rssEnclosure struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+func toRSSDocument(articles []*types.Article) *rssDocument {
+	items := make([]rssItem, 0, len(articles))
+	for _, a := range articles {
+		item := rssItem{
+			GUID:        a.GUID,
+			Title:       a.Title,
+			Link:        a.Link,
+			Comments:    a.Comments,
+			Categories:  a.Categories,
+			Description: a.Description,
+			Author:      a.Author,
+		}
+		if !a.PublishDate.IsZero() {
+			item.PubDate = a.PublishDate.Format(time.RFC1123Z)
+		}
+		for _, e := range a.Enclosures {
+			item.Enclosures = append(item.Enclosures, rssEnclosure{URL: e.URL, Type: e.Type})
+		}
+		items = append(items, item)
+	}
+	return &rssDocument{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       renderedFeedTitle,
+			Description: "Articles gathered by " + renderedFeedTitle,
+			Items:       items,
+		},
+	}
+}
+
+type jsonFeed struct {
+	Version string         `json:"version"`
+	Title   string         `json:"title"`
+	Items   []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url,omitempty"`
+	Title         string               `json:"title,omitempty"`
+	ContentHTML   string               `json:"content_html"`
+	Summary       string               `json:"summary,omitempty"`
+	DatePublished string               `json:"date_published,omitempty"`
+	Tags          []string             `json:"tags,omitempty"`
+	Authors       []jsonFeedAuthor     `json:"authors,omitempty"`
+	Attachments   []jsonFeedAttachment `json:"attachments,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedAttachment struct {
+	URL      string `json:"url"`
+	MIMEType string `json:"mime_type"`
+}
+
+func toJSONFeed(articles []*types.Article) *jsonFeed {
+	items := make([]jsonFeedItem, 0, len(articles))
+	for _, a := range articles {
+		item := jsonFeedItem{
+			ID:          a.ID,
+			URL:         a.Link,
+			Title:       a.Title,
+			ContentHTML: a.Content,
+			Summary:     a.Description,
+			Tags:        a.Categories,
+		}
+		// JSON Feed requires some content for each item, so the description is used if the feed
+		// didn't provide any.
+		if item.ContentHTML == "" {
+			item.ContentHTML = a.Description
+		}
+		if !a.PublishDate.IsZero() {
+			item.DatePublished = a.PublishDate.Format(time.RFC3339)
+		}
+		if a.Author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: a.Author}}
+		}
+		for _, e := range a.Enclosures {
+			item.Attachments = append(item.Attachments, jsonFeedAttachment{URL: e.URL, MIMEType: e.Type})
+		}
+		items = append(items, item)
+	}
+	return &jsonFeed{
+		Version: jsonFeedVersion,
+		Title:   renderedFeedTitle,
+		Items:   items,
+	}
+}
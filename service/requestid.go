@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// requestIDHeader is the header holding the ID of a request, which is echoed in the response.
+	requestIDHeader = "X-Request-ID"
+	// requestIDKey is the context key holding the ID of the current request.
+	requestIDKey = "requestID"
+	// maxRequestIDLength is the maximum length of the request IDs honored, longer ones are replaced.
+	maxRequestIDLength = 128
+)
+
+// requestID is a middleware identifying each request by the ID sent in the X-Request-ID header, or by
+// a generated one when absent, so requests can be traced across services. The ID is stored in the
+// context under the requestID key and echoed in the response header.
+func requestID(c *gin.Context) {
+	ID := c.GetHeader(requestIDHeader)
+	if ID == "" || len(ID) > maxRequestIDLength {
+		ID = uuid.New().String()
+	}
+	c.Set(requestIDKey, ID)
+	c.Header(requestIDHeader, ID)
+	c.Next()
+}
+
+// requestLogger returns a middleware logging requests like the default gin logger, followed by the
+// ID of the request.
+func requestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %v\n%s",
+			p.TimeStamp.Format(time.RFC3339),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			p.Path,
+			p.Keys[requestIDKey],
+			p.ErrorMessage,
+		)
+	})
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"../types"
+)
+
+// WithRefreshInterval loads all feeds every interval while the service is served. Each feed is
+// loaded after a random delay of up to jitter, which is capped to the interval, so that the loads
+// spread out instead of all feeds of the same host being requested at once. Zero disables the
+// periodic refresh.
+func WithRefreshInterval(interval, jitter time.Duration) Option {
+	return func(s *Service) {
+		s.refreshInterval = interval
+		s.refreshJitter = jitter
+	}
+}
+
+// scheduledRefresh is the time a feed is due to be loaded by the periodic refresh.
+type scheduledRefresh struct {
+	Feed *types.Feed
+	At   time.Time
+}
+
+// refreshSchedule returns the times the feeds are loaded at by a periodic refresh starting at the
+// provided time, each one delayed by a random jitter.
+func (s *Service) refreshSchedule(feeds []*types.Feed, start time.Time) []scheduledRefresh {
+	jitter := s.refreshJitter
+	if jitter > s.refreshInterval {
+		jitter = s.refreshInterval
+	}
+	schedule := make([]scheduledRefresh, 0, len(feeds))
+	for _, feed := range feeds {
+		at := start
+		if jitter > 0 {
+			at = at.Add(time.Duration(s.rand.Int63n(int64(jitter))))
+		}
+		schedule = append(schedule, scheduledRefresh{Feed: feed, At: at})
+	}
+	return schedule
+}
+
+// refreshPeriodically loads all feeds every refresh interval, as scheduled by refreshSchedule. It
+// never returns.
+func (s *Service) refreshPeriodically() {
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		feeds, err := s.feedStore.ListPage("", "", 0)
+		if err != nil {
+			log.Printf("could not list feeds for refreshing: %v", err)
+			continue
+		}
+		for _, r := range s.refreshSchedule(feeds, time.Now()) {
+			feed := r.Feed
+			time.AfterFunc(time.Until(r.At), func() {
+				s.refreshScheduled(feed)
+			})
+		}
+	}
+}
+
+// refreshScheduled loads a feed due for a periodic refresh, logging the failures since there is no
+// client to report them to.
+func (s *Service) refreshScheduled(feed *types.Feed) {
+	if s.insecure(feed.Addresses()...) {
+		log.Printf("could not refresh feed %s: %v", feed.ID, errInsecureFeed)
+		return
+	}
+	if _, err := s.feeder.Consume(feed, false); err != nil {
+		log.Printf("could not refresh feed %s: %v", feed.ID, err)
+	}
+}
@@ -2,52 +2,204 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"../types"
 
 	"github.com/gin-gonic/gin"
 )
 
+// feedPreviewSize is the maximum number of articles returned when testing a feed.
+const feedPreviewSize = 5
+
+// maxBatchSize is the maximum number of articles that can be requested at once.
+const maxBatchSize = 100
+
+// refreshPageSize is the default number of articles returned when refreshing a feed.
+const refreshPageSize = 10
+
+// defaultPageSize is the default number of articles listed when no page size is requested.
+const defaultPageSize = 20
+
+// defaultMaxCategoryFilters is the default maximum number of categories an article listing can be
+// filtered by.
+const defaultMaxCategoryFilters = 50
+
 // Feeder describes the functionality needed to consume articles from a feeder.
 type Feeder interface {
-	Consume(feed *types.Feed) error
+	Consume(feed *types.Feed, force bool) (*types.LoadSummary, error)
+	Ingest(feed *types.Feed, channel *types.Channel) (*types.LoadSummary, error)
+}
+
+// FeedReader describes the functionality needed to read a feed without storing its articles.
+type FeedReader interface {
+	Read(address string, opts types.ReadOptions) (*types.Channel, error)
+	ReadRaw(address string, opts types.ReadOptions) (*types.RawFeed, error)
+	Parse(address string, body []byte) (*types.Channel, error)
 }
 
 // ArticleStore describes the functionality needed to store and retrieve articles.
 type ArticleStore interface {
+	Create(article *types.Article) (*types.Article, error)
 	List(cursor string, pageSize int, feed string, categories ...string) ([]*types.Article, error)
+	ListFiltered(cursor string, pageSize int, filter types.ArticleFilter, order types.ArticleOrder) ([]*types.Article, error)
+	Latest(n int, filter types.ArticleFilter) ([]*types.Article, error)
 	Get(ID string) (*types.Article, error)
+	GetMany(IDs []string) ([]*types.Article, error)
+	FirstCursor(filter types.ArticleFilter) (string, error)
+	LastCursor(filter types.ArticleFilter) (string, error)
+	MarkRead(ID string, read bool) (*types.Article, error)
+	MarkStarred(ID string, starred bool) (*types.Article, error)
+	AddLabels(ID string, labels ...string) (*types.Article, error)
+	RemoveLabel(ID string, label string) (*types.Article, error)
+	UnreadCountsByCategory() map[string]int
+	Snapshot() string
+	GroupByProvider(pageSize int, filter types.ArticleFilter) (map[string][]*types.Article, error)
+	CountByDay(from, to time.Time, filter types.ArticleFilter) (map[string]int, error)
+	MoveFeed(sourceID string, target *types.Feed) (int, error)
+	Neighbors(ID string, filter types.ArticleFilter) (*types.Article, *types.Article, error)
+	UpdateFullText(ID string, fullText string, canonicalLink string, truncated bool) (*types.Article, error)
+	Delete(ID string) (*types.Tombstone, error)
+	ListTombstones() []*types.Tombstone
+	ClearTombstones(IDs ...string) (int, error)
+	FeedsForCategory(category string) []string
+	TrendingCategories(window time.Duration, limit int, filter types.ArticleFilter) ([]*types.CategoryCount, error)
+	Reindex() (int, error)
+	Reset() (int, error)
 }
 
 // FeedStore describes the functionality needed to store and retrieve feeds.
 type FeedStore interface {
 	List() ([]*types.Feed, error)
+	ListPage(provider string, cursor string, pageSize int) ([]*types.Feed, error)
+	Search(query string) ([]*types.Feed, error)
 	Create(feed *types.Feed) (*types.Feed, error)
+	ComputeID(address string, category string) string
 	Get(ID string) (*types.Feed, error)
+	GetByAddress(address string) (*types.Feed, error)
+	RenameCategory(old, new string) int
+	UpdateTimeout(ID string, timeout time.Duration) (*types.Feed, error)
+	Delete(ID string) error
 }
 
 // Service represents a web service capable of acting on RESTful requests for getting articles.
 type Service struct {
 	feeder       Feeder
+	reader       FeedReader
 	articleStore ArticleStore
 	feedStore    FeedStore
+
+	enclosureClient  *http.Client
+	contentFetcher   ContentFetcher
+	maxEnclosureSize int64
+	defaultPageSize  int
+	maxCatFilters    int
+	ui               bool
+	adminToken       string
+	httpsOnly        bool
+	baseURL          string
+	cacheRules       map[string]string
+	loadQueueSize    int
+	loadWorkers      int
+	loadQueue        *loadQueue
+	refreshInterval  time.Duration
+	refreshJitter    time.Duration
+	fetchWorkers     int
+	fetchTimeout     time.Duration
+	maxFullText      int
+	rand             *rand.Rand
+	websubClient     *http.Client
+	websub           *websubSubscriptions
+}
+
+// Option configures optional behaviour of a Service.
+type Option func(*Service)
+
+// WithDefaultPageSize sets the number of articles listed when no page size is requested.
+func WithDefaultPageSize(n int) Option {
+	return func(s *Service) {
+		s.defaultPageSize = n
+	}
+}
+
+// WithMaxCategoryFilters limits the number of categories the articles can be filtered by in a single
+// request, since each of them adds to the cost of the filtering. Requests exceeding it are rejected.
+// Zero means unlimited.
+func WithMaxCategoryFilters(n int) Option {
+	return func(s *Service) {
+		s.maxCatFilters = n
+	}
+}
+
+// tooManyCategories returns whether more category filters than allowed were provided.
+func (s *Service) tooManyCategories(categories []string) bool {
+	return s.maxCatFilters > 0 && len(categories) > s.maxCatFilters
+}
+
+// WithHTTPSOnly rejects feeds with plain HTTP addresses, either primary or fallback, which can't be
+// created, tested nor loaded when enabled.
+func WithHTTPSOnly(enabled bool) Option {
+	return func(s *Service) {
+		s.httpsOnly = enabled
+	}
+}
+
+// WithBaseURL sets the absolute URL the service is reached at, such as "https://news.example.com",
+// which makes the self links of the articles absolute. When unset, they are relative paths.
+func WithBaseURL(base string) Option {
+	return func(s *Service) {
+		s.baseURL = strings.TrimRight(base, "/")
+	}
+}
+
+// WithUI enables serving a minimal reader UI under /ui, which is useful for demos.
+func WithUI(enabled bool) Option {
+	return func(s *Service) {
+		s.ui = enabled
+	}
 }
 
 // NewService returns a new Service capable of exposing the required endpoints for the news app.
-func NewService(feeder Feeder, feedStore FeedStore, articleStore ArticleStore) *Service {
-	return &Service{
+func NewService(feeder Feeder, reader FeedReader, feedStore FeedStore, articleStore ArticleStore, opts ...Option) *Service {
+	s := &Service{
 		feeder:       feeder,
+		reader:       reader,
 		feedStore:    feedStore,
 		articleStore: articleStore,
+
+		enclosureClient:  &http.Client{Timeout: enclosureTimeout},
+		contentFetcher:   &HTTPContentFetcher{Client: &http.Client{Timeout: contentTimeout}},
+		fetchWorkers:     defaultContentRefreshConcurrency,
+		fetchTimeout:     contentTimeout,
+		maxEnclosureSize: maxEnclosureSize,
+		defaultPageSize:  defaultPageSize,
+		maxCatFilters:    defaultMaxCategoryFilters,
+		rand:             rand.New(rand.NewSource(time.Now().UnixNano())),
+		websubClient:     &http.Client{Timeout: websubTimeout},
+		websub:           newWebSubSubscriptions(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	if s.loadQueueSize > 0 {
+		s.loadQueue = newLoadQueue(s.feeder, s.loadQueueSize, s.loadWorkers)
+	}
+	return s
 }
 
 // ServeForever sets up the service router and start serving until receiving a signal to exit.
 func (s *Service) ServeForever(port uint) {
 	r := s.setupServiceRouter()
+	if s.refreshInterval > 0 {
+		go s.refreshPeriodically()
+	}
 	// Run http server
 	if err := r.Run(fmt.Sprintf(":%d", port)); err != nil {
 		log.Fatalf("could not run server: %v", err)
@@ -55,24 +207,83 @@ func (s *Service) ServeForever(port uint) {
 }
 
 func (s *Service) setupServiceRouter() *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
+	r.Use(requestID, requestLogger(), gin.Recovery())
+	// Routes are only served under their canonical paths, without a trailing slash. Requests with a
+	// trailing slash are redirected to them, permanently for GET requests and keeping the method
+	// and body for the rest.
+	r.RedirectTrailingSlash = true
+	r.RedirectFixedPath = false
+	r.Use(prettyJSON)
+	if len(s.cacheRules) > 0 {
+		r.Use(s.cacheControl)
+	}
 
 	r.PUT("/feeds", s.createFeed)
+	r.POST("/feeds/bulk", s.createFeedsBulk)
 	r.GET("/feeds", s.listFeeds)
+	r.GET("/feeds/compute-id", s.computeFeedID)
+	r.GET("/feeds/search", s.searchFeeds)
 	r.GET("/feeds/:id", s.getFeed)
+	r.PATCH("/feeds/:id", s.updateFeed)
+	r.POST("/feeds/:id/clone", s.cloneFeed)
+	r.POST("/feeds/import/preview", s.previewImportFeeds)
 	r.POST("/feeds/load", s.loadFeed)
+	r.POST("/feeds/load-all", s.loadAllFeeds)
+	r.GET("/feeds/load/:jobId", s.getLoadJob)
+	r.POST("/feeds/test", s.testFeed)
+	r.POST("/feeds/merge", s.mergeFeeds)
+	r.POST("/feeds/:id/refresh", s.refreshFeed)
+	r.GET("/feeds/:id/raw", s.getRawFeed)
+	r.POST("/feeds/:id/websub", s.subscribeFeed)
+
+	r.GET(websubCallbackPath, s.verifyWebSub)
+	r.POST(websubCallbackPath, s.receiveWebSub)
 
 	r.GET("/articles", s.listArticles)
+	r.POST("/articles", s.injectArticle)
+	r.GET("/articles/unread-counts", s.unreadCounts)
+	r.GET("/articles/cursors", s.articleCursors)
+	r.GET("/articles/batch", s.getArticles)
+	r.GET("/articles/grouped", s.groupArticles)
+	r.GET("/articles/histogram", s.articleHistogram)
+	r.GET("/articles/export.csv", s.exportArticles)
+	r.GET("/articles/tombstones", s.listTombstones)
+	r.DELETE("/articles/tombstones", s.clearTombstones)
 	r.GET("/articles/:id", s.getArticle)
+	r.DELETE("/articles/:id", s.deleteArticle)
+	r.GET("/articles/:id/neighbors", s.articleNeighbors)
+	r.POST("/articles/:id/read", s.markArticleRead)
+	r.POST("/articles/:id/star", s.markArticleStarred)
+	r.POST("/articles/:id/labels", s.addArticleLabels)
+	r.POST("/articles/:id/fulltext", s.fetchFullText)
+	r.DELETE("/articles/:id/labels/:label", s.removeArticleLabel)
+	r.GET("/articles/:id/enclosure/:index", s.getEnclosure)
+
+	r.GET("/categories/trending", s.trendingCategories)
+	r.GET("/categories/:category/feeds", s.categoryFeeds)
+
+	r.POST("/graphql", s.queryGraphQL)
+
+	if s.ui {
+		r.StaticFS(uiPath, uiFS())
+	}
+	if s.adminToken != "" {
+		s.setupAdminRoutes(r)
+	}
 
 	return r
 }
 
 // CreateFeedArgs represents the arguments in a create feed request.
 type CreateFeedArgs struct {
-	Provider string `json:"provider" binding:"required"`
-	Category string `json:"category" binding:"required"`
-	Address  string `json:"address" binding:"required"`
+	Provider  string   `json:"provider" binding:"required"`
+	Category  string   `json:"category"`
+	Address   string   `json:"address" binding:"required"`
+	Fallbacks []string `json:"fallbacks"`
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+	Timeout   string   `json:"timeout"`
 }
 
 func (s *Service) createFeed(c *gin.Context) {
@@ -83,10 +294,139 @@ func (s *Service) createFeed(c *gin.Context) {
 		})
 		return
 	}
+	timeout, err := parseTimeout(args.Timeout)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.insecure(append([]string{args.Address}, args.Fallbacks...)...) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": errInsecureFeed.Error(),
+		})
+		return
+	}
+	// Addresses are canonicalized like in ComputeFeedID, so the IDs it returns match the created feeds.
+	if address, ok := canonicalAddress(args.Address); ok {
+		args.Address = address
+	}
+	feed, err := s.feedStore.Create(&types.Feed{
+		Provider:    args.Provider,
+		Category:    args.Category,
+		Address:     args.Address,
+		Fallbacks:   args.Fallbacks,
+		Credentials: credentials(args.Username, args.Password),
+		Timeout:     timeout,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, feed)
+}
+
+// errInsecureFeed is the error reported for feeds with plain HTTP addresses when only HTTPS is allowed.
+var errInsecureFeed = errors.New("only https feed addresses are allowed")
+
+// insecure reports whether any of the provided addresses is rejected for not using HTTPS, which is
+// only the case when HTTPS is required.
+func (s *Service) insecure(addresses ...string) bool {
+	if !s.httpsOnly {
+		return false
+	}
+	for _, address := range addresses {
+		u, err := url.Parse(strings.TrimSpace(address))
+		if err != nil || !strings.EqualFold(u.Scheme, "https") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimeout parses a feed timeout such as "5s", where an empty value means the default timeout.
+func parseTimeout(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(v)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid timeout %q", v)
+	}
+	return timeout, nil
+}
+
+// UpdateFeedArgs represents the arguments in an update feed request.
+type UpdateFeedArgs struct {
+	Timeout *string `json:"timeout" binding:"required"`
+}
+
+func (s *Service) updateFeed(c *gin.Context) {
+	var uriArgs GetFeedArgs
+	var args UpdateFeedArgs
+	if c.BindUri(&uriArgs) != nil || c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	timeout, err := parseTimeout(*args.Timeout)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	feed, err := s.feedStore.UpdateTimeout(uriArgs.ID, timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, feed)
+}
+
+// CloneFeedArgs represents the arguments in a clone feed request.
+type CloneFeedArgs struct {
+	Category string `json:"category" binding:"required"`
+}
+
+// cloneFeed creates a feed with the same settings as an existing one in another category, returning
+// the new feed. Since article IDs only depend on their GUID, the articles already stored by the
+// source feed are not stored again for the clone and keep belonging to the source.
+func (s *Service) cloneFeed(c *gin.Context) {
+	var uriArgs GetFeedArgs
+	var args CloneFeedArgs
+	if c.BindUri(&uriArgs) != nil || c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	source, err := s.feedStore.Get(uriArgs.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if args.Category == source.Category {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "clone must have a different category",
+		})
+		return
+	}
 	feed, err := s.feedStore.Create(&types.Feed{
-		Provider: args.Provider,
-		Category: args.Category,
-		Address:  args.Address,
+		Provider:    source.Provider,
+		Category:    args.Category,
+		Address:     source.Address,
+		Fallbacks:   append([]string(nil), source.Fallbacks...),
+		Credentials: source.Credentials,
+		Timeout:     source.Timeout,
+		ClonedFrom:  source.ID,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -97,6 +437,15 @@ func (s *Service) createFeed(c *gin.Context) {
 	c.JSON(http.StatusOK, feed)
 }
 
+// credentials returns the credentials for authenticating against a feed, or nil if no username is
+// provided.
+func credentials(username string, password string) *types.Credentials {
+	if username == "" {
+		return nil
+	}
+	return &types.Credentials{Username: username, Password: password}
+}
+
 // GetFeedArgs represents the arguments in a get feed request.
 type GetFeedArgs struct {
 	ID string `uri:"id" binding:"required"`
@@ -120,8 +469,89 @@ func (s *Service) getFeed(c *gin.Context) {
 	c.JSON(http.StatusOK, feed)
 }
 
+// ComputeFeedIDArgs represents the arguments in a compute feed ID request.
+type ComputeFeedIDArgs struct {
+	Address  string `form:"address" binding:"required"`
+	Category string `form:"category"`
+}
+
+// computeFeedID returns the canonical form of the provided address along with the ID a feed created
+// with it and the provided category receives, without creating the feed.
+func (s *Service) computeFeedID(c *gin.Context) {
+	var args ComputeFeedIDArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	address, ok := canonicalAddress(args.Address)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid address",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"address": address,
+		"id":      s.feedStore.ComputeID(address, args.Category),
+	})
+}
+
+// ListFeedsArgs represents the arguments in a list feeds request. Feeds are listed by ID, starting
+// after the cursor, and all of them are listed for a zero page size.
+type ListFeedsArgs struct {
+	Provider string `form:"provider"`
+	Cursor   string `form:"cursor"`
+	PageSize int    `form:"pageSize" binding:"min=0"`
+}
+
 func (s *Service) listFeeds(c *gin.Context) {
-	feeds, err := s.feedStore.List()
+	var args ListFeedsArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	feeds, err := s.feedStore.ListPage(args.Provider, args.Cursor, args.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	// The tag covers every field of the feeds listed, so any change to them invalidates it.
+	etag, err := jsonETag(feeds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, feeds)
+}
+
+// SearchFeedsArgs represents the arguments in a search feeds request.
+type SearchFeedsArgs struct {
+	Query string `form:"q" binding:"required"`
+}
+
+// searchFeeds returns the feeds whose provider, category or address contain the query.
+func (s *Service) searchFeeds(c *gin.Context) {
+	var args SearchFeedsArgs
+	if c.BindQuery(&args) != nil || strings.TrimSpace(args.Query) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	feeds, err := s.feedStore.Search(strings.TrimSpace(args.Query))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -131,81 +561,973 @@ func (s *Service) listFeeds(c *gin.Context) {
 	c.JSON(http.StatusOK, feeds)
 }
 
-// LoadFeedArgs represents the arguments in a load feed request.
+// LoadFeedArgs represents the arguments in a load feed request, which identifies the feed either by
+// its ID or by its address.
 type LoadFeedArgs struct {
-	ID string `json:"id" binding:"required"`
+	ID      string `json:"id"`
+	Address string `json:"address"`
+}
+
+// fields returns the errors of the invalid fields, which is empty when the arguments are valid.
+func (args LoadFeedArgs) fields() map[string]string {
+	switch {
+	case args.ID == "" && args.Address == "":
+		return map[string]string{
+			"id":      "either id or address is required",
+			"address": "either id or address is required",
+		}
+	case args.ID != "" && args.Address != "":
+		return map[string]string{
+			"id":      "only one of id or address can be provided",
+			"address": "only one of id or address can be provided",
+		}
+	}
+	return nil
+}
+
+// LoadFeedQuery represents the query parameters accepted in a load feed request.
+type LoadFeedQuery struct {
+	Force bool `form:"force"`
 }
 
 func (s *Service) loadFeed(c *gin.Context) {
+	var query LoadFeedQuery
 	var args LoadFeedArgs
-	if c.BindJSON(&args) != nil {
+	if c.BindQuery(&query) != nil || c.BindJSON(&args) != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "invalid arguments",
 		})
 		return
 	}
-	feed, err := s.feedStore.Get(args.ID)
+	if fields := args.fields(); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "invalid arguments",
+			"fields": fields,
+		})
+		return
+	}
+	var feed *types.Feed
+	var err error
+	if args.ID != "" {
+		feed, err = s.feedStore.Get(args.ID)
+	} else {
+		feed, err = s.feedStore.GetByAddress(args.Address)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
-	err = s.feeder.Consume(feed)
+	if s.insecure(feed.Addresses()...) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": errInsecureFeed.Error(),
+		})
+		return
+	}
+	if s.loadQueue != nil {
+		job, ok := s.loadQueue.enqueue(feed, query.Force)
+		if !ok {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "load queue is full",
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+	summary, err := s.feeder.Consume(feed, query.Force)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
+		return
 	}
-	return
+	c.JSON(http.StatusOK, summary)
 }
 
-// GetArticleArgs represents the arguments in a get article request.
-type GetArticleArgs struct {
-	ID string `uri:"id" binding:"required"`
+// loadAllFeeds loads every feed, reporting the outcome of each one. The response is a 207 when only
+// some of the feeds failed to load, and a 500 when all of them did.
+func (s *Service) loadAllFeeds(c *gin.Context) {
+	var query LoadFeedQuery
+	if c.BindQuery(&query) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	feeds, err := s.feedStore.ListPage("", "", 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	results := make([]*types.FeedLoadResult, 0, len(feeds))
+	failed := 0
+	for _, feed := range feeds {
+		result := &types.FeedLoadResult{FeedID: feed.ID}
+		if s.insecure(feed.Addresses()...) {
+			result.Error = errInsecureFeed.Error()
+			failed++
+		} else if result.Summary, err = s.feeder.Consume(feed, query.Force); err != nil {
+			result.Error = err.Error()
+			failed++
+		}
+		results = append(results, result)
+	}
+	status := http.StatusOK
+	switch {
+	case failed > 0 && failed == len(feeds):
+		status = http.StatusInternalServerError
+	case failed > 0:
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, results)
 }
 
-func (s *Service) getArticle(c *gin.Context) {
-	var args GetArticleArgs
+// RefreshFeedQuery represents the query parameters accepted in a refresh feed request.
+type RefreshFeedQuery struct {
+	PageSize int `form:"pageSize"`
+}
+
+// refreshFeed loads the feed and returns the first page of its articles, newest first.
+func (s *Service) refreshFeed(c *gin.Context) {
+	var args GetFeedArgs
+	var query RefreshFeedQuery
+	if c.BindUri(&args) != nil || c.BindQuery(&query) != nil || query.PageSize < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if query.PageSize == 0 {
+		query.PageSize = refreshPageSize
+	}
+	feed, err := s.feedStore.Get(args.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if s.insecure(feed.Addresses()...) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": errInsecureFeed.Error(),
+		})
+		return
+	}
+	if _, err := s.feeder.Consume(feed, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	latest, err := s.articleStore.Latest(query.PageSize, types.ArticleFilter{Feed: feed.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	renderArticles(c, s.linkArticles(latest))
+}
+
+// getRawFeed returns the body of the feed as received from its primary address, which helps debugging
+// how its items are converted. Nothing is stored.
+func (s *Service) getRawFeed(c *gin.Context) {
+	var args GetFeedArgs
 	if c.BindUri(&args) != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "invalid arguments",
 		})
 		return
 	}
-	article, err := s.articleStore.Get(args.ID)
+	feed, err := s.feedStore.Get(args.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
-	c.JSON(http.StatusOK, article)
+	if s.insecure(feed.Addresses()...) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": errInsecureFeed.Error(),
+		})
+		return
+	}
+	raw, err := s.reader.ReadRaw(feed.Address, feed.ReadOptions())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("could not read feed: %v", err),
+		})
+		return
+	}
+	c.Data(http.StatusOK, raw.ContentType, raw.Body)
 }
 
-// ListArgs represents the arguments accepted in a list articles request.
-type ListArgs struct {
-	Cursor     string   `form:"c"`
-	PageSize   int      `form:"pageSize"`
-	Feed       string   `form:"feed"`
-	Categories []string `form:"cat"`
+// MergeFeedsArgs represents the arguments in a merge feeds request.
+type MergeFeedsArgs struct {
+	SourceID string `json:"sourceId" binding:"required"`
+	TargetID string `json:"targetId" binding:"required"`
 }
 
-func (s *Service) listArticles(c *gin.Context) {
-	var args ListArgs
-	if c.BindQuery(&args) != nil {
+// mergeFeeds moves the articles of the source feed to the target one and deletes the source feed,
+// returning the target feed along with the number of moved articles.
+func (s *Service) mergeFeeds(c *gin.Context) {
+	var args MergeFeedsArgs
+	if c.BindJSON(&args) != nil || args.SourceID == args.TargetID {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "invalid arguments",
 		})
 		return
 	}
-
-	articles, err := s.articleStore.List(args.Cursor, args.PageSize, args.Feed, args.Categories...)
+	if _, err := s.feedStore.Get(args.SourceID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	target, err := s.feedStore.Get(args.TargetID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
-	c.JSON(http.StatusOK, articles)
+	moved, err := s.articleStore.MoveFeed(args.SourceID, target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.feedStore.Delete(args.SourceID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"feed":  target,
+		"moved": moved,
+	})
+}
+
+// TestFeedArgs represents the arguments in a test feed request.
+type TestFeedArgs struct {
+	Address  string `json:"address" binding:"required"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (s *Service) testFeed(c *gin.Context) {
+	var args TestFeedArgs
+	if c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.insecure(args.Address) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": errInsecureFeed.Error(),
+		})
+		return
+	}
+	channel, err := s.reader.Read(args.Address, types.ReadOptions{Credentials: credentials(args.Username, args.Password)})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("could not read feed: %v", err),
+		})
+		return
+	}
+	// Only a preview of the feed is returned, nothing is stored.
+	if len(channel.Articles) > feedPreviewSize {
+		channel.Articles = channel.Articles[:feedPreviewSize]
+	}
+	c.JSON(http.StatusOK, channel)
+}
+
+// GetArticleArgs represents the arguments in a get article request.
+type GetArticleArgs struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// TruncateQuery represents the query parameters limiting the length of the description and content
+// of the articles returned, where zero means no limit.
+type TruncateQuery struct {
+	MaxDesc int `form:"maxDesc" binding:"min=0"`
+}
+
+// GetArticleQuery represents the query parameters accepted in a get article request. Expand can be
+// set to "feed" to embed the details of the feed the article was read from.
+type GetArticleQuery struct {
+	TruncateQuery
+	Expand string `form:"expand"`
+}
+
+func (s *Service) getArticle(c *gin.Context) {
+	var args GetArticleArgs
+	var query GetArticleQuery
+	if c.BindUri(&args) != nil || c.BindQuery(&query) != nil || (query.Expand != "" && query.Expand != "feed") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	article, err := s.articleStore.Get(args.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	article = s.linkArticle(truncateArticle(article, query.MaxDesc))
+	if query.Expand == "feed" {
+		c.JSON(http.StatusOK, s.expandFeed(article))
+		return
+	}
+	c.JSON(http.StatusOK, article)
+}
+
+// InjectArticleArgs represents the arguments in an inject article request.
+type InjectArticleArgs struct {
+	FeedID      string    `json:"feedId"`
+	Provider    string    `json:"provider"`
+	GUID        string    `json:"guid" binding:"required"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	PublishDate time.Time `json:"publishDate"`
+	Categories  []string  `json:"categories"`
+	Description string    `json:"description"`
+	Author      string    `json:"author"`
+	Content     string    `json:"content"`
+}
+
+// InjectArticleQuery represents the query parameters accepted in an inject article request.
+type InjectArticleQuery struct {
+	FailIfExists bool `form:"failIfExists"`
+}
+
+// injectArticle stores an article provided manually. Like loading feeds, an article whose GUID is
+// already stored is not duplicated and the existing one is returned, unless failIfExists is set.
+func (s *Service) injectArticle(c *gin.Context) {
+	var query InjectArticleQuery
+	var args InjectArticleArgs
+	if c.BindQuery(&query) != nil || c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	article := &types.Article{
+		FeedID:      args.FeedID,
+		Provider:    args.Provider,
+		GUID:        args.GUID,
+		Title:       args.Title,
+		Link:        args.Link,
+		PublishDate: args.PublishDate,
+		Categories:  args.Categories,
+		Description: args.Description,
+		Author:      args.Author,
+		Content:     args.Content,
+	}
+	stored, err := s.articleStore.Create(article)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	// The store returns nil for deleted articles, until their tombstone is cleared or expires.
+	if stored == nil {
+		c.JSON(http.StatusGone, gin.H{
+			"error": "article was deleted",
+		})
+		return
+	}
+	// The store returns the existing article instead of the provided one for known GUIDs.
+	if stored != article && query.FailIfExists {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "article already exists",
+			"id":    stored.ID,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, stored)
+}
+
+// GetArticlesArgs represents the arguments in a get articles request, where IDs are separated by
+// commas.
+type GetArticlesArgs struct {
+	IDs     string `form:"ids" binding:"required"`
+	MaxDesc int    `form:"maxDesc" binding:"min=0"`
+}
+
+func (s *Service) getArticles(c *gin.Context) {
+	var args GetArticlesArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	IDs := strings.Split(args.IDs, ",")
+	if len(IDs) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("at most %d articles can be requested", maxBatchSize),
+		})
+		return
+	}
+	articles, err := s.articleStore.GetMany(IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, s.linkArticles(truncateArticles(articles, args.MaxDesc)))
+}
+
+// ListArgs represents the arguments accepted in a list articles request.
+type ListArgs struct {
+	Cursor        string   `form:"c"`
+	PageSize      int      `form:"pageSize"`
+	Feed          string   `form:"feed"`
+	Categories    []string `form:"cat"`
+	Labels        []string `form:"label"`
+	EnclosureType string   `form:"enclosureType"`
+	NoEnclosures  bool     `form:"noEnclosures"`
+	HasFullText   bool     `form:"hasFullText"`
+	HasImage      bool     `form:"hasImage"`
+	HideFuture    bool     `form:"hideFuture"`
+	Batch         string   `form:"batch"`
+	Order         string   `form:"order"`
+	SortBy        string   `form:"sortBy"`
+	SortOrder     string   `form:"sortOrder"`
+	All           bool     `form:"all"`
+	Consistent    bool     `form:"consistent"`
+	Snapshot      string   `form:"snapshot"`
+	MaxDesc       int      `form:"maxDesc" binding:"min=0"`
+	IDsOnly       bool     `form:"idsOnly"`
+	Collapse      bool     `form:"collapseDuplicates"`
+}
+
+// snapshotHeader is the response header holding the snapshot token used for listing articles.
+const snapshotHeader = "X-Snapshot"
+
+// nextCursorHeader is the response header holding the cursor of the next page of articles when they
+// are collapsed, since the last article returned may not be the last one of the page.
+const nextCursorHeader = "X-Next-Cursor"
+
+const (
+	// orderPublished lists articles by publish date.
+	orderPublished = "published"
+	// orderIngested lists articles by the time they were ingested.
+	orderIngested = "ingested"
+)
+
+// sortKeys maps the fields articles can be sorted by to their order.
+var sortKeys = map[string]types.ArticleOrder{
+	"publishDate": types.OrderPublished,
+	"ingestedAt":  types.OrderIngested,
+	"title":       types.OrderTitle,
+}
+
+// articleOrder returns the order requested by the list arguments, where sortBy supersedes the
+// order argument kept for compatibility. Returns false if the arguments are invalid.
+func articleOrder(args ListArgs) (types.ArticleOrder, bool) {
+	order := types.OrderPublished
+	switch {
+	case args.SortBy != "" && args.Order != "":
+		return 0, false
+	case args.SortBy != "":
+		var ok bool
+		if order, ok = sortKeys[args.SortBy]; !ok {
+			return 0, false
+		}
+	case args.Order == orderIngested:
+		order = types.OrderIngested
+	case args.Order != "" && args.Order != orderPublished:
+		return 0, false
+	}
+	switch args.SortOrder {
+	case "", "asc":
+	case "desc":
+		order |= types.OrderDescending
+	default:
+		return 0, false
+	}
+	return order, true
+}
+
+func (s *Service) listArticles(c *gin.Context) {
+	var args ListArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.tooManyCategories(args.Categories) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "too many category filters",
+		})
+		return
+	}
+
+	order, ok := articleOrder(args)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	// The store lists all articles for a zero page size, which is only done when explicitly requested.
+	pageSize := args.PageSize
+	if args.All {
+		pageSize = 0
+	} else if pageSize <= 0 {
+		pageSize = s.defaultPageSize
+	}
+	filter := types.ArticleFilter{
+		Feed:          args.Feed,
+		Categories:    args.Categories,
+		Labels:        args.Labels,
+		EnclosureType: args.EnclosureType,
+		NoEnclosures:  args.NoEnclosures,
+		HasFullText:   args.HasFullText,
+		HasImage:      args.HasImage,
+		HideFuture:    args.HideFuture,
+		Batch:         args.Batch,
+		Snapshot:      args.Snapshot,
+	}
+	// Consistent reads start by taking a snapshot, which clients send along with the cursor of the
+	// following pages so articles created in between are not returned.
+	if args.Consistent && filter.Snapshot == "" {
+		filter.Snapshot = s.articleStore.Snapshot()
+	}
+	articles, err := s.articleStore.ListFiltered(args.Cursor, pageSize, filter, order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if filter.Snapshot != "" {
+		c.Header(snapshotHeader, filter.Snapshot)
+	}
+	// Clients syncing many articles can list their IDs only, fetching the ones they are missing later.
+	if args.IDsOnly {
+		ids := make([]string, 0, len(articles))
+		for _, article := range articles {
+			ids = append(ids, article.ID)
+		}
+		c.JSON(http.StatusOK, ids)
+		return
+	}
+	// Duplicates are collapsed within the page, after paginating.
+	if args.Collapse {
+		if len(articles) > 0 {
+			c.Header(nextCursorHeader, articles[len(articles)-1].ID)
+		}
+		renderCollapsedArticles(c, collapseDuplicates(s.linkArticles(truncateArticles(articles, args.MaxDesc))))
+		return
+	}
+	renderArticles(c, s.linkArticles(truncateArticles(articles, args.MaxDesc)))
+}
+
+// GroupArticlesArgs represents the arguments in a group articles request. Articles can only be
+// grouped by provider.
+type GroupArticlesArgs struct {
+	By         string   `form:"by" binding:"required"`
+	PageSize   int      `form:"pageSize"`
+	Categories []string `form:"cat"`
+	Labels     []string `form:"label"`
+}
+
+// groupByProvider is the only grouping supported for articles.
+const groupByProvider = "provider"
+
+// groupArticles returns the newest page of articles of each provider, keyed by provider.
+func (s *Service) groupArticles(c *gin.Context) {
+	var args GroupArticlesArgs
+	if c.BindQuery(&args) != nil || args.By != groupByProvider || args.PageSize < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.tooManyCategories(args.Categories) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "too many category filters",
+		})
+		return
+	}
+	if args.PageSize == 0 {
+		args.PageSize = s.defaultPageSize
+	}
+	filter := types.ArticleFilter{Categories: args.Categories, Labels: args.Labels}
+	groups, err := s.articleStore.GroupByProvider(args.PageSize, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// NeighborsArgs represents the filters of an article neighbors request, which keep the navigation
+// within the filtered articles.
+type NeighborsArgs struct {
+	Feed          string   `form:"feed"`
+	Categories    []string `form:"cat"`
+	Labels        []string `form:"label"`
+	EnclosureType string   `form:"enclosureType"`
+	HasFullText   bool     `form:"hasFullText"`
+}
+
+// articleNeighbors returns the articles published right before and after the article, which are
+// null at either end.
+func (s *Service) articleNeighbors(c *gin.Context) {
+	var uriArgs GetArticleArgs
+	var args NeighborsArgs
+	if c.BindUri(&uriArgs) != nil || c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.tooManyCategories(args.Categories) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "too many category filters",
+		})
+		return
+	}
+	filter := types.ArticleFilter{
+		Feed:          args.Feed,
+		Categories:    args.Categories,
+		Labels:        args.Labels,
+		EnclosureType: args.EnclosureType,
+		HasFullText:   args.HasFullText,
+	}
+	prev, next, err := s.articleStore.Neighbors(uriArgs.ID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"previous": s.linkArticle(prev),
+		"next":     s.linkArticle(next),
+	})
+}
+
+// HistogramArgs represents the arguments in an article histogram request. Both days are
+// inclusive and optional.
+type HistogramArgs struct {
+	From          time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To            time.Time `form:"to" time_format:"2006-01-02" time_utc:"1"`
+	Feed          string    `form:"feed"`
+	Categories    []string  `form:"cat"`
+	Labels        []string  `form:"label"`
+	EnclosureType string    `form:"enclosureType"`
+}
+
+// articleHistogram returns the number of articles published on each day, keyed as YYYY-MM-DD.
+func (s *Service) articleHistogram(c *gin.Context) {
+	var args HistogramArgs
+	if c.BindQuery(&args) != nil || (!args.To.IsZero() && args.To.Before(args.From)) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.tooManyCategories(args.Categories) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "too many category filters",
+		})
+		return
+	}
+	// The store range excludes its end, so the whole last day is counted by ending on the next one.
+	to := args.To
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+	filter := types.ArticleFilter{
+		Feed:          args.Feed,
+		Categories:    args.Categories,
+		Labels:        args.Labels,
+		EnclosureType: args.EnclosureType,
+	}
+	counts, err := s.articleStore.CountByDay(args.From, to, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, counts)
+}
+
+// MarkReadArgs represents the arguments in a mark article read request.
+type MarkReadArgs struct {
+	Read *bool `json:"read" binding:"required"`
+}
+
+func (s *Service) markArticleRead(c *gin.Context) {
+	var uriArgs GetArticleArgs
+	var args MarkReadArgs
+	if c.BindUri(&uriArgs) != nil || c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	article, err := s.articleStore.MarkRead(uriArgs.ID, *args.Read)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, s.linkArticle(article))
+}
+
+// MarkStarredArgs represents the arguments in a mark article starred request.
+type MarkStarredArgs struct {
+	Starred *bool `json:"starred" binding:"required"`
+}
+
+func (s *Service) markArticleStarred(c *gin.Context) {
+	var uriArgs GetArticleArgs
+	var args MarkStarredArgs
+	if c.BindUri(&uriArgs) != nil || c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	article, err := s.articleStore.MarkStarred(uriArgs.ID, *args.Starred)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, s.linkArticle(article))
+}
+
+// AddLabelsArgs represents the arguments in an add article labels request.
+type AddLabelsArgs struct {
+	Labels []string `json:"labels" binding:"required"`
+}
+
+func (s *Service) addArticleLabels(c *gin.Context) {
+	var uriArgs GetArticleArgs
+	var args AddLabelsArgs
+	if c.BindUri(&uriArgs) != nil || c.BindJSON(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	article, err := s.articleStore.AddLabels(uriArgs.ID, args.Labels...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, s.linkArticle(article))
+}
+
+func (s *Service) deleteArticle(c *gin.Context) {
+	var args GetArticleArgs
+	if c.BindUri(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	tombstone, err := s.articleStore.Delete(args.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, tombstone)
+}
+
+func (s *Service) listTombstones(c *gin.Context) {
+	c.JSON(http.StatusOK, s.articleStore.ListTombstones())
+}
+
+// ClearTombstonesArgs represents the arguments in a clear tombstones request, where no IDs means all
+// tombstones are cleared.
+type ClearTombstonesArgs struct {
+	IDs []string `form:"id"`
+}
+
+func (s *Service) clearTombstones(c *gin.Context) {
+	var args ClearTombstonesArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	cleared, err := s.articleStore.ClearTombstones(args.IDs...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"cleared": cleared,
+	})
+}
+
+// RemoveLabelArgs represents the arguments in a remove article label request.
+type RemoveLabelArgs struct {
+	ID    string `uri:"id" binding:"required"`
+	Label string `uri:"label" binding:"required"`
+}
+
+func (s *Service) removeArticleLabel(c *gin.Context) {
+	var args RemoveLabelArgs
+	if c.BindUri(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	article, err := s.articleStore.RemoveLabel(args.ID, args.Label)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, s.linkArticle(article))
+}
+
+func (s *Service) unreadCounts(c *gin.Context) {
+	c.JSON(http.StatusOK, s.articleStore.UnreadCountsByCategory())
+}
+
+// TrendingCategoriesArgs represents the arguments in a trending categories request, where the window
+// is a duration such as "24h".
+type TrendingCategoriesArgs struct {
+	Window string   `form:"window"`
+	Limit  int      `form:"limit" binding:"min=0"`
+	Feed   string   `form:"feed"`
+	Labels []string `form:"label"`
+}
+
+const (
+	// defaultTrendingWindow is the time until now within which articles are counted for trending
+	// categories when no window is requested.
+	defaultTrendingWindow = 24 * time.Hour
+	// defaultTrendingLimit is the number of trending categories returned when no limit is requested.
+	defaultTrendingLimit = 10
+)
+
+// trendingCategories returns the categories with the most articles published recently.
+func (s *Service) trendingCategories(c *gin.Context) {
+	var args TrendingCategoriesArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	window := defaultTrendingWindow
+	if args.Window != "" {
+		var err error
+		if window, err = time.ParseDuration(args.Window); err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid arguments",
+			})
+			return
+		}
+	}
+	if args.Limit == 0 {
+		args.Limit = defaultTrendingLimit
+	}
+	filter := types.ArticleFilter{Feed: args.Feed, Labels: args.Labels}
+	categories, err := s.articleStore.TrendingCategories(window, args.Limit, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, categories)
+}
+
+// CategoryFeedsArgs represents the arguments in a category feeds request.
+type CategoryFeedsArgs struct {
+	Category string `uri:"category" binding:"required"`
+}
+
+// categoryFeeds returns the IDs of the feeds contributing articles to a category.
+func (s *Service) categoryFeeds(c *gin.Context) {
+	var args CategoryFeedsArgs
+	if c.BindUri(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, s.articleStore.FeedsForCategory(args.Category))
+}
+
+// CursorsArgs represents the arguments accepted in an article cursors request.
+type CursorsArgs struct {
+	Feed       string   `form:"feed"`
+	Categories []string `form:"cat"`
+}
+
+func (s *Service) articleCursors(c *gin.Context) {
+	var args CursorsArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.tooManyCategories(args.Categories) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "too many category filters",
+		})
+		return
+	}
+	filter := types.ArticleFilter{
+		Feed:       args.Feed,
+		Categories: args.Categories,
+	}
+	first, err := s.articleStore.FirstCursor(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	last, err := s.articleStore.LastCursor(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"first": first,
+		"last":  last,
+	})
 }
@@ -0,0 +1,3242 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../clock"
+	"../feedconsumer"
+	"../rssreader"
+	"../store"
+	"../types"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// rssFixture returns a valid rss document with the provided title and number of items.
+func rssFixture(title string, items int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>%s</title>`, title)
+	for i := 0; i < items; i++ {
+		fmt.Fprintf(&b, `<item><guid>guid_%d</guid><title>title_%d</title>`, i, i)
+		fmt.Fprintf(&b, `<pubDate>Mon, 02 Jan 2006 15:%02d:05 GMT</pubDate></item>`, i)
+	}
+	b.WriteString(`</channel></rss>`)
+	return b.String()
+}
+
+// newFixtureServer returns a test server that always responds with the provided body.
+func newFixtureServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, body)
+	}))
+}
+
+// mutableFixtureServer is a test server responding with a body that can be changed between calls.
+type mutableFixtureServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	body string
+}
+
+func newMutableFixtureServer(body string) *mutableFixtureServer {
+	m := &mutableFixtureServer{body: body}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, m.body)
+	}))
+	return m
+}
+
+func (m *mutableFixtureServer) setBody(body string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.body = body
+}
+
+// newTestService returns a service wired with real components and in-memory stores.
+func newTestService() (*Service, *store.FeedStore, *store.ArticleStore) {
+	feedStore := store.NewFeedStore()
+	articleStore := store.NewArticleStore()
+	reader := rssreader.NewFeed()
+	consumer := feedconsumer.NewFeedConsumer(reader, articleStore,
+		feedconsumer.WithFeedStore(feedStore),
+		feedconsumer.WithLoadRecorder(feedStore),
+	)
+	return NewService(consumer, reader, feedStore, articleStore), feedStore, articleStore
+}
+
+func performRequest(h http.Handler, method string, path string, body io.Reader) *httptest.ResponseRecorder {
+	return performRequestWithHeader(h, method, path, body, nil)
+}
+
+func performRequestWithHeader(h http.Handler, method string, path string, body io.Reader, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func jsonBody(v interface{}) io.Reader {
+	data, _ := json.Marshal(v)
+	return bytes.NewReader(data)
+}
+
+func TestTestFeed(t *testing.T) {
+	fixture := newFixtureServer(rssFixture("Fixture News", 7))
+	defer fixture.Close()
+	invalidFixture := newFixtureServer("not a feed")
+	defer invalidFixture.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	router := NewService(nil, rssreader.NewFeed(), nil, nil).setupServiceRouter()
+
+	t.Run("returns the channel title and a preview of the articles", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/test", jsonBody(map[string]string{
+			"address": fixture.URL,
+		}))
+		r.Equal(http.StatusOK, w.Code)
+		var channel types.Channel
+		r.NoError(json.NewDecoder(w.Body).Decode(&channel))
+		a.Equal("Fixture News", channel.Title)
+		r.Len(channel.Articles, feedPreviewSize, "unexpected number of articles")
+		a.Equal("guid_0", channel.Articles[0].GUID)
+		a.Equal("title_0", channel.Articles[0].Title)
+		a.Empty(channel.Articles[0].ID, "previewed articles must not be stored")
+	})
+
+	t.Run("bad gateway for unreachable address", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/test", jsonBody(map[string]string{
+			"address": unreachable.URL,
+		}))
+		a.Equal(http.StatusBadGateway, w.Code)
+		a.Contains(w.Body.String(), "could not read feed")
+	})
+
+	t.Run("bad gateway for unparseable feed", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/test", jsonBody(map[string]string{
+			"address": invalidFixture.URL,
+		}))
+		a.Equal(http.StatusBadGateway, w.Code)
+		a.Contains(w.Body.String(), "could not read feed")
+	})
+
+	t.Run("bad request without address", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/test", jsonBody(map[string]string{}))
+		a.Equal(http.StatusBadRequest, w.Code)
+		a.Contains(w.Body.String(), "invalid arguments")
+	})
+}
+
+func TestUnreadCounts(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	first, err := articleStore.Create(&types.Article{GUID: "first", Categories: []string{"cat_1"}})
+	r.NoError(err)
+	_, err = articleStore.Create(&types.Article{GUID: "second", Categories: []string{"cat_1", "cat_2"}})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	w := performRequest(router, http.MethodPost, "/articles/"+first.ID+"/read", jsonBody(map[string]bool{
+		"read": true,
+	}))
+	r.Equal(http.StatusOK, w.Code)
+	var article types.Article
+	r.NoError(json.NewDecoder(w.Body).Decode(&article))
+	a.True(article.Read)
+
+	w = performRequest(router, http.MethodGet, "/articles/unread-counts", nil)
+	r.Equal(http.StatusOK, w.Code)
+	var counts map[string]int
+	r.NoError(json.NewDecoder(w.Body).Decode(&counts))
+	a.Equal(map[string]int{"cat_1": 1, "cat_2": 1}, counts)
+}
+
+func TestListArticlesContentNegotiation(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	_, err := articleStore.Create(&types.Article{
+		GUID:        "first",
+		Title:       "title",
+		Link:        "link",
+		Description: "description",
+		Categories:  []string{"cat_1"},
+		Enclosures:  []*types.Enclosure{&types.Enclosure{URL: "url", Type: "image/png"}},
+	})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	t.Run("defaults to json", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles", nil)
+		r.Equal(http.StatusOK, w.Code)
+		a.Contains(w.Header().Get("Content-Type"), "application/json")
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("first", articles[0].GUID)
+	})
+
+	t.Run("renders json when requested", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequestWithHeader(router, http.MethodGet, "/articles", nil, http.Header{
+			"Accept": []string{"application/json"},
+		})
+		r.Equal(http.StatusOK, w.Code)
+		a.Contains(w.Header().Get("Content-Type"), "application/json")
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		r.Len(articles, 1, "unexpected number of articles")
+	})
+
+	t.Run("renders rss when requested", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequestWithHeader(router, http.MethodGet, "/articles", nil, http.Header{
+			"Accept": []string{"application/rss+xml"},
+		})
+		r.Equal(http.StatusOK, w.Code)
+		a.Contains(w.Header().Get("Content-Type"), "application/rss+xml")
+		var doc rssDocument
+		r.NoError(xml.NewDecoder(w.Body).Decode(&doc))
+		a.Equal("2.0", doc.Version)
+		r.Len(doc.Channel.Items, 1, "unexpected number of items")
+		a.Equal("first", doc.Channel.Items[0].GUID)
+		a.Equal("title", doc.Channel.Items[0].Title)
+		a.Equal([]string{"cat_1"}, doc.Channel.Items[0].Categories)
+		r.Len(doc.Channel.Items[0].Enclosures, 1, "unexpected number of enclosures")
+		a.Equal("url", doc.Channel.Items[0].Enclosures[0].URL)
+	})
+
+	t.Run("renders json feed when requested", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequestWithHeader(router, http.MethodGet, "/articles", nil, http.Header{
+			"Accept": []string{"application/feed+json"},
+		})
+		r.Equal(http.StatusOK, w.Code)
+		a.Contains(w.Header().Get("Content-Type"), "application/feed+json")
+		var feed jsonFeed
+		r.NoError(json.NewDecoder(w.Body).Decode(&feed))
+		a.Equal(jsonFeedVersion, feed.Version)
+		r.Len(feed.Items, 1, "unexpected number of items")
+		a.Equal("link", feed.Items[0].URL)
+		a.Equal("description", feed.Items[0].ContentHTML)
+		r.Len(feed.Items[0].Attachments, 1, "unexpected number of attachments")
+		a.Equal("image/png", feed.Items[0].Attachments[0].MIMEType)
+	})
+}
+
+func TestLoadFeedForce(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fixture := newMutableFixtureServer(rssFixture("Fixture News", 2))
+	defer fixture.Close()
+	s, feedStore, articleStore := newTestService()
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	r.NoError(err)
+
+	w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	articles, err := articleStore.List("", 0, "")
+	r.NoError(err)
+	r.Len(articles, 2, "unexpected number of articles")
+	a.Equal("title_1", articles[1].Title)
+
+	// The feed corrects an article in place.
+	fixture.setBody(strings.Replace(rssFixture("Fixture News", 2), "title_1", "corrected_title", 1))
+
+	w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	article, err := articleStore.Get(articles[1].ID)
+	r.NoError(err)
+	a.Equal("title_1", article.Title, "articles must not be updated without force")
+
+	w = performRequest(router, http.MethodPost, "/feeds/load?force=true", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	var summary types.LoadSummary
+	r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+	a.Equal(0, summary.Created)
+	a.Equal(1, summary.Updated)
+	a.Equal(1, summary.Unchanged)
+	a.Equal(map[string][]string{articles[1].ID: []string{"Title"}}, summary.Changes)
+	article, err = articleStore.Get(articles[1].ID)
+	r.NoError(err)
+	a.Equal("corrected_title", article.Title)
+	articles, err = articleStore.List("", 0, "")
+	r.NoError(err)
+	a.Len(articles, 2, "unexpected number of articles")
+}
+
+func TestLoadFeedCategoryFromChannel(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fixture := newFixtureServer(rssFixture("Fixture News", 1))
+	defer fixture.Close()
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+
+	w := performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{"provider": "provider", "address": fixture.URL}))
+	r.Equal(http.StatusOK, w.Code)
+	var feed types.Feed
+	r.NoError(json.NewDecoder(w.Body).Decode(&feed))
+	a.Empty(feed.Category)
+
+	w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	stored, err := feedStore.Get(feed.ID)
+	r.NoError(err)
+	a.Equal("Fixture News", stored.Category)
+}
+
+func TestLoadFeedSummary(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fixture := newFixtureServer(rssFixture("Fixture News", 3))
+	defer fixture.Close()
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	r.NoError(err)
+
+	w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	var summary types.LoadSummary
+	r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+	a.Equal(3, summary.Created)
+	a.Equal(0, summary.Unchanged)
+
+	// An identical forced reload reports no changes.
+	w = performRequest(router, http.MethodPost, "/feeds/load?force=true", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	summary = types.LoadSummary{}
+	r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+	a.Equal(0, summary.Created)
+	a.Equal(0, summary.Updated)
+	a.Equal(3, summary.Unchanged)
+	a.Empty(summary.Changes)
+}
+
+func TestGraphQL(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	for i, categories := range [][]string{{"cat_1"}, {"cat_2"}, {"cat_1", "cat_2"}} {
+		_, err := articleStore.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			Title:       fmt.Sprintf("title_%d", i),
+			Link:        "link",
+			Categories:  categories,
+			PublishDate: time.Unix(int64(i), 0).UTC(),
+		})
+		r.NoError(err)
+	}
+	feedStore := store.NewFeedStore()
+	_, err := feedStore.Create(&types.Feed{Provider: "provider", Category: "category", Address: "address"})
+	r.NoError(err)
+	router := NewService(nil, nil, feedStore, articleStore).setupServiceRouter()
+
+	t.Run("queries articles filtered by category", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/graphql", jsonBody(map[string]interface{}{
+			"query": `query Articles($category: String) {
+				articles(filter: {categories: [$category]}, pageSize: 10) { guid title categories }
+			}`,
+			"variables": map[string]string{"category": "cat_1"},
+		}))
+		r.Equal(http.StatusOK, w.Code)
+		a.JSONEq(`{"data": {"articles": [
+			{"guid": "guid_0", "title": "title_0", "categories": ["cat_1"]},
+			{"guid": "guid_2", "title": "title_2", "categories": ["cat_1", "cat_2"]}
+		]}}`, w.Body.String())
+	})
+
+	t.Run("queries feeds and articles at once", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/graphql", jsonBody(map[string]string{
+			"query": `{ feeds { provider address } first: articles(pageSize: 1) { title } }`,
+		}))
+		r.Equal(http.StatusOK, w.Code)
+		a.JSONEq(`{"data": {
+			"feeds": [{"provider": "provider", "address": "address"}],
+			"first": [{"title": "title_0"}]
+		}}`, w.Body.String())
+	})
+
+	t.Run("lists the default page size when none is provided", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		router := NewService(nil, nil, feedStore, articleStore, WithDefaultPageSize(2)).setupServiceRouter()
+		for _, query := range []string{`{ articles { title } }`, `{ articles(pageSize: 0) { title } }`} {
+			w := performRequest(router, http.MethodPost, "/graphql", jsonBody(map[string]string{
+				"query": query,
+			}))
+			r.Equal(http.StatusOK, w.Code, query)
+			a.JSONEq(`{"data": {"articles": [{"title": "title_0"}, {"title": "title_1"}]}}`, w.Body.String(), query)
+		}
+	})
+
+	t.Run("reports unknown fields", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/graphql", jsonBody(map[string]string{
+			"query": `{ articles { unknown } }`,
+		}))
+		r.Equal(http.StatusOK, w.Code)
+		a.Contains(w.Body.String(), `unknown field \"unknown\" on Article`)
+	})
+
+	t.Run("bad request for invalid query", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/graphql", jsonBody(map[string]string{
+			"query": `{ articles { title }`,
+		}))
+		a.Equal(http.StatusBadRequest, w.Code)
+		a.Contains(w.Body.String(), "syntax error")
+	})
+
+	t.Run("request too large for oversized body", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/graphql", jsonBody(map[string]string{
+			"query": "{ feeds { id } }" + strings.Repeat(" ", maxGraphQLSize),
+		}))
+		a.Equal(http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
+
+func TestListArticlesOrder(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	_, err := articleStore.Create(&types.Article{GUID: "newer", Title: "a title", PublishDate: time.Unix(20, 0).UTC()})
+	r.NoError(err)
+	_, err = articleStore.Create(&types.Article{GUID: "older", Title: "B title", PublishDate: time.Unix(10, 0).UTC()})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for order, expected := range map[string][]string{
+		"":          {"older", "newer"},
+		"published": {"older", "newer"},
+		"ingested":  {"newer", "older"},
+	} {
+		w := performRequest(router, http.MethodGet, "/articles?order="+order, nil)
+		r.Equal(http.StatusOK, w.Code, order)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		r.Len(articles, 2, "unexpected number of articles")
+		assert.Equal(t, expected, []string{articles[0].GUID, articles[1].GUID}, order)
+	}
+
+	for query, expected := range map[string][]string{
+		"sortBy=publishDate":                {"older", "newer"},
+		"sortBy=publishDate&sortOrder=desc": {"newer", "older"},
+		"sortBy=ingestedAt":                 {"newer", "older"},
+		"sortBy=ingestedAt&sortOrder=desc":  {"older", "newer"},
+		"sortBy=title&sortOrder=asc":        {"newer", "older"},
+		"sortBy=title&sortOrder=desc":       {"older", "newer"},
+		"order=ingested&sortOrder=desc":     {"older", "newer"},
+	} {
+		w := performRequest(router, http.MethodGet, "/articles?"+query, nil)
+		r.Equal(http.StatusOK, w.Code, query)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		r.Len(articles, 2, "unexpected number of articles")
+		assert.Equal(t, expected, []string{articles[0].GUID, articles[1].GUID}, query)
+	}
+
+	for _, query := range []string{"order=unknown", "sortBy=unknown", "sortOrder=up", "sortBy=title&order=ingested"} {
+		w := performRequest(router, http.MethodGet, "/articles?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestGetEnclosure(t *testing.T) {
+	image := []byte("\x89PNG fake image")
+	media := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("ETag", `"etag"`)
+			w.Write(image)
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(bytes.Repeat([]byte("a"), 100))
+		case "/chunked.png":
+			// Flushing before writing the content leaves its length unknown.
+			w.Header().Set("Content-Type", "image/png")
+			w.(http.Flusher).Flush()
+			w.Write(bytes.Repeat([]byte("a"), 100))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<script>alert(1)</script>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer media.Close()
+
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	article, err := articleStore.Create(&types.Article{
+		GUID: "guid",
+		Enclosures: []*types.Enclosure{
+			&types.Enclosure{URL: media.URL + "/image.png", Type: "image/png"},
+			&types.Enclosure{URL: media.URL + "/missing.png", Type: "image/png"},
+			&types.Enclosure{URL: media.URL + "/large.png", Type: "image/png"},
+			&types.Enclosure{URL: media.URL + "/chunked.png", Type: "image/png"},
+			&types.Enclosure{URL: media.URL + "/page.html", Type: "image/png"},
+		},
+	})
+	r.NoError(err)
+	s := NewService(nil, nil, nil, articleStore)
+	s.maxEnclosureSize = 50
+	router := s.setupServiceRouter()
+
+	t.Run("streams the enclosure content", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"/enclosure/0", nil)
+		r.Equal(http.StatusOK, w.Code)
+		a.Equal("image/png", w.Header().Get("Content-Type"))
+		a.Equal(enclosureCacheControl, w.Header().Get("Cache-Control"))
+		a.Equal(`"etag"`, w.Header().Get("ETag"))
+		a.Equal("nosniff", w.Header().Get("X-Content-Type-Options"))
+		a.Equal("attachment", w.Header().Get("Content-Disposition"))
+		a.Equal(image, w.Body.Bytes())
+	})
+
+	t.Run("not found for invalid index", func(t *testing.T) {
+		a := assert.New(t)
+		for _, index := range []string{"5", "-1"} {
+			w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"/enclosure/"+index, nil)
+			a.Equal(http.StatusNotFound, w.Code, index)
+		}
+	})
+
+	t.Run("bad request for non numeric index", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"/enclosure/first", nil)
+		a.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("bad gateway for failing origin", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"/enclosure/1", nil)
+		a.Equal(http.StatusBadGateway, w.Code)
+		a.Contains(w.Body.String(), "unexpected status code 404")
+	})
+
+	t.Run("bad gateway for enclosures exceeding the maximum size", func(t *testing.T) {
+		a := assert.New(t)
+		for _, index := range []string{"2", "3"} {
+			w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"/enclosure/"+index, nil)
+			a.Equal(http.StatusBadGateway, w.Code, index)
+			a.Contains(w.Body.String(), "maximum size exceeded", index)
+		}
+	})
+
+	t.Run("bad gateway for content other than media", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"/enclosure/4", nil)
+		a.Equal(http.StatusBadGateway, w.Code)
+		a.Contains(w.Body.String(), "unsupported content type")
+		a.NotContains(w.Body.String(), "<script>")
+	})
+}
+
+func TestFeedCredentials(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fixture := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, rssFixture("Private News", 1))
+	}))
+	defer fixture.Close()
+	s, _, _ := newTestService()
+	router := s.setupServiceRouter()
+
+	w := performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{
+		"provider": "provider",
+		"category": "category",
+		"address":  fixture.URL,
+		"username": "user",
+		"password": "secret",
+	}))
+	r.Equal(http.StatusOK, w.Code)
+	a.NotContains(w.Body.String(), "secret")
+	var feed types.Feed
+	r.NoError(json.NewDecoder(w.Body).Decode(&feed))
+
+	w = performRequest(router, http.MethodGet, "/feeds", nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.NotContains(w.Body.String(), "secret")
+	w = performRequest(router, http.MethodGet, "/feeds/"+feed.ID, nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.NotContains(w.Body.String(), "secret")
+
+	w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	var summary types.LoadSummary
+	r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+	a.Equal(1, summary.Created)
+
+	w = performRequest(router, http.MethodPost, "/feeds/test", jsonBody(map[string]string{"address": fixture.URL}))
+	a.Equal(http.StatusBadGateway, w.Code)
+	w = performRequest(router, http.MethodPost, "/feeds/test", jsonBody(map[string]string{
+		"address":  fixture.URL,
+		"username": "user",
+		"password": "secret",
+	}))
+	a.Equal(http.StatusOK, w.Code)
+}
+
+func TestPrettyJSON(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	_, err := articleStore.Create(&types.Article{GUID: "guid", Categories: []string{"cat_1"}})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	t.Run("compact by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/unread-counts", nil)
+		r.Equal(http.StatusOK, w.Code)
+		a.Equal(`{"cat_1":1}`, w.Body.String())
+	})
+
+	t.Run("indented when requested", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/unread-counts?pretty=true", nil)
+		r.Equal(http.StatusOK, w.Code)
+		a.Contains(w.Header().Get("Content-Type"), "application/json")
+		a.Equal("{\n  \"cat_1\": 1\n}\n", w.Body.String())
+	})
+
+	t.Run("keeps the status code of errors", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/unknown?pretty=true", nil)
+		r.Equal(http.StatusInternalServerError, w.Code)
+		a.Equal("{\n  \"error\": \"resource not found\"\n}\n", w.Body.String())
+	})
+
+	t.Run("leaves other formats untouched", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequestWithHeader(router, http.MethodGet, "/articles?pretty=true", nil, http.Header{
+			"Accept": []string{"application/rss+xml"},
+		})
+		r.Equal(http.StatusOK, w.Code)
+		a.True(strings.HasPrefix(w.Body.String(), xml.Header+"<rss"))
+	})
+
+	t.Run("streams other formats without buffering", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := httptest.NewRecorder()
+		var flushed string
+		streaming := gin.New()
+		streaming.Use(prettyJSON)
+		streaming.GET("/stream", func(c *gin.Context) {
+			c.Header("Content-Type", mimeCSV)
+			c.Status(http.StatusOK)
+			c.Writer.WriteString("first\n")
+			c.Writer.Flush()
+			flushed = w.Body.String()
+			c.Writer.WriteString("second\n")
+		})
+		streaming.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream?pretty=true", nil))
+		r.Equal(http.StatusOK, w.Code)
+		a.Equal("first\n", flushed, "flushed content must be sent right away")
+		a.Equal("first\nsecond\n", w.Body.String())
+	})
+}
+
+func TestGetArticles(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	first, err := articleStore.Create(&types.Article{GUID: "first"})
+	r.NoError(err)
+	second, err := articleStore.Create(&types.Article{GUID: "second"})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	t.Run("returns articles in request order marking missing ones", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/batch?ids="+second.ID+",missing,"+first.ID, nil)
+		r.Equal(http.StatusOK, w.Code)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		r.Len(articles, 3, "unexpected number of articles")
+		a.Equal("second", articles[0].GUID)
+		a.Nil(articles[1])
+		a.Equal("first", articles[2].GUID)
+	})
+
+	t.Run("bad request without ids", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/batch", nil)
+		a.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("bad request for too many ids", func(t *testing.T) {
+		a := assert.New(t)
+		IDs := strings.Repeat("id,", maxBatchSize) + "id"
+		w := performRequest(router, http.MethodGet, "/articles/batch?ids="+IDs, nil)
+		a.Equal(http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestGetRawFeed(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	body := rssFixture("Fixture News", 2)
+	fixture := newFixtureServer(body)
+	defer fixture.Close()
+	s, feedStore, articleStore := newTestService()
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	r.NoError(err)
+
+	w := performRequest(router, http.MethodGet, "/feeds/"+feed.ID+"/raw", nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.Equal("application/rss+xml", w.Header().Get("Content-Type"))
+	a.Equal(body, w.Body.String())
+	articles, err := articleStore.List("", 0, "")
+	r.NoError(err)
+	a.Empty(articles, "raw feeds must not be stored")
+
+	fixture.Close()
+	w = performRequest(router, http.MethodGet, "/feeds/"+feed.ID+"/raw", nil)
+	a.Equal(http.StatusBadGateway, w.Code)
+}
+
+func TestRefreshFeed(t *testing.T) {
+	fixture := newFixtureServer(rssFixture("Fixture News", 12))
+	defer fixture.Close()
+	// Article GUIDs are unique globally, so the other feed must not reuse the fixture ones.
+	other := newFixtureServer(strings.Replace(rssFixture("Other News", 1), "guid_0", "other_guid", 1))
+	defer other.Close()
+	r := require.New(t)
+	s, feedStore, articleStore := newTestService()
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	r.NoError(err)
+	otherFeed, err := feedStore.Create(&types.Feed{Address: other.URL})
+	r.NoError(err)
+	_, err = s.feeder.Consume(otherFeed, false)
+	r.NoError(err)
+
+	t.Run("loads the feed returning its latest articles", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/"+feed.ID+"/refresh", nil)
+		r.Equal(http.StatusOK, w.Code)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		r.Len(articles, refreshPageSize, "unexpected number of articles")
+		a.Equal("guid_11", articles[0].GUID)
+		a.Equal("guid_2", articles[refreshPageSize-1].GUID)
+		for _, article := range articles {
+			a.Equal(feed.ID, article.FeedID)
+		}
+		stored, err := articleStore.List("", 0, feed.ID)
+		r.NoError(err)
+		a.Len(stored, 12, "unexpected number of stored articles")
+	})
+
+	t.Run("accepts a page size", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/"+feed.ID+"/refresh?pageSize=3", nil)
+		r.Equal(http.StatusOK, w.Code)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		r.Len(articles, 3, "unexpected number of articles")
+		a.Equal("guid_11", articles[0].GUID)
+	})
+
+	t.Run("error for unknown feed", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/unknown/refresh", nil)
+		a.Equal(http.StatusInternalServerError, w.Code)
+		a.Contains(w.Body.String(), "resource not found")
+	})
+}
+
+func TestListArticlesPageSize(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	for i := 0; i < defaultPageSize+5; i++ {
+		_, err := articleStore.Create(&types.Article{GUID: fmt.Sprintf("guid_%d", i)})
+		r.NoError(err)
+	}
+	listArticles := func(router http.Handler, query string) []*types.Article {
+		w := performRequest(router, http.MethodGet, "/articles"+query, nil)
+		r.Equal(http.StatusOK, w.Code)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		return articles
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	t.Run("default page size when omitted", func(t *testing.T) {
+		a := assert.New(t)
+		a.Len(listArticles(router, ""), defaultPageSize, "unexpected number of articles")
+		a.Len(listArticles(router, "?pageSize=0"), defaultPageSize, "unexpected number of articles")
+	})
+
+	t.Run("requested page size", func(t *testing.T) {
+		a := assert.New(t)
+		a.Len(listArticles(router, "?pageSize=3"), 3, "unexpected number of articles")
+	})
+
+	t.Run("all articles when explicitly requested", func(t *testing.T) {
+		a := assert.New(t)
+		a.Len(listArticles(router, "?all=true"), defaultPageSize+5, "unexpected number of articles")
+	})
+
+	t.Run("configured default page size", func(t *testing.T) {
+		a := assert.New(t)
+		router := NewService(nil, nil, nil, articleStore, WithDefaultPageSize(7)).setupServiceRouter()
+		a.Len(listArticles(router, ""), 7, "unexpected number of articles")
+	})
+}
+
+func TestArticleLabels(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	first, err := articleStore.Create(&types.Article{GUID: "first"})
+	r.NoError(err)
+	_, err = articleStore.Create(&types.Article{GUID: "second"})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	w := performRequest(router, http.MethodPost, "/articles/"+first.ID+"/labels", jsonBody(map[string][]string{
+		"labels": {"work", "later"},
+	}))
+	r.Equal(http.StatusOK, w.Code)
+	var article types.Article
+	r.NoError(json.NewDecoder(w.Body).Decode(&article))
+	a.Equal([]string{"work", "later"}, article.Labels)
+
+	w = performRequest(router, http.MethodGet, "/articles?label=later", nil)
+	r.Equal(http.StatusOK, w.Code)
+	var articles []*types.Article
+	r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+	r.Len(articles, 1, "unexpected number of articles")
+	a.Equal("first", articles[0].GUID)
+
+	w = performRequest(router, http.MethodDelete, "/articles/"+first.ID+"/labels/later", nil)
+	r.Equal(http.StatusOK, w.Code)
+	article = types.Article{}
+	r.NoError(json.NewDecoder(w.Body).Decode(&article))
+	a.Equal([]string{"work"}, article.Labels)
+
+	w = performRequest(router, http.MethodGet, "/articles?label=later", nil)
+	r.Equal(http.StatusOK, w.Code)
+	articles = nil
+	r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+	a.Empty(articles)
+
+	w = performRequest(router, http.MethodPost, "/articles/"+first.ID+"/labels", jsonBody(map[string]string{}))
+	a.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestFeedTimeout(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprint(w, rssFixture("Slow News", 1))
+	}))
+	defer slow.Close()
+	fast := newFixtureServer(strings.Replace(rssFixture("Fast News", 1), "guid_0", "fast_guid", 1))
+	defer fast.Close()
+	s, _, _ := newTestService()
+	router := s.setupServiceRouter()
+
+	createFeed := func(address, timeout string) types.Feed {
+		w := performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{
+			"provider": "provider",
+			"category": "category",
+			"address":  address,
+			"timeout":  timeout,
+		}))
+		r.Equal(http.StatusOK, w.Code)
+		var feed types.Feed
+		r.NoError(json.NewDecoder(w.Body).Decode(&feed))
+		return feed
+	}
+	slowFeed := createFeed(slow.URL, "100ms")
+	a.Equal(100*time.Millisecond, slowFeed.Timeout)
+	fastFeed := createFeed(fast.URL, "5s")
+	a.Equal(5*time.Second, fastFeed.Timeout)
+
+	w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": slowFeed.ID}))
+	a.Equal(http.StatusInternalServerError, w.Code)
+	w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": fastFeed.ID}))
+	a.Equal(http.StatusOK, w.Code)
+
+	w = performRequest(router, http.MethodPatch, "/feeds/"+slowFeed.ID, jsonBody(map[string]string{"timeout": "5s"}))
+	r.Equal(http.StatusOK, w.Code)
+	var feed types.Feed
+	r.NoError(json.NewDecoder(w.Body).Decode(&feed))
+	a.Equal(5*time.Second, feed.Timeout)
+	w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": slowFeed.ID}))
+	a.Equal(http.StatusOK, w.Code)
+
+	for _, timeout := range []string{"soon", "-1s"} {
+		w = performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{
+			"address": fast.URL,
+			"timeout": timeout,
+		}))
+		a.Equal(http.StatusBadRequest, w.Code, timeout)
+		w = performRequest(router, http.MethodPatch, "/feeds/"+slowFeed.ID, jsonBody(map[string]string{"timeout": timeout}))
+		a.Equal(http.StatusBadRequest, w.Code, timeout)
+	}
+	w = performRequest(router, http.MethodPatch, "/feeds/"+slowFeed.ID, jsonBody(map[string]string{}))
+	a.Equal(http.StatusBadRequest, w.Code)
+	w = performRequest(router, http.MethodPatch, "/feeds/unknown", jsonBody(map[string]string{"timeout": "1s"}))
+	a.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func TestTrailingSlash(t *testing.T) {
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: "http://localhost/feed"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		method   string
+		path     string
+		body     interface{}
+		expected int
+	}{
+		{http.MethodGet, "/articles", nil, http.StatusOK},
+		{http.MethodGet, "/articles/", nil, http.StatusMovedPermanently},
+		{http.MethodGet, "/feeds", nil, http.StatusOK},
+		{http.MethodGet, "/feeds/", nil, http.StatusMovedPermanently},
+		{http.MethodGet, "/feeds/" + feed.ID, nil, http.StatusOK},
+		{http.MethodGet, "/feeds/" + feed.ID + "/", nil, http.StatusMovedPermanently},
+		{http.MethodPost, "/feeds/test/", map[string]string{"address": "http://localhost/feed"}, http.StatusTemporaryRedirect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != nil {
+				body = jsonBody(tt.body)
+			}
+			w := performRequest(router, tt.method, tt.path, body)
+			require.Equal(t, tt.expected, w.Code)
+			if tt.expected != http.StatusOK {
+				assert.Equal(t, strings.TrimSuffix(tt.path, "/"), w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestListArticlesEnclosureType(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	_, err := articleStore.Create(&types.Article{
+		GUID:       "image",
+		Enclosures: []*types.Enclosure{{URL: "http://localhost/image.png", Type: "image/png"}},
+	})
+	r.NoError(err)
+	_, err = articleStore.Create(&types.Article{
+		GUID:       "audio",
+		Enclosures: []*types.Enclosure{{URL: "http://localhost/audio.ogg", Type: "audio/ogg"}},
+	})
+	r.NoError(err)
+	_, err = articleStore.Create(&types.Article{GUID: "none"})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for enclosureType, expected := range map[string][]string{
+		"image/": {"image"},
+		"audio/": {"audio"},
+		"video/": {},
+	} {
+		w := performRequest(router, http.MethodGet, "/articles?enclosureType="+enclosureType, nil)
+		r.Equal(http.StatusOK, w.Code, enclosureType)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		guids := []string{}
+		for _, article := range articles {
+			guids = append(guids, article.GUID)
+		}
+		assert.Equal(t, expected, guids, enclosureType)
+	}
+}
+
+func TestInjectArticle(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	w := performRequest(router, http.MethodPost, "/articles", jsonBody(map[string]string{
+		"guid":  "manual_guid",
+		"title": "original title",
+	}))
+	r.Equal(http.StatusOK, w.Code)
+	var created types.Article
+	r.NoError(json.NewDecoder(w.Body).Decode(&created))
+	a.NotEmpty(created.ID)
+	a.Equal("original title", created.Title)
+
+	t.Run("existing GUID returns the stored article by default", func(t *testing.T) {
+		w := performRequest(router, http.MethodPost, "/articles", jsonBody(map[string]string{
+			"guid":  "manual_guid",
+			"title": "other title",
+		}))
+		r.Equal(http.StatusOK, w.Code)
+		var article types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&article))
+		a.Equal(created.ID, article.ID)
+		a.Equal("original title", article.Title)
+	})
+
+	t.Run("existing GUID conflicts with failIfExists", func(t *testing.T) {
+		w := performRequest(router, http.MethodPost, "/articles?failIfExists=true", jsonBody(map[string]string{
+			"guid":  "manual_guid",
+			"title": "other title",
+		}))
+		r.Equal(http.StatusConflict, w.Code)
+		var body map[string]string
+		r.NoError(json.NewDecoder(w.Body).Decode(&body))
+		a.Equal(created.ID, body["id"])
+		article, err := articleStore.Get(created.ID)
+		r.NoError(err)
+		a.Equal("original title", article.Title)
+	})
+
+	t.Run("new GUID is created with failIfExists", func(t *testing.T) {
+		w := performRequest(router, http.MethodPost, "/articles?failIfExists=true", jsonBody(map[string]string{
+			"guid": "new_guid",
+		}))
+		a.Equal(http.StatusOK, w.Code)
+	})
+
+	t.Run("GUID is required", func(t *testing.T) {
+		w := performRequest(router, http.MethodPost, "/articles", jsonBody(map[string]string{
+			"title": "no guid",
+		}))
+		a.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	articles, err := articleStore.List("", 0, "")
+	r.NoError(err)
+	a.Len(articles, 2, "unexpected number of articles")
+}
+
+func TestListArticlesConsistent(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	for i := 0; i < 4; i++ {
+		_, err := articleStore.Create(&types.Article{GUID: fmt.Sprintf("guid_%d", i), PublishDate: time.Unix(int64(10*i), 0).UTC()})
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+	list := func(query string) ([]*types.Article, string) {
+		w := performRequest(router, http.MethodGet, "/articles?"+query, nil)
+		r.Equal(http.StatusOK, w.Code)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		return articles, w.Header().Get(snapshotHeader)
+	}
+
+	articles, snapshot := list("pageSize=2&consistent=true")
+	r.NotEmpty(snapshot)
+	r.Len(articles, 2, "unexpected number of articles")
+	_, err := articleStore.Create(&types.Article{GUID: "new_guid", PublishDate: time.Unix(15, 0).UTC()})
+	r.NoError(err)
+
+	articles, header := list("pageSize=2&snapshot=" + snapshot + "&c=" + articles[1].ID)
+	a.Equal(snapshot, header)
+	r.Len(articles, 2, "unexpected number of articles")
+	a.Equal("guid_2", articles[0].GUID)
+
+	articles, header = list("all=true")
+	a.Empty(header)
+	a.Len(articles, 5, "unexpected number of articles")
+}
+
+func TestGroupArticles(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	bbc := newFixtureServer(rssFixture("BBC News", 3))
+	defer bbc.Close()
+	cnn := newFixtureServer(strings.Replace(rssFixture("CNN News", 2), "guid_", "cnn_guid_", -1))
+	defer cnn.Close()
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	for provider, address := range map[string]string{"BBC": bbc.URL, "CNN": cnn.URL} {
+		feed, err := feedStore.Create(&types.Feed{Provider: provider, Address: address})
+		r.NoError(err)
+		w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		r.Equal(http.StatusOK, w.Code)
+	}
+
+	w := performRequest(router, http.MethodGet, "/articles/grouped?by=provider&pageSize=2", nil)
+	r.Equal(http.StatusOK, w.Code)
+	var groups map[string][]*types.Article
+	r.NoError(json.NewDecoder(w.Body).Decode(&groups))
+	r.Len(groups, 2, "unexpected number of groups")
+	r.Len(groups["BBC"], 2, "unexpected number of articles")
+	r.Len(groups["CNN"], 2, "unexpected number of articles")
+	for provider, articles := range groups {
+		for _, article := range articles {
+			a.Equal(provider, article.Provider)
+		}
+		a.True(articles[0].PublishDate.After(articles[1].PublishDate), "articles must be ordered newest first")
+	}
+
+	for _, query := range []string{"", "?by=feed", "?by=provider&pageSize=-1"} {
+		w = performRequest(router, http.MethodGet, "/articles/grouped"+query, nil)
+		a.Equal(http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestUI(t *testing.T) {
+	t.Run("serves the embedded reader when enabled", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		router := NewService(nil, nil, nil, store.NewArticleStore(), WithUI(true)).setupServiceRouter()
+		w := performRequest(router, http.MethodGet, "/ui/", nil)
+		r.Equal(http.StatusOK, w.Code)
+		a.True(strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"), w.Header().Get("Content-Type"))
+		a.Contains(w.Body.String(), "<title>znews</title>")
+
+		w = performRequest(router, http.MethodGet, "/ui", nil)
+		a.Equal(http.StatusMovedPermanently, w.Code)
+		w = performRequest(router, http.MethodGet, "/articles", nil)
+		a.Equal(http.StatusOK, w.Code)
+		a.Equal(gin.MIMEJSON+"; charset=utf-8", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("is not served by default", func(t *testing.T) {
+		router := NewService(nil, nil, nil, store.NewArticleStore()).setupServiceRouter()
+		w := performRequest(router, http.MethodGet, "/ui/", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestArticleHistogram(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	day := time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		_, err := articleStore.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			FeedID:      fmt.Sprintf("feed_%d", i%2),
+			PublishDate: day.AddDate(0, 0, i/2),
+		})
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for query, expected := range map[string]map[string]int{
+		"":                               {"2021-01-01": 2, "2021-01-02": 2, "2021-01-03": 2},
+		"?from=2021-01-02":               {"2021-01-02": 2, "2021-01-03": 2},
+		"?from=2021-01-01&to=2021-01-02": {"2021-01-01": 2, "2021-01-02": 2},
+		"?feed=feed_1&to=2021-01-02":     {"2021-01-01": 1, "2021-01-02": 1},
+		"?from=2021-01-04":               {},
+	} {
+		w := performRequest(router, http.MethodGet, "/articles/histogram"+query, nil)
+		r.Equal(http.StatusOK, w.Code, query)
+		var counts map[string]int
+		r.NoError(json.NewDecoder(w.Body).Decode(&counts))
+		a.Equal(expected, counts, query)
+	}
+
+	for _, query := range []string{"?from=yesterday", "?from=2021-01-02&to=2021-01-01"} {
+		w := performRequest(router, http.MethodGet, "/articles/histogram"+query, nil)
+		a.Equal(http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestMergeFeeds(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	source := newFixtureServer(rssFixture("Source News", 2))
+	defer source.Close()
+	target := newFixtureServer(strings.Replace(rssFixture("Target News", 1), "guid_", "target_guid_", -1))
+	defer target.Close()
+	s, feedStore, articleStore := newTestService()
+	router := s.setupServiceRouter()
+	sourceFeed, err := feedStore.Create(&types.Feed{Provider: "Source", Address: source.URL})
+	r.NoError(err)
+	targetFeed, err := feedStore.Create(&types.Feed{Provider: "Target", Address: target.URL})
+	r.NoError(err)
+	for _, feed := range []*types.Feed{sourceFeed, targetFeed} {
+		w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		r.Equal(http.StatusOK, w.Code)
+	}
+
+	w := performRequest(router, http.MethodPost, "/feeds/merge", jsonBody(map[string]string{
+		"sourceId": sourceFeed.ID,
+		"targetId": targetFeed.ID,
+	}))
+	r.Equal(http.StatusOK, w.Code)
+	var body struct {
+		Feed  types.Feed `json:"feed"`
+		Moved int        `json:"moved"`
+	}
+	r.NoError(json.NewDecoder(w.Body).Decode(&body))
+	a.Equal(targetFeed.ID, body.Feed.ID)
+	a.Equal(2, body.Moved)
+
+	articles, err := articleStore.List("", 0, targetFeed.ID)
+	r.NoError(err)
+	a.Len(articles, 3, "unexpected number of articles")
+	articles, err = articleStore.List("", 0, sourceFeed.ID)
+	r.NoError(err)
+	a.Empty(articles)
+	w = performRequest(router, http.MethodGet, "/feeds/"+sourceFeed.ID, nil)
+	a.Equal(http.StatusInternalServerError, w.Code)
+
+	for _, args := range []map[string]string{
+		{"sourceId": targetFeed.ID, "targetId": targetFeed.ID},
+		{"sourceId": targetFeed.ID},
+	} {
+		w = performRequest(router, http.MethodPost, "/feeds/merge", jsonBody(args))
+		a.Equal(http.StatusBadRequest, w.Code)
+	}
+	w = performRequest(router, http.MethodPost, "/feeds/merge", jsonBody(map[string]string{
+		"sourceId": sourceFeed.ID,
+		"targetId": targetFeed.ID,
+	}))
+	a.Equal(http.StatusInternalServerError, w.Code, "merging a deleted feed must fail")
+}
+
+func TestLoadFeedRetries(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	var requests int32
+	fixture := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, rssFixture("Flaky News", 1))
+	}))
+	defer fixture.Close()
+	feedStore := store.NewFeedStore()
+	articleStore := store.NewArticleStore()
+	reader := rssreader.NewFeed(rssreader.WithRetries(1, time.Millisecond))
+	consumer := feedconsumer.NewFeedConsumer(reader, articleStore)
+	router := NewService(consumer, reader, feedStore, articleStore).setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	r.NoError(err)
+
+	w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	var summary types.LoadSummary
+	r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+	a.Equal(2, summary.Attempts)
+	a.True(summary.Retried)
+	a.Equal(1, summary.Created)
+
+	w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+	a.Equal(1, summary.Attempts)
+	a.False(summary.Retried)
+}
+
+func TestDeleteArticle(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fixture := newFixtureServer(rssFixture("Deleting News", 2))
+	defer fixture.Close()
+	s, feedStore, articleStore := newTestService()
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	r.NoError(err)
+	w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	articles, err := articleStore.List("", 0, "")
+	r.NoError(err)
+	r.Len(articles, 2, "unexpected number of articles")
+	deleted := articles[0]
+
+	w = performRequest(router, http.MethodDelete, "/articles/"+deleted.ID, nil)
+	r.Equal(http.StatusOK, w.Code)
+	var tombstone types.Tombstone
+	r.NoError(json.NewDecoder(w.Body).Decode(&tombstone))
+	a.Equal(deleted.ID, tombstone.ID)
+	a.Equal(deleted.GUID, tombstone.GUID)
+	w = performRequest(router, http.MethodDelete, "/articles/"+deleted.ID, nil)
+	a.Equal(http.StatusInternalServerError, w.Code)
+
+	for _, path := range []string{"/feeds/load", "/feeds/load?force=true"} {
+		w = performRequest(router, http.MethodPost, path, jsonBody(map[string]string{"id": feed.ID}))
+		r.Equal(http.StatusOK, w.Code)
+		var summary types.LoadSummary
+		r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+		a.Equal(1, summary.Deleted)
+		w = performRequest(router, http.MethodGet, "/articles/"+deleted.ID, nil)
+		a.Equal(http.StatusInternalServerError, w.Code, "deleted article must not be loaded again")
+	}
+	w = performRequest(router, http.MethodPost, "/articles", jsonBody(map[string]string{"guid": deleted.GUID}))
+	a.Equal(http.StatusGone, w.Code)
+
+	w = performRequest(router, http.MethodGet, "/articles/tombstones", nil)
+	r.Equal(http.StatusOK, w.Code)
+	var tombstones []types.Tombstone
+	r.NoError(json.NewDecoder(w.Body).Decode(&tombstones))
+	r.Len(tombstones, 1, "unexpected number of tombstones")
+	a.Equal(deleted.ID, tombstones[0].ID)
+
+	w = performRequest(router, http.MethodDelete, "/articles/tombstones?id="+deleted.ID, nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.JSONEq(`{"cleared":1}`, w.Body.String())
+	w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	w = performRequest(router, http.MethodGet, "/articles/"+deleted.ID, nil)
+	a.Equal(http.StatusOK, w.Code)
+}
+
+func TestExportArticles(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	s, _, articleStore := newTestService()
+	router := s.setupServiceRouter()
+	for _, article := range []*types.Article{
+		{FeedID: "feed_1", GUID: "guid_1", Title: "First, with comma", Link: "https://example.com/1", PublishDate: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), Author: "Jane", Categories: []string{"news", "sports"}},
+		{FeedID: "feed_1", GUID: "guid_2", Title: "Second", PublishDate: time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)},
+		{FeedID: "feed_2", GUID: "guid_3", Title: "Other feed"},
+	} {
+		_, err := articleStore.Create(article)
+		r.NoError(err)
+	}
+	first, err := articleStore.List("", 1, "feed_1")
+	r.NoError(err)
+
+	w := performRequest(router, http.MethodGet, "/articles/export.csv?feed=feed_1", nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.Equal("text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	r.Len(lines, 3, "unexpected number of rows")
+	a.Equal("id,feedId,guid,title,link,publishDate,author,categories", lines[0])
+	a.Equal(first[0].ID+`,feed_1,guid_1,"First, with comma",https://example.com/1,2021-01-01T10:00:00Z,Jane,"news,sports"`, lines[1])
+	a.True(strings.HasSuffix(lines[2], ",feed_1,guid_2,Second,,2021-01-02T10:00:00Z,,"), lines[2])
+
+	t.Run("exports all pages", func(t *testing.T) {
+		s, _, articleStore := newTestService()
+		for i := 0; i < exportPageSize+1; i++ {
+			_, err := articleStore.Create(&types.Article{GUID: fmt.Sprintf("guid_%d", i)})
+			require.NoError(t, err)
+		}
+		w := performRequest(s.setupServiceRouter(), http.MethodGet, "/articles/export.csv", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, strings.Split(strings.TrimSpace(w.Body.String()), "\n"), exportPageSize+2)
+	})
+
+	w = performRequest(router, http.MethodGet, "/articles/export.csv?sortBy=unknown", nil)
+	a.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestFeedHealth(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	var requests int32
+	fixture := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every third load fails.
+		if atomic.AddInt32(&requests, 1)%3 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, rssFixture("Flaky News", 1))
+	}))
+	defer fixture.Close()
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	r.NoError(err)
+
+	for i := 0; i < 6; i++ {
+		performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	}
+	w := performRequest(router, http.MethodGet, "/feeds/"+feed.ID, nil)
+	r.Equal(http.StatusOK, w.Code)
+	var body types.Feed
+	r.NoError(json.NewDecoder(w.Body).Decode(&body))
+	a.InDelta(4.0/6.0, body.Health, 0.0001)
+	a.Equal(6, body.Loads)
+}
+
+func TestCategoryFeeds(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	for i, article := range []*types.Article{
+		{FeedID: "feed_2", Categories: []string{"world news"}},
+		{FeedID: "feed_1", Categories: []string{"world news", "sports"}},
+		{FeedID: "feed_3", Categories: []string{"sports"}},
+	} {
+		article.GUID = fmt.Sprintf("guid_%d", i)
+		_, err := articleStore.Create(article)
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	w := performRequest(router, http.MethodGet, "/categories/world%20news/feeds", nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.JSONEq(`["feed_1","feed_2"]`, w.Body.String())
+
+	w = performRequest(router, http.MethodGet, "/categories/unknown/feeds", nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.JSONEq(`[]`, w.Body.String())
+}
+
+func TestLoadFeedArguments(t *testing.T) {
+	fixture := newFixtureServer(rssFixture("Fixture News", 1))
+	defer fixture.Close()
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	require.NoError(t, err)
+
+	t.Run("loads by id", func(t *testing.T) {
+		w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("loads by address", func(t *testing.T) {
+		r := require.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"address": fixture.URL}))
+		r.Equal(http.StatusOK, w.Code)
+		var summary types.LoadSummary
+		r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+		assert.Equal(t, 1, summary.Unchanged)
+
+		w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"address": "http://unknown.com"}))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	for name, args := range map[string]map[string]string{
+		"neither":   {},
+		"both":      {"id": feed.ID, "address": fixture.URL},
+		"all empty": {"id": "", "address": ""},
+	} {
+		args := args
+		t.Run("errors for "+name, func(t *testing.T) {
+			r := require.New(t)
+			a := assert.New(t)
+			w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(args))
+			r.Equal(http.StatusBadRequest, w.Code)
+			var body struct {
+				Error  string            `json:"error"`
+				Fields map[string]string `json:"fields"`
+			}
+			r.NoError(json.NewDecoder(w.Body).Decode(&body))
+			a.Equal("invalid arguments", body.Error)
+			a.Contains(body.Fields, "id")
+			a.Contains(body.Fields, "address")
+		})
+	}
+}
+
+func TestAdminReindex(t *testing.T) {
+	articleStore := store.NewArticleStore()
+	_, err := articleStore.Create(&types.Article{GUID: "guid"})
+	require.NoError(t, err)
+
+	t.Run("requires the admin token", func(t *testing.T) {
+		router := NewService(nil, nil, nil, articleStore, WithAdminToken("secret")).setupServiceRouter()
+		for _, header := range []http.Header{nil, {"Authorization": {"Bearer wrong"}}, {"Authorization": {"secret"}}} {
+			w := performRequestWithHeader(router, http.MethodPost, "/admin/reindex", nil, header)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+		w := performRequestWithHeader(router, http.MethodPost, "/admin/reindex", nil, http.Header{"Authorization": {"Bearer secret"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"moved":0}`, w.Body.String())
+	})
+
+	t.Run("is disabled without a token", func(t *testing.T) {
+		router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+		w := performRequestWithHeader(router, http.MethodPost, "/admin/reindex", nil, http.Header{"Authorization": {"Bearer "}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestListArticlesHasFullText(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	for _, article := range []*types.Article{
+		{GUID: "full", FullText: "full text", Categories: []string{"news"}},
+		{GUID: "other_full", FullText: "full text", Categories: []string{"sports"}},
+		{GUID: "empty", Categories: []string{"news"}},
+	} {
+		_, err := articleStore.Create(article)
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for query, expected := range map[string][]string{
+		"hasFullText=true":          {"full", "other_full"},
+		"hasFullText=true&cat=news": {"full"},
+		"hasFullText=false":         {"full", "other_full", "empty"},
+	} {
+		w := performRequest(router, http.MethodGet, "/articles?"+query, nil)
+		r.Equal(http.StatusOK, w.Code, query)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		guids := []string{}
+		for _, article := range articles {
+			guids = append(guids, article.GUID)
+		}
+		assert.ElementsMatch(t, expected, guids, query)
+	}
+}
+
+func TestAdminRenameFeedCategory(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	feedStore := store.NewFeedStore()
+	first, err := feedStore.Create(&types.Feed{Address: "address_1", Category: "UK"})
+	r.NoError(err)
+	second, err := feedStore.Create(&types.Feed{Address: "address_2", Category: "UK"})
+	r.NoError(err)
+	router := NewService(nil, nil, feedStore, nil, WithAdminToken("secret")).setupServiceRouter()
+	header := http.Header{"Authorization": {"Bearer secret"}}
+
+	w := performRequestWithHeader(router, http.MethodPost, "/admin/feeds/rename-category", jsonBody(map[string]string{
+		"old": "UK",
+		"new": "United Kingdom",
+	}), header)
+	r.Equal(http.StatusOK, w.Code)
+	a.JSONEq(`{"renamed":2}`, w.Body.String())
+	for _, feed := range []*types.Feed{first, second} {
+		feed, err := feedStore.Get(feed.ID)
+		r.NoError(err)
+		a.Equal("United Kingdom", feed.Category)
+	}
+
+	for _, args := range []map[string]string{
+		{"old": "UK"},
+		{"old": "UK", "new": "UK"},
+	} {
+		w = performRequestWithHeader(router, http.MethodPost, "/admin/feeds/rename-category", jsonBody(args), header)
+		a.Equal(http.StatusBadRequest, w.Code)
+	}
+	w = performRequest(router, http.MethodPost, "/admin/feeds/rename-category", jsonBody(map[string]string{"old": "a", "new": "b"}))
+	a.Equal(http.StatusUnauthorized, w.Code)
+}
+
+func TestArticlesMaxDesc(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	article, err := articleStore.Create(&types.Article{
+		GUID:        "guid",
+		Description: "Café au lait",
+		Content:     "short",
+	})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for _, path := range []string{
+		"/articles?maxDesc=4",
+		"/articles/batch?maxDesc=4&ids=" + article.ID,
+	} {
+		w := performRequest(router, http.MethodGet, path, nil)
+		r.Equal(http.StatusOK, w.Code, path)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		r.Len(articles, 1, path)
+		a.Equal("Café", articles[0].Description, path)
+		a.Equal("shor", articles[0].Content, path)
+	}
+
+	w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"?maxDesc=20", nil)
+	r.Equal(http.StatusOK, w.Code)
+	var body types.Article
+	r.NoError(json.NewDecoder(w.Body).Decode(&body))
+	a.Equal("Café au lait", body.Description)
+	w = performRequest(router, http.MethodGet, "/articles/"+article.ID+"?maxDesc=1", nil)
+	r.Equal(http.StatusOK, w.Code)
+	r.NoError(json.NewDecoder(w.Body).Decode(&body))
+	a.Equal("C", body.Description)
+
+	stored, err := articleStore.Get(article.ID)
+	r.NoError(err)
+	a.Equal("Café au lait", stored.Description, "storage must not be truncated")
+	a.Equal("short", stored.Content, "storage must not be truncated")
+
+	w = performRequest(router, http.MethodGet, "/articles?maxDesc=-1", nil)
+	a.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestTrendingCategories(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	now := time.Now()
+	for i, article := range []*types.Article{
+		{Categories: []string{"old"}, PublishDate: now.Add(-72 * time.Hour)},
+		{Categories: []string{"old"}, PublishDate: now.Add(-71 * time.Hour)},
+		{Categories: []string{"news", "sports"}, PublishDate: now.Add(-2 * time.Hour)},
+		{Categories: []string{"sports"}, PublishDate: now.Add(-time.Hour)},
+	} {
+		article.GUID = fmt.Sprintf("guid_%d", i)
+		_, err := articleStore.Create(article)
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	w := performRequest(router, http.MethodGet, "/categories/trending", nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.JSONEq(`[{"Category":"sports","Count":2},{"Category":"news","Count":1}]`, w.Body.String())
+
+	w = performRequest(router, http.MethodGet, "/categories/trending?window=96h&limit=1", nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.JSONEq(`[{"Category":"old","Count":2}]`, w.Body.String())
+
+	for _, query := range []string{"window=invalid", "window=-1h", "limit=-1"} {
+		w = performRequest(router, http.MethodGet, "/categories/trending?"+query, nil)
+		a.Equal(http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	router := NewService(nil, nil, nil, store.NewArticleStore()).setupServiceRouter()
+
+	t.Run("echoes the provided ID", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(requestIDHeader, "trace-123")
+		w := performRequestWithHeader(router, http.MethodGet, "/articles", nil, header)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "trace-123", w.Header().Get(requestIDHeader))
+	})
+
+	t.Run("generates an ID when absent", func(t *testing.T) {
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles", nil)
+		a.Equal(http.StatusOK, w.Code)
+		ID := w.Header().Get(requestIDHeader)
+		a.NotEmpty(ID)
+		w = performRequest(router, http.MethodGet, "/articles", nil)
+		a.NotEqual(ID, w.Header().Get(requestIDHeader), "generated IDs must be unique")
+	})
+
+	t.Run("replaces IDs that are too long", func(t *testing.T) {
+		ID := strings.Repeat("a", maxRequestIDLength+1)
+		header := http.Header{}
+		header.Set(requestIDHeader, ID)
+		w := performRequestWithHeader(router, http.MethodGet, "/articles", nil, header)
+		assert.NotEqual(t, ID, w.Header().Get(requestIDHeader))
+	})
+}
+
+// feederFunc adapts a function into a Feeder.
+type feederFunc func(feed *types.Feed, force bool) (*types.LoadSummary, error)
+
+func (f feederFunc) Consume(feed *types.Feed, force bool) (*types.LoadSummary, error) {
+	return f(feed, force)
+}
+
+func (f feederFunc) Ingest(feed *types.Feed, channel *types.Channel) (*types.LoadSummary, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestLoadQueue(t *testing.T) {
+	t.Run("loads feeds asynchronously", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		fixture := newFixtureServer(rssFixture("Fixture News", 2))
+		defer fixture.Close()
+		feedStore := store.NewFeedStore()
+		articleStore := store.NewArticleStore()
+		reader := rssreader.NewFeed()
+		consumer := feedconsumer.NewFeedConsumer(reader, articleStore)
+		s := NewService(consumer, reader, feedStore, articleStore, WithLoadQueue(2, 1))
+		router := s.setupServiceRouter()
+		feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+		r.NoError(err)
+
+		w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		r.Equal(http.StatusAccepted, w.Code)
+		var job types.LoadJob
+		r.NoError(json.NewDecoder(w.Body).Decode(&job))
+		a.NotEmpty(job.ID)
+		a.Equal(feed.ID, job.FeedID)
+
+		r.Eventually(func() bool {
+			w := performRequest(router, http.MethodGet, "/feeds/load/"+job.ID, nil)
+			return w.Code == http.StatusOK && json.NewDecoder(w.Body).Decode(&job) == nil && job.Status == types.LoadJobDone
+		}, time.Second, 10*time.Millisecond)
+		r.NotNil(job.Summary)
+		a.Equal(2, job.Summary.Created)
+
+		w = performRequest(router, http.MethodGet, "/feeds/load/unknown", nil)
+		a.Equal(http.StatusNotFound, w.Code)
+	})
+
+	t.Run("rejects loads when the queue is full", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		release := make(chan struct{})
+		feeder := feederFunc(func(feed *types.Feed, force bool) (*types.LoadSummary, error) {
+			<-release
+			return nil, fmt.Errorf("load failed")
+		})
+		feedStore := store.NewFeedStore()
+		s := NewService(feeder, rssreader.NewFeed(), feedStore, store.NewArticleStore(), WithLoadQueue(1, 1))
+		router := s.setupServiceRouter()
+		feed, err := feedStore.Create(&types.Feed{Address: "address"})
+		r.NoError(err)
+
+		// The first load is taken by the worker, and the second one fills the queue.
+		w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		r.Equal(http.StatusAccepted, w.Code)
+		var running types.LoadJob
+		r.NoError(json.NewDecoder(w.Body).Decode(&running))
+		r.Eventually(func() bool {
+			job, _ := s.loadQueue.job(running.ID)
+			return job.Status == types.LoadJobRunning
+		}, time.Second, 10*time.Millisecond)
+		w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		r.Equal(http.StatusAccepted, w.Code)
+
+		w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		a.Equal(http.StatusTooManyRequests, w.Code)
+
+		close(release)
+		r.Eventually(func() bool {
+			job, _ := s.loadQueue.job(running.ID)
+			return job.Status == types.LoadJobFailed
+		}, time.Second, 10*time.Millisecond)
+		job, _ := s.loadQueue.job(running.ID)
+		a.Equal("load failed", job.Error)
+	})
+}
+
+func TestListFeedsByProvider(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	for i := 1; i <= 3; i++ {
+		_, err := feedStore.Create(&types.Feed{Provider: "BBC", Address: fmt.Sprintf("bbc_%d", i)})
+		r.NoError(err)
+		_, err = feedStore.Create(&types.Feed{Provider: "CNN", Address: fmt.Sprintf("cnn_%d", i)})
+		r.NoError(err)
+	}
+
+	var ids []string
+	cursor := ""
+	for {
+		w := performRequest(router, http.MethodGet, "/feeds?provider=BBC&pageSize=2&cursor="+cursor, nil)
+		r.Equal(http.StatusOK, w.Code)
+		var feeds []*types.Feed
+		r.NoError(json.NewDecoder(w.Body).Decode(&feeds))
+		if len(feeds) == 0 {
+			break
+		}
+		for _, feed := range feeds {
+			a.Equal("BBC", feed.Provider)
+			ids = append(ids, feed.ID)
+		}
+		cursor = feeds[len(feeds)-1].ID
+	}
+	r.Len(ids, 3, "unexpected number of feeds")
+	a.True(sort.StringsAreSorted(ids), "feeds must be listed in a stable order")
+
+	w := performRequest(router, http.MethodGet, "/feeds?pageSize=-1", nil)
+	a.Equal(http.StatusBadRequest, w.Code)
+}
+
+// contentFetcherFunc adapts a function into a ContentFetcher.
+type contentFetcherFunc func(ctx context.Context, url string) (string, error)
+
+func (f contentFetcherFunc) Fetch(ctx context.Context, url string) (string, error) {
+	return f(ctx, url)
+}
+
+func TestCanonicalLink(t *testing.T) {
+	for name, tc := range map[string]struct {
+		page     string
+		expected string
+	}{
+		"absolute":          {page: `<link rel="canonical" href="https://example.com/story">`, expected: "https://example.com/story"},
+		"relative":          {page: `<link href='/story' rel='canonical'/>`, expected: "https://news.example.com/story"},
+		"multiple rels":     {page: `<link rel="alternate canonical" href=https://example.com/story>`, expected: "https://example.com/story"},
+		"first one wins":    {page: `<link rel="canonical" href="/first"><link rel="canonical" href="/second">`, expected: "https://news.example.com/first"},
+		"other rels":        {page: `<link rel="amphtml" href="https://example.com/amp">`},
+		"not http":          {page: `<link rel="canonical" href="javascript:alert(1)">`},
+		"missing href":      {page: `<link rel="canonical">`},
+		"extracted text":    {page: "Plain text of the story"},
+		"unrelated anchors": {page: `<a rel="canonical" href="https://example.com/story">story</a>`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, canonicalLink(tc.page, "https://news.example.com/feed/story?id=1"))
+		})
+	}
+}
+
+func TestFetchFullText(t *testing.T) {
+	t.Run("stores the content of the fetcher", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		var fetched string
+		fetcher := contentFetcherFunc(func(ctx context.Context, url string) (string, error) {
+			fetched = url
+			return "extracted text", nil
+		})
+		feedStore := store.NewFeedStore()
+		articleStore := store.NewArticleStore()
+		s := NewService(nil, rssreader.NewFeed(), feedStore, articleStore, WithContentFetcher(fetcher))
+		router := s.setupServiceRouter()
+		article, err := articleStore.Create(&types.Article{GUID: "guid", Link: "http://example.com/story"})
+		r.NoError(err)
+
+		w := performRequest(router, http.MethodPost, "/articles/"+article.ID+"/fulltext", nil)
+		r.Equal(http.StatusOK, w.Code)
+		a.Equal("http://example.com/story", fetched)
+		stored, err := articleStore.Get(article.ID)
+		r.NoError(err)
+		a.Equal("extracted text", stored.FullText)
+	})
+
+	t.Run("stores the content of the fetcher unchanged", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		content := "<p>First  paragraph</p>\n\n<p>Second &amp; last</p>\n"
+		fetcher := contentFetcherFunc(func(ctx context.Context, url string) (string, error) {
+			return content, nil
+		})
+		articleStore := store.NewArticleStore()
+		s := NewService(nil, rssreader.NewFeed(), store.NewFeedStore(), articleStore, WithContentFetcher(fetcher))
+		router := s.setupServiceRouter()
+		article, err := articleStore.Create(&types.Article{GUID: "guid", Link: "http://example.com/story"})
+		r.NoError(err)
+
+		w := performRequest(router, http.MethodPost, "/articles/"+article.ID+"/fulltext", nil)
+		r.Equal(http.StatusOK, w.Code)
+		stored, err := articleStore.Get(article.ID)
+		r.NoError(err)
+		a.Equal(content, stored.FullText)
+		a.False(stored.Truncated)
+	})
+
+	t.Run("stores the canonical link declared by the page", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<html><head><link rel="stylesheet" href="/style.css">`+
+				`<LINK href="/news/story?id=1&amp;ref=share" REL="Canonical"></head><body>Story</body></html>`)
+		}))
+		defer page.Close()
+		articleStore := store.NewArticleStore()
+		s := NewService(nil, rssreader.NewFeed(), store.NewFeedStore(), articleStore)
+		router := s.setupServiceRouter()
+		article, err := articleStore.Create(&types.Article{GUID: "guid", Link: page.URL + "/story?utm_source=rss"})
+		r.NoError(err)
+
+		w := performRequest(router, http.MethodPost, "/articles/"+article.ID+"/fulltext", nil)
+		r.Equal(http.StatusOK, w.Code)
+		var got types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&got))
+		a.Equal(page.URL+"/news/story?id=1&ref=share", got.CanonicalLink)
+		stored, err := articleStore.Get(article.ID)
+		r.NoError(err)
+		a.Equal(page.URL+"/news/story?id=1&ref=share", stored.CanonicalLink)
+		a.Equal(page.URL+"/story?utm_source=rss", stored.Link)
+	})
+
+	t.Run("fails when the fetcher fails", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		fetcher := contentFetcherFunc(func(ctx context.Context, url string) (string, error) {
+			return "", fmt.Errorf("blocked")
+		})
+		articleStore := store.NewArticleStore()
+		s := NewService(nil, rssreader.NewFeed(), store.NewFeedStore(), articleStore, WithContentFetcher(fetcher))
+		router := s.setupServiceRouter()
+		article, err := articleStore.Create(&types.Article{GUID: "guid", Link: "http://example.com/story"})
+		r.NoError(err)
+
+		w := performRequest(router, http.MethodPost, "/articles/"+article.ID+"/fulltext", nil)
+		a.Equal(http.StatusBadGateway, w.Code)
+		a.Contains(w.Body.String(), "blocked")
+	})
+
+	t.Run("fetches the link over HTTP by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		page := newFixtureServer("<html><body>story</body></html>")
+		defer page.Close()
+		s, _, articleStore := newTestService()
+		router := s.setupServiceRouter()
+		article, err := articleStore.Create(&types.Article{GUID: "guid", Link: page.URL})
+		r.NoError(err)
+
+		w := performRequest(router, http.MethodPost, "/articles/"+article.ID+"/fulltext", nil)
+		r.Equal(http.StatusOK, w.Code)
+		var updated types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&updated))
+		a.Equal("story", updated.FullText)
+	})
+
+	t.Run("truncates the text to the maximum length", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		fetcher := contentFetcherFunc(func(ctx context.Context, url string) (string, error) {
+			return strings.Repeat("é", 10), nil
+		})
+		articleStore := store.NewArticleStore()
+		s := NewService(nil, rssreader.NewFeed(), store.NewFeedStore(), articleStore,
+			WithContentFetcher(fetcher), WithMaxFullTextLength(5))
+		router := s.setupServiceRouter()
+		article, err := articleStore.Create(&types.Article{GUID: "guid", Link: "http://example.com/story"})
+		r.NoError(err)
+
+		w := performRequest(router, http.MethodPost, "/articles/"+article.ID+"/fulltext", nil)
+		r.Equal(http.StatusOK, w.Code)
+		stored, err := articleStore.Get(article.ID)
+		r.NoError(err)
+		a.Equal("éé", stored.FullText)
+		a.True(stored.Truncated)
+	})
+}
+
+func TestExtractText(t *testing.T) {
+	for name, tc := range map[string]struct {
+		page     string
+		expected string
+	}{
+		"body": {
+			page:     `<html><head><title>Title</title></head><body><h1>Story</h1><p>First &amp; <b>bold</b></p><p>Second</p></body></html>`,
+			expected: "Story\nFirst & bold\nSecond",
+		},
+		"article": {
+			page:     `<body><nav>Menu</nav><article><p>Story</p></article><footer>Footer</footer></body>`,
+			expected: "Story",
+		},
+		"hidden elements": {
+			page:     `<body><script>var a = "<p>";</script><style>p {}</style><!-- comment --><p>Story</p></body>`,
+			expected: "Story",
+		},
+		"line breaks": {
+			page:     "<body>First<br/>Second<div>Third</div>\n\n  Fourth   line</body>",
+			expected: "First\nSecond\nThird\nFourth line",
+		},
+		"text":          {page: "Plain text\n\nof the story", expected: "Plain text\nof the story"},
+		"unclosed body": {page: `<html><body><p>Story`, expected: "Story"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, extractText(tc.page))
+		})
+	}
+}
+
+func TestPreviewImportFeeds(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	_, err := feedStore.Create(&types.Feed{Provider: "CNN", Address: "http://rss.cnn.com/rss/edition.rss"})
+	r.NoError(err)
+	opml := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <body>
+    <outline text="World">
+      <outline text="BBC" xmlUrl="HTTP://Feeds.BBCI.co.uk:80/news/world/rss.xml#top"/>
+      <outline text="BBC again" xmlUrl="http://feeds.bbci.co.uk/news/world/rss.xml"/>
+      <outline text="CNN" xmlUrl="http://rss.cnn.com/rss/edition.rss"/>
+    </outline>
+    <outline text="Broken" xmlUrl="ftp://example.com/feed"/>
+    <outline text="Empty"/>
+  </body>
+</opml>`
+
+	w := performRequest(router, http.MethodPost, "/feeds/import/preview", strings.NewReader(opml))
+	r.Equal(http.StatusOK, w.Code)
+	var preview ImportPreview
+	r.NoError(json.NewDecoder(w.Body).Decode(&preview))
+	r.Len(preview.Feeds, 1, "unexpected number of feeds")
+	a.Equal("BBC", preview.Feeds[0].Provider)
+	a.Equal("World", preview.Feeds[0].Category)
+	a.Equal("http://feeds.bbci.co.uk/news/world/rss.xml", preview.Feeds[0].Address)
+	r.Len(preview.Skipped, 4, "unexpected number of skipped outlines")
+	a.Equal("BBC again", preview.Skipped[0].Text)
+	a.Equal("duplicate address", preview.Skipped[0].Reason)
+	a.Equal("CNN", preview.Skipped[1].Text)
+	a.Equal("duplicate address", preview.Skipped[1].Reason)
+	a.Equal("Broken", preview.Skipped[2].Text)
+	a.Equal("invalid address", preview.Skipped[2].Reason)
+	a.Equal("Empty", preview.Skipped[3].Text)
+	a.Equal("missing xmlUrl", preview.Skipped[3].Reason)
+
+	// Nothing is created.
+	feeds, err := feedStore.List()
+	r.NoError(err)
+	a.Len(feeds, 1, "unexpected number of feeds")
+
+	w = performRequest(router, http.MethodPost, "/feeds/import/preview", strings.NewReader("not xml"))
+	a.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestLoadAllFeeds(t *testing.T) {
+	newService := func(failing ...string) (*Service, *store.FeedStore) {
+		feeder := feederFunc(func(feed *types.Feed, force bool) (*types.LoadSummary, error) {
+			for _, address := range failing {
+				if feed.Address == address {
+					return nil, fmt.Errorf("could not read %s", address)
+				}
+			}
+			return &types.LoadSummary{Created: 1}, nil
+		})
+		feedStore := store.NewFeedStore()
+		for _, address := range []string{"address_1", "address_2"} {
+			_, err := feedStore.Create(&types.Feed{Address: address})
+			require.NoError(t, err)
+		}
+		return NewService(feeder, rssreader.NewFeed(), feedStore, store.NewArticleStore()), feedStore
+	}
+
+	t.Run("reports success when all feeds load", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		s, _ := newService()
+		w := performRequest(s.setupServiceRouter(), http.MethodPost, "/feeds/load-all", nil)
+		r.Equal(http.StatusOK, w.Code)
+		var results []*types.FeedLoadResult
+		r.NoError(json.NewDecoder(w.Body).Decode(&results))
+		r.Len(results, 2, "unexpected number of results")
+		for _, result := range results {
+			a.Empty(result.Error)
+			a.Equal(1, result.Summary.Created)
+		}
+	})
+
+	t.Run("reports partial success when some feeds fail", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		s, feedStore := newService("address_2")
+		failing, err := feedStore.GetByAddress("address_2")
+		r.NoError(err)
+		w := performRequest(s.setupServiceRouter(), http.MethodPost, "/feeds/load-all", nil)
+		r.Equal(http.StatusMultiStatus, w.Code)
+		var results []*types.FeedLoadResult
+		r.NoError(json.NewDecoder(w.Body).Decode(&results))
+		r.Len(results, 2, "unexpected number of results")
+		for _, result := range results {
+			if result.FeedID == failing.ID {
+				a.Equal("could not read address_2", result.Error)
+				a.Nil(result.Summary)
+			} else {
+				a.Empty(result.Error)
+				a.Equal(1, result.Summary.Created)
+			}
+		}
+	})
+
+	t.Run("reports failure when all feeds fail", func(t *testing.T) {
+		r := require.New(t)
+		s, _ := newService("address_1", "address_2")
+		w := performRequest(s.setupServiceRouter(), http.MethodPost, "/feeds/load-all", nil)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestArticleNeighbors(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	s, _, articleStore := newTestService()
+	router := s.setupServiceRouter()
+	var ids []string
+	for i := 1; i <= 3; i++ {
+		article, err := articleStore.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			Categories:  []string{fmt.Sprintf("cat_%d", i%2)},
+			PublishDate: time.Unix(int64(i), 0).UTC(),
+		})
+		r.NoError(err)
+		ids = append(ids, article.ID)
+	}
+
+	w := performRequest(router, http.MethodGet, "/articles/"+ids[1]+"/neighbors", nil)
+	r.Equal(http.StatusOK, w.Code)
+	var neighbors struct {
+		Previous *types.Article `json:"previous"`
+		Next     *types.Article `json:"next"`
+	}
+	r.NoError(json.NewDecoder(w.Body).Decode(&neighbors))
+	a.Equal("guid_1", neighbors.Previous.GUID)
+	a.Equal("guid_3", neighbors.Next.GUID)
+
+	w = performRequest(router, http.MethodGet, "/articles/"+ids[0]+"/neighbors?cat=cat_1", nil)
+	r.Equal(http.StatusOK, w.Code)
+	neighbors.Previous, neighbors.Next = nil, nil
+	r.NoError(json.NewDecoder(w.Body).Decode(&neighbors))
+	a.Nil(neighbors.Previous)
+	a.Equal("guid_3", neighbors.Next.GUID)
+}
+
+func TestHTTPSOnly(t *testing.T) {
+	newService := func(httpsOnly bool) (*Service, *store.FeedStore) {
+		feedStore := store.NewFeedStore()
+		articleStore := store.NewArticleStore()
+		reader := rssreader.NewFeed()
+		consumer := feedconsumer.NewFeedConsumer(reader, articleStore)
+		return NewService(consumer, reader, feedStore, articleStore, WithHTTPSOnly(httpsOnly)), feedStore
+	}
+
+	t.Run("rejects http feeds when enabled", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		s, feedStore := newService(true)
+		router := s.setupServiceRouter()
+		w := performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{
+			"provider": "provider",
+			"address":  "http://example.com/rss.xml",
+		}))
+		r.Equal(http.StatusBadRequest, w.Code)
+		a.Contains(w.Body.String(), "only https feed addresses are allowed")
+
+		w = performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]interface{}{
+			"provider":  "provider",
+			"address":   "https://example.com/rss.xml",
+			"fallbacks": []string{"http://mirror.example.com/rss.xml"},
+		}))
+		r.Equal(http.StatusBadRequest, w.Code)
+
+		w = performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{
+			"provider": "provider",
+			"address":  "https://example.com/rss.xml",
+		}))
+		r.Equal(http.StatusOK, w.Code)
+
+		// Feeds stored before enabling the flag can't be loaded.
+		feed, err := feedStore.Create(&types.Feed{Address: "http://example.com/old.xml"})
+		r.NoError(err)
+		w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		a.Equal(http.StatusForbidden, w.Code)
+	})
+
+	t.Run("accepts http feeds when disabled", func(t *testing.T) {
+		r := require.New(t)
+		fixture := newFixtureServer(rssFixture("Fixture News", 1))
+		defer fixture.Close()
+		s, _ := newService(false)
+		router := s.setupServiceRouter()
+		w := performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{
+			"provider": "provider",
+			"address":  fixture.URL,
+		}))
+		r.Equal(http.StatusOK, w.Code)
+		var feed types.Feed
+		r.NoError(json.NewDecoder(w.Body).Decode(&feed))
+
+		w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+		r.Equal(http.StatusOK, w.Code)
+	})
+}
+
+func TestListArticlesBatch(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fixture := newMutableFixtureServer(rssFixture("Fixture News", 2))
+	defer fixture.Close()
+	feedStore := store.NewFeedStore()
+	articleStore := store.NewArticleStore()
+	reader := rssreader.NewFeed()
+	consumer := feedconsumer.NewFeedConsumer(reader, articleStore, feedconsumer.WithBatchIDs(true))
+	s := NewService(consumer, reader, feedStore, articleStore)
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Address: fixture.URL})
+	r.NoError(err)
+
+	w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	var first types.LoadSummary
+	r.NoError(json.NewDecoder(w.Body).Decode(&first))
+	fixture.setBody(rssFixture("Fixture News", 3))
+	w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": feed.ID}))
+	r.Equal(http.StatusOK, w.Code)
+	var second types.LoadSummary
+	r.NoError(json.NewDecoder(w.Body).Decode(&second))
+	r.NotEqual(first.BatchID, second.BatchID)
+
+	w = performRequest(router, http.MethodGet, "/articles?batch="+second.BatchID, nil)
+	r.Equal(http.StatusOK, w.Code)
+	var articles []*types.Article
+	r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+	r.Len(articles, 1, "unexpected number of articles")
+	a.Equal("guid_2", articles[0].GUID)
+	a.Equal(second.BatchID, articles[0].BatchID)
+}
+
+func TestListFeedsETag(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	_, err := feedStore.Create(&types.Feed{Address: "address_1"})
+	r.NoError(err)
+
+	w := performRequest(router, http.MethodGet, "/feeds", nil)
+	r.Equal(http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	r.NotEmpty(etag)
+
+	header := http.Header{}
+	header.Set("If-None-Match", etag)
+	w = performRequestWithHeader(router, http.MethodGet, "/feeds", nil, header)
+	r.Equal(http.StatusNotModified, w.Code)
+	a.Empty(w.Body.String())
+
+	_, err = feedStore.Create(&types.Feed{Address: "address_2"})
+	r.NoError(err)
+	w = performRequestWithHeader(router, http.MethodGet, "/feeds", nil, header)
+	r.Equal(http.StatusOK, w.Code)
+	a.NotEqual(etag, w.Header().Get("ETag"))
+	var feeds []*types.Feed
+	r.NoError(json.NewDecoder(w.Body).Decode(&feeds))
+	a.Len(feeds, 2, "unexpected number of feeds")
+
+	// Updates to a feed invalidate the tag too.
+	etag = w.Header().Get("ETag")
+	_, err = feedStore.UpdateTimeout(feeds[0].ID, time.Second)
+	r.NoError(err)
+	header.Set("If-None-Match", etag)
+	w = performRequestWithHeader(router, http.MethodGet, "/feeds", nil, header)
+	a.Equal(http.StatusOK, w.Code)
+}
+
+func TestListArticlesIDsOnly(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	for i := 0; i < 5; i++ {
+		_, err := articleStore.Create(&types.Article{
+			GUID:       fmt.Sprintf("guid_%d", i),
+			Categories: []string{fmt.Sprintf("category_%d", i%2)},
+		})
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for _, query := range []string{"pageSize=3", "cat=category_0", "sortBy=title&sortOrder=desc&pageSize=2"} {
+		t.Run(query, func(t *testing.T) {
+			r := require.New(t)
+			w := performRequest(router, http.MethodGet, "/articles?"+query, nil)
+			r.Equal(http.StatusOK, w.Code)
+			var articles []*types.Article
+			r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+			r.NotEmpty(articles)
+			var expected []string
+			for _, article := range articles {
+				expected = append(expected, article.ID)
+			}
+
+			w = performRequest(router, http.MethodGet, "/articles?idsOnly=true&"+query, nil)
+			r.Equal(http.StatusOK, w.Code)
+			var ids []string
+			r.NoError(json.NewDecoder(w.Body).Decode(&ids))
+			r.Equal(expected, ids)
+		})
+	}
+}
+
+func TestCanonicalAddress(t *testing.T) {
+	for address, expected := range map[string]string{
+		"HTTP://Example.COM/rss":        "http://example.com/rss",
+		"https://example.com:443":       "https://example.com/",
+		"http://example.com:8080/rss#a": "http://example.com:8080/rss",
+		"http://[::1]:8080/rss":         "http://[::1]:8080/rss",
+		"http://[::1]:80/rss":           "http://[::1]/rss",
+		"https://[2001:DB8::1]/rss":     "https://[2001:db8::1]/rss",
+	} {
+		canonical, ok := canonicalAddress(address)
+		assert.True(t, ok, address)
+		assert.Equal(t, expected, canonical, address)
+	}
+}
+
+func TestComputeFeedID(t *testing.T) {
+	s, _, _ := newTestService()
+	router := s.setupServiceRouter()
+
+	t.Run("computed ID matches the created feed", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/feeds/compute-id?address="+url.QueryEscape("HTTPS://Example.com:443/rss#top"), nil)
+		r.Equal(http.StatusOK, w.Code)
+		var computed struct {
+			Address string
+			ID      string
+		}
+		r.NoError(json.NewDecoder(w.Body).Decode(&computed))
+		a.Equal("https://example.com/rss", computed.Address)
+
+		w = performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{"provider": "Example", "address": computed.Address}))
+		r.Equal(http.StatusOK, w.Code)
+		var feed types.Feed
+		r.NoError(json.NewDecoder(w.Body).Decode(&feed))
+		a.Equal(computed.ID, feed.ID)
+	})
+
+	t.Run("computed ID matches the feed created with the raw address", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		raw := "HTTP://News.Example.COM:80/rss#frag"
+		w := performRequest(router, http.MethodGet, "/feeds/compute-id?category=World&address="+url.QueryEscape(raw), nil)
+		r.Equal(http.StatusOK, w.Code)
+		var computed struct {
+			Address string
+			ID      string
+		}
+		r.NoError(json.NewDecoder(w.Body).Decode(&computed))
+
+		w = performRequest(router, http.MethodPut, "/feeds", jsonBody(map[string]string{"provider": "Example", "category": "World", "address": raw}))
+		r.Equal(http.StatusOK, w.Code)
+		var feed types.Feed
+		r.NoError(json.NewDecoder(w.Body).Decode(&feed))
+		a.Equal(computed.ID, feed.ID)
+		a.Equal(computed.Address, feed.Address)
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		a := assert.New(t)
+		for _, query := range []string{"", "?address=ftp://example.com/rss", "?address=not-a-url"} {
+			w := performRequest(router, http.MethodGet, "/feeds/compute-id"+query, nil)
+			a.Equal(http.StatusBadRequest, w.Code, query)
+		}
+	})
+}
+
+func TestCloneFeed(t *testing.T) {
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	r := require.New(t)
+	source, err := feedStore.Create(&types.Feed{
+		Provider:  "BBC",
+		Category:  "UK",
+		Address:   "https://example.com/rss",
+		Fallbacks: []string{"https://mirror.example.com/rss"},
+		Timeout:   5,
+	})
+	r.NoError(err)
+
+	t.Run("clone keeps the settings under the new category", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/"+source.ID+"/clone", jsonBody(map[string]string{"category": "World"}))
+		r.Equal(http.StatusOK, w.Code)
+		var clone types.Feed
+		r.NoError(json.NewDecoder(w.Body).Decode(&clone))
+		a.NotEqual(source.ID, clone.ID)
+		a.Equal(feedStore.ComputeID(source.Address, "World"), clone.ID)
+		a.Equal("World", clone.Category)
+		a.Equal(source.Provider, clone.Provider)
+		a.Equal(source.Address, clone.Address)
+		a.Equal(source.Fallbacks, clone.Fallbacks)
+		a.Equal(source.Timeout, clone.Timeout)
+
+		feeds, err := feedStore.List()
+		r.NoError(err)
+		a.Len(feeds, 2)
+	})
+
+	t.Run("same category", func(t *testing.T) {
+		w := performRequest(router, http.MethodPost, "/feeds/"+source.ID+"/clone", jsonBody(map[string]string{"category": "UK"}))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("missing category", func(t *testing.T) {
+		w := performRequest(router, http.MethodPost, "/feeds/"+source.ID+"/clone", jsonBody(map[string]string{}))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown feed", func(t *testing.T) {
+		w := performRequest(router, http.MethodPost, "/feeds/unknown/clone", jsonBody(map[string]string{"category": "World"}))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("articles loaded by the clone are listed by the clone", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		fixture := newFixtureServer(rssFixture("Fixture News", 2))
+		defer fixture.Close()
+		s, feedStore, articleStore := newTestService()
+		router := s.setupServiceRouter()
+		source, err := feedStore.Create(&types.Feed{Provider: "BBC", Category: "UK", Address: fixture.URL})
+		r.NoError(err)
+		w := performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": source.ID}))
+		r.Equal(http.StatusOK, w.Code)
+
+		w = performRequest(router, http.MethodPost, "/feeds/"+source.ID+"/clone", jsonBody(map[string]string{"category": "World"}))
+		r.Equal(http.StatusOK, w.Code)
+		var clone types.Feed
+		r.NoError(json.NewDecoder(w.Body).Decode(&clone))
+		w = performRequest(router, http.MethodPost, "/feeds/load", jsonBody(map[string]string{"id": clone.ID}))
+		r.Equal(http.StatusOK, w.Code)
+		var summary types.LoadSummary
+		r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+		a.Equal(2, summary.Created)
+		a.Equal(0, summary.Unchanged)
+		a.Equal(source.ID, clone.ClonedFrom)
+
+		for _, feed := range []*types.Feed{source, &clone} {
+			w = performRequest(router, http.MethodGet, "/articles?feed="+feed.ID, nil)
+			r.Equal(http.StatusOK, w.Code)
+			var articles []*types.Article
+			r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+			r.Len(articles, 2, "unexpected number of articles")
+			for _, article := range articles {
+				a.Equal(feed.ID, article.FeedID)
+			}
+		}
+		articles, err := articleStore.List("", 0, "")
+		r.NoError(err)
+		a.Len(articles, 4, "articles must be stored for each feed")
+	})
+}
+
+func TestListArticlesHasImage(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	for _, article := range []*types.Article{
+		{GUID: "image", ImageURL: "https://example.com/image.jpg", Categories: []string{"news"}},
+		{GUID: "other_image", ImageURL: "https://example.com/other.jpg", Categories: []string{"sports"}},
+		{GUID: "no_image", Categories: []string{"news"}},
+	} {
+		_, err := articleStore.Create(article)
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for query, expected := range map[string][]string{
+		"hasImage=true":          {"image", "other_image"},
+		"hasImage=true&cat=news": {"image"},
+		"hasImage=false":         {"image", "other_image", "no_image"},
+	} {
+		w := performRequest(router, http.MethodGet, "/articles?"+query, nil)
+		r.Equal(http.StatusOK, w.Code, query)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		guids := []string{}
+		for _, article := range articles {
+			guids = append(guids, article.GUID)
+		}
+		assert.ElementsMatch(t, expected, guids, query)
+	}
+}
+
+func TestCreateFeedsBulk(t *testing.T) {
+	t.Run("reports the outcome of each entry", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		s, feedStore, _ := newTestService()
+		router := s.setupServiceRouter()
+		existing, err := feedStore.Create(&types.Feed{Provider: "BBC", Address: "https://example.com/existing"})
+		r.NoError(err)
+
+		w := performRequest(router, http.MethodPost, "/feeds/bulk", jsonBody([]map[string]string{
+			{"provider": "BBC", "category": "UK", "address": "HTTPS://Example.com/uk"},
+			{"provider": "BBC", "category": "UK", "address": "https://example.com/uk#top"},
+			{"provider": "BBC", "address": "not-a-url"},
+			{"provider": "BBC", "address": "https://example.com/existing"},
+			{"address": "https://example.com/world"},
+		}))
+		r.Equal(http.StatusOK, w.Code)
+		var results []*BulkFeedResult
+		r.NoError(json.NewDecoder(w.Body).Decode(&results))
+		r.Len(results, 5, "unexpected number of results")
+
+		r.NotNil(results[0].Feed)
+		a.True(results[0].Created)
+		a.Empty(results[0].Error)
+		a.Equal("https://example.com/uk", results[0].Address)
+		a.Equal("UK", results[0].Feed.Category)
+
+		r.NotNil(results[1].Feed)
+		a.False(results[1].Created, "duplicate entries must not be created again")
+		a.Equal(results[0].Feed.ID, results[1].Feed.ID)
+
+		a.Nil(results[2].Feed)
+		a.Equal("invalid address", results[2].Error)
+
+		r.NotNil(results[3].Feed)
+		a.False(results[3].Created)
+		a.Equal(existing.ID, results[3].Feed.ID)
+
+		a.Nil(results[4].Feed)
+		a.Equal("missing provider", results[4].Error)
+
+		feeds, err := feedStore.List()
+		r.NoError(err)
+		a.Len(feeds, 2, "unexpected number of feeds")
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		a := assert.New(t)
+		s, _, _ := newTestService()
+		w := performRequest(s.setupServiceRouter(), http.MethodPost, "/feeds/bulk", strings.NewReader(`{"address": "https://example.com"}`))
+		a.Equal(http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestArticleSelfURL(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts     []Option
+		expected string
+	}{
+		"relative without base URL": {expected: "/articles/"},
+		"absolute with base URL":    {opts: []Option{WithBaseURL("https://news.example.com/")}, expected: "https://news.example.com/articles/"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			a := assert.New(t)
+			articleStore := store.NewArticleStore()
+			article, err := articleStore.Create(&types.Article{GUID: "guid"})
+			r.NoError(err)
+			router := NewService(nil, nil, nil, articleStore, tc.opts...).setupServiceRouter()
+
+			w := performRequest(router, http.MethodGet, "/articles", nil)
+			r.Equal(http.StatusOK, w.Code)
+			var articles []*types.Article
+			r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+			r.Len(articles, 1, "unexpected number of articles")
+			a.Equal(tc.expected+article.ID, articles[0].SelfURL)
+
+			w = performRequest(router, http.MethodGet, "/articles/"+article.ID, nil)
+			r.Equal(http.StatusOK, w.Code)
+			var got types.Article
+			r.NoError(json.NewDecoder(w.Body).Decode(&got))
+			a.Equal(tc.expected+article.ID, got.SelfURL)
+
+			stored, err := articleStore.Get(article.ID)
+			r.NoError(err)
+			a.Empty(stored.SelfURL, "self links must not be stored")
+		})
+	}
+}
+
+func TestRefreshSchedule(t *testing.T) {
+	start := time.Unix(1000, 0).UTC()
+	var feeds []*types.Feed
+	for i := 0; i < 50; i++ {
+		feeds = append(feeds, &types.Feed{ID: fmt.Sprintf("feed_%d", i), Address: "https://example.com/rss"})
+	}
+
+	t.Run("loads spread out within the jitter", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		s := NewService(nil, nil, nil, nil, WithRefreshInterval(time.Hour, 10*time.Minute))
+		s.rand = rand.New(rand.NewSource(1))
+
+		schedule := s.refreshSchedule(feeds, start)
+		r.Len(schedule, len(feeds), "unexpected number of refreshes")
+		distinct := map[time.Time]bool{}
+		earliest, latest := schedule[0].At, schedule[0].At
+		for i, refresh := range schedule {
+			a.Equal(feeds[i], refresh.Feed)
+			a.False(refresh.At.Before(start), "refreshes must not start before the tick")
+			a.True(refresh.At.Before(start.Add(10*time.Minute)), "refreshes must happen within the jitter")
+			distinct[refresh.At] = true
+			if refresh.At.Before(earliest) {
+				earliest = refresh.At
+			}
+			if refresh.At.After(latest) {
+				latest = refresh.At
+			}
+		}
+		a.Len(distinct, len(feeds), "refreshes must not be simultaneous")
+		a.True(latest.Sub(earliest) > 5*time.Minute, "refreshes must spread across the jitter")
+	})
+
+	t.Run("jitter is capped to the interval", func(t *testing.T) {
+		a := assert.New(t)
+		s := NewService(nil, nil, nil, nil, WithRefreshInterval(time.Minute, time.Hour))
+		s.rand = rand.New(rand.NewSource(1))
+		for _, refresh := range s.refreshSchedule(feeds, start) {
+			a.True(refresh.At.Before(start.Add(time.Minute)), "refreshes must happen within the interval")
+		}
+	})
+
+	t.Run("no jitter loads all feeds on the tick", func(t *testing.T) {
+		a := assert.New(t)
+		s := NewService(nil, nil, nil, nil, WithRefreshInterval(time.Hour, 0))
+		for _, refresh := range s.refreshSchedule(feeds, start) {
+			a.Equal(start, refresh.At)
+		}
+	})
+}
+
+func TestAdminResetArticles(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	feedStore := store.NewFeedStore()
+	feed, err := feedStore.Create(&types.Feed{Address: "address_1", Category: "UK"})
+	r.NoError(err)
+	articleStore := store.NewArticleStore()
+	for _, guid := range []string{"guid_1", "guid_2"} {
+		_, err := articleStore.Create(&types.Article{FeedID: feed.ID, GUID: guid})
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, feedStore, articleStore, WithAdminToken("secret")).setupServiceRouter()
+
+	w := performRequest(router, http.MethodPost, "/admin/articles/reset", nil)
+	a.Equal(http.StatusUnauthorized, w.Code)
+
+	w = performRequestWithHeader(router, http.MethodPost, "/admin/articles/reset", nil, http.Header{"Authorization": {"Bearer secret"}})
+	r.Equal(http.StatusOK, w.Code)
+	a.JSONEq(`{"cleared":2}`, w.Body.String())
+	articles, err := articleStore.List("", 0, "")
+	r.NoError(err)
+	a.Empty(articles)
+	feeds, err := feedStore.List()
+	r.NoError(err)
+	a.Equal([]*types.Feed{feed}, feeds, "feeds must be kept")
+}
+
+func TestSearchFeeds(t *testing.T) {
+	s, feedStore, _ := newTestService()
+	router := s.setupServiceRouter()
+	bbc, err := feedStore.Create(&types.Feed{Provider: "BBC News", Category: "UK", Address: "https://feeds.bbci.co.uk/news/uk/rss.xml"})
+	require.NoError(t, err)
+	cnn, err := feedStore.Create(&types.Feed{Provider: "CNN", Category: "World", Address: "https://rss.cnn.com/rss/edition.rss"})
+	require.NoError(t, err)
+
+	for query, expected := range map[string][]string{
+		"Bbc":     {bbc.ID},
+		"uk":      {bbc.ID},
+		"cnn.com": {cnn.ID},
+		"RSS":     {bbc.ID, cnn.ID},
+		"missing": {},
+	} {
+		t.Run(query, func(t *testing.T) {
+			r := require.New(t)
+			w := performRequest(router, http.MethodGet, "/feeds/search?q="+url.QueryEscape(query), nil)
+			r.Equal(http.StatusOK, w.Code)
+			var feeds []*types.Feed
+			r.NoError(json.NewDecoder(w.Body).Decode(&feeds))
+			IDs := []string{}
+			for _, feed := range feeds {
+				IDs = append(IDs, feed.ID)
+			}
+			assert.ElementsMatch(t, expected, IDs)
+		})
+	}
+
+	t.Run("requires a query", func(t *testing.T) {
+		for _, query := range []string{"", "?q=", "?q=%20"} {
+			w := performRequest(router, http.MethodGet, "/feeds/search"+query, nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+}
+
+func TestListArticlesNoEnclosures(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	for _, article := range []*types.Article{
+		{GUID: "image", Enclosures: []*types.Enclosure{{URL: "https://example.com/1.jpg", Type: "image/jpeg"}}, Categories: []string{"news"}},
+		{GUID: "empty", Enclosures: []*types.Enclosure{}, Categories: []string{"news"}},
+		{GUID: "none", Categories: []string{"sports"}},
+	} {
+		_, err := articleStore.Create(article)
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for query, expected := range map[string][]string{
+		"noEnclosures=true":                      {"empty", "none"},
+		"noEnclosures=true&cat=news":             {"empty"},
+		"noEnclosures=true&enclosureType=image/": {},
+		"noEnclosures=false":                     {"image", "empty", "none"},
+	} {
+		w := performRequest(router, http.MethodGet, "/articles?"+query, nil)
+		r.Equal(http.StatusOK, w.Code, query)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		guids := []string{}
+		for _, article := range articles {
+			guids = append(guids, article.GUID)
+		}
+		assert.ElementsMatch(t, expected, guids, query)
+	}
+}
+
+func TestListArticlesHideFuture(t *testing.T) {
+	r := require.New(t)
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	articleStore := store.NewArticleStore(store.WithClock(clock.NewFake(now)))
+	for _, article := range []*types.Article{
+		{GUID: "past", PublishDate: now.Add(-time.Hour), Categories: []string{"news"}},
+		{GUID: "now", PublishDate: now, Categories: []string{"news"}},
+		{GUID: "future", PublishDate: now.Add(time.Hour), Categories: []string{"news"}},
+	} {
+		_, err := articleStore.Create(article)
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	for query, expected := range map[string][]string{
+		"hideFuture=true":          {"past", "now"},
+		"hideFuture=true&cat=news": {"past", "now"},
+		"hideFuture=false":         {"past", "now", "future"},
+		"":                         {"past", "now", "future"},
+	} {
+		w := performRequest(router, http.MethodGet, "/articles?"+query, nil)
+		r.Equal(http.StatusOK, w.Code, query)
+		var articles []*types.Article
+		r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+		guids := []string{}
+		for _, article := range articles {
+			guids = append(guids, article.GUID)
+		}
+		assert.ElementsMatch(t, expected, guids, query)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	r := require.New(t)
+	articleStore := store.NewArticleStore()
+	article, err := articleStore.Create(&types.Article{GUID: "guid"})
+	r.NoError(err)
+	router := NewService(nil, nil, nil, articleStore, WithCacheControl(map[string]string{
+		"/articles/:id": "public, max-age=86400",
+		"/articles":     "no-cache",
+	})).setupServiceRouter()
+
+	for path, expected := range map[string]string{
+		"/articles/" + article.ID:               "public, max-age=86400",
+		"/articles/" + article.ID + "?pretty=1": "public, max-age=86400",
+		"/articles":                             "no-cache",
+		"/articles/invalid_id":                  "",
+		"/articles/unread-counts":               "",
+	} {
+		t.Run(path, func(t *testing.T) {
+			w := performRequest(router, http.MethodGet, path, nil)
+			assert.Equal(t, expected, w.Header().Get("Cache-Control"))
+		})
+	}
+
+	t.Run("only GET requests", func(t *testing.T) {
+		w := performRequest(router, http.MethodDelete, "/articles/"+article.ID, nil)
+		r.Equal(http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Cache-Control"))
+	})
+}
+
+func TestListArticlesCollapseDuplicates(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	articleStore := store.NewArticleStore()
+	for i, title := range []string{"Breaking news", "Other", "breaking  NEWS ", "", "Breaking news", ""} {
+		_, err := articleStore.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			Title:       title,
+			PublishDate: time.Unix(int64(100+i), 0).UTC(),
+		})
+		r.NoError(err)
+	}
+	router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+
+	w := performRequest(router, http.MethodGet, "/articles?collapseDuplicates=true", nil)
+	r.Equal(http.StatusOK, w.Code)
+	var articles []struct {
+		GUID           string
+		DuplicateCount int `json:"duplicateCount"`
+	}
+	r.NoError(json.NewDecoder(w.Body).Decode(&articles))
+	r.Len(articles, 4, "unexpected number of articles")
+	a.Equal("guid_4", articles[0].GUID, "the newest article of the group must be returned")
+	a.Equal(2, articles[0].DuplicateCount)
+	for i, guid := range []string{"guid_1", "guid_3", "guid_5"} {
+		a.Equal(guid, articles[i+1].GUID)
+		a.Zero(articles[i+1].DuplicateCount)
+	}
+
+	// The next page starts after the last article of the page, which is collapsed into the first one.
+	page, err := articleStore.List("", 5, "")
+	r.NoError(err)
+	w = performRequest(router, http.MethodGet, "/articles?collapseDuplicates=true&pageSize=5", nil)
+	r.Equal(http.StatusOK, w.Code)
+	a.Equal(page[4].ID, w.Header().Get(nextCursorHeader))
+
+	w = performRequestWithHeader(router, http.MethodGet, "/articles?collapseDuplicates=true", nil, http.Header{"Accept": {mimeRSS}})
+	r.Equal(http.StatusOK, w.Code)
+	a.Contains(w.Header().Get("Content-Type"), mimeRSS)
+	a.Equal(4, strings.Count(w.Body.String(), "<item>"), "collapsed articles must be rendered in the negotiated format")
+
+	w = performRequest(router, http.MethodGet, "/articles", nil)
+	r.Equal(http.StatusOK, w.Code)
+	var all []*types.Article
+	r.NoError(json.NewDecoder(w.Body).Decode(&all))
+	a.Len(all, 6, "articles must not be collapsed by default")
+}
+
+func TestCollapseDuplicatesCanonicalLink(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	collapsed := collapseDuplicates([]*types.Article{
+		{GUID: "original", Title: "Story", CanonicalLink: "https://example.com/story", PublishDate: time.Unix(100, 0)},
+		{GUID: "retitled", Title: "Story, updated", CanonicalLink: "https://example.com/story", PublishDate: time.Unix(200, 0)},
+		{GUID: "other", Title: "Other", CanonicalLink: "https://example.com/other", PublishDate: time.Unix(300, 0)},
+		{GUID: "same_title", Title: "story", PublishDate: time.Unix(50, 0)},
+	})
+	r.Len(collapsed, 2, "unexpected number of articles")
+	a.Equal("retitled", collapsed[0].GUID)
+	a.Equal(2, collapsed[0].DuplicateCount)
+	a.Equal("other", collapsed[1].GUID)
+	a.Zero(collapsed[1].DuplicateCount)
+}
+
+// channelReader is a FeedReader returning the same channel for any address, used to test channels
+// that can't be served by fixtures, such as ones declaring hubs on other hosts.
+type channelReader struct {
+	channel *types.Channel
+}
+
+func (cr channelReader) Read(address string, opts types.ReadOptions) (*types.Channel, error) {
+	return cr.channel, nil
+}
+
+func (cr channelReader) ReadRaw(address string, opts types.ReadOptions) (*types.RawFeed, error) {
+	return nil, errors.New("not supported")
+}
+
+func (cr channelReader) Parse(address string, body []byte) (*types.Channel, error) {
+	return cr.channel, nil
+}
+
+func TestWebSub(t *testing.T) {
+	var mu sync.Mutex
+	var subscription url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		subscription = r.PostForm
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+	hubLinks := `<atom:link xmlns:atom="http://www.w3.org/2005/Atom" rel="hub" href="` + hub.URL + `"/>` +
+		`<atom:link xmlns:atom="http://www.w3.org/2005/Atom" rel="self" href="https://example.com/feed.xml"/>`
+	fixture := newFixtureServer(strings.Replace(rssFixture("Fixture News", 1), "<channel>", "<channel>"+hubLinks, 1))
+	defer fixture.Close()
+
+	feedStore := store.NewFeedStore()
+	articleStore := store.NewArticleStore()
+	reader := rssreader.NewFeed()
+	consumer := feedconsumer.NewFeedConsumer(reader, articleStore)
+	s := NewService(consumer, reader, feedStore, articleStore, WithBaseURL("https://news.example.com/"))
+	router := s.setupServiceRouter()
+	feed, err := feedStore.Create(&types.Feed{Provider: "Fixture", Category: "news", Address: fixture.URL})
+	require.NoError(t, err)
+
+	push := func(body string, signature string) *httptest.ResponseRecorder {
+		header := http.Header{"Content-Type": {"application/rss+xml"}}
+		if signature != "" {
+			header.Set("X-Hub-Signature", signature)
+		}
+		return performRequestWithHeader(router, http.MethodPost, "/websub/callback?feed="+feed.ID, strings.NewReader(body), header)
+	}
+	sign := func(secret string, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	t.Run("subscribes to the hub declared by the feed", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodPost, "/feeds/"+feed.ID+"/websub", nil)
+		r.Equal(http.StatusAccepted, w.Code, w.Body.String())
+		mu.Lock()
+		defer mu.Unlock()
+		a.Equal("subscribe", subscription.Get("hub.mode"))
+		a.Equal("https://example.com/feed.xml", subscription.Get("hub.topic"))
+		a.Equal("https://news.example.com/websub/callback?feed="+feed.ID, subscription.Get("hub.callback"))
+		a.NotEmpty(subscription.Get("hub.secret"))
+	})
+
+	t.Run("content is rejected until the hub verifies the subscription", func(t *testing.T) {
+		w := push(rssFixture("Fixture News", 1), "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("verification handshake", func(t *testing.T) {
+		a := assert.New(t)
+		query := url.Values{
+			"feed":              {feed.ID},
+			"hub.mode":          {"subscribe"},
+			"hub.topic":         {"https://example.com/other.xml"},
+			"hub.challenge":     {"challenge_value"},
+			"hub.lease_seconds": {"3600"},
+		}
+		w := performRequest(router, http.MethodGet, "/websub/callback?"+query.Encode(), nil)
+		a.Equal(http.StatusNotFound, w.Code)
+
+		query.Set("hub.topic", "https://example.com/feed.xml")
+		w = performRequest(router, http.MethodGet, "/websub/callback?"+query.Encode(), nil)
+		a.Equal(http.StatusOK, w.Code)
+		a.Equal("challenge_value", w.Body.String())
+	})
+
+	t.Run("subscriptions are renewed before the lease expires", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(54*time.Minute, websubRenewal(time.Hour))
+		s.websub.mu.Lock()
+		a.NotNil(s.websub.subs[feed.ID].renewal, "verified leases must schedule a renewal")
+		s.websub.mu.Unlock()
+
+		mu.Lock()
+		secret := subscription.Get("hub.secret")
+		subscription = nil
+		mu.Unlock()
+		s.renewSubscription(feed.ID)
+		mu.Lock()
+		defer mu.Unlock()
+		a.Equal("subscribe", subscription.Get("hub.mode"))
+		a.Equal("https://example.com/feed.xml", subscription.Get("hub.topic"))
+		a.Equal(secret, subscription.Get("hub.secret"))
+	})
+
+	t.Run("pushed content is ingested", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		mu.Lock()
+		secret := subscription.Get("hub.secret")
+		mu.Unlock()
+		body := rssFixture("Fixture News", 3)
+		w := push(body, sign(secret, body))
+		r.Equal(http.StatusOK, w.Code, w.Body.String())
+		var summary types.LoadSummary
+		r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+		a.Equal(3, summary.Created)
+
+		articles, err := articleStore.List("", 0, feed.ID)
+		r.NoError(err)
+		a.Len(articles, 3)
+		for _, article := range articles {
+			a.Equal("Fixture", article.Provider)
+		}
+	})
+
+	t.Run("content with invalid signature is ignored", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		body := rssFixture("Fixture News", 5)
+		for _, signature := range []string{"", "sha256=00", sign("wrong_secret", body)} {
+			w := push(body, signature)
+			a.Equal(http.StatusAccepted, w.Code, signature)
+		}
+		articles, err := articleStore.List("", 0, feed.ID)
+		r.NoError(err)
+		a.Len(articles, 3)
+	})
+
+	t.Run("feed without hub", func(t *testing.T) {
+		r := require.New(t)
+		plain := newFixtureServer(rssFixture("Plain News", 1))
+		defer plain.Close()
+		other, err := feedStore.Create(&types.Feed{Provider: "Plain", Address: plain.URL})
+		r.NoError(err)
+		w := performRequest(router, http.MethodPost, "/feeds/"+other.ID+"/websub", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("requires the base URL", func(t *testing.T) {
+		router := NewService(consumer, reader, feedStore, articleStore).setupServiceRouter()
+		w := performRequest(router, http.MethodPost, "/feeds/"+feed.ID+"/websub", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unreadable feed", func(t *testing.T) {
+		r := require.New(t)
+		closed := newFixtureServer(rssFixture("Closed News", 1))
+		closed.Close()
+		other, err := feedStore.Create(&types.Feed{Provider: "Closed", Address: closed.URL})
+		r.NoError(err)
+		w := performRequest(router, http.MethodPost, "/feeds/"+other.ID+"/websub", nil)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+
+	t.Run("only https is allowed when required", func(t *testing.T) {
+		r := require.New(t)
+		secure, err := feedStore.Create(&types.Feed{Provider: "Secure", Address: "https://example.com/feed.xml"})
+		r.NoError(err)
+		for name, tc := range map[string]struct {
+			feed    *types.Feed
+			hub     string
+			baseURL string
+			err     error
+		}{
+			"insecure feed":     {feed: feed, hub: "https://hub.example.com", baseURL: "https://news.example.com", err: errInsecureFeed},
+			"insecure hub":      {feed: secure, hub: "http://hub.example.com", baseURL: "https://news.example.com", err: errInsecureWebSub},
+			"insecure callback": {feed: secure, hub: "https://hub.example.com", baseURL: "http://news.example.com", err: errInsecureWebSub},
+		} {
+			t.Run(name, func(t *testing.T) {
+				a := assert.New(t)
+				reader := channelReader{channel: &types.Channel{Hub: tc.hub, Topic: tc.feed.Address}}
+				router := NewService(consumer, reader, feedStore, articleStore,
+					WithBaseURL(tc.baseURL),
+					WithHTTPSOnly(true),
+				).setupServiceRouter()
+				w := performRequest(router, http.MethodPost, "/feeds/"+tc.feed.ID+"/websub", nil)
+				a.Equal(http.StatusForbidden, w.Code)
+				a.Contains(w.Body.String(), tc.err.Error())
+			})
+		}
+	})
+}
+
+func TestGetArticleExpandFeed(t *testing.T) {
+	s, feedStore, articleStore := newTestService()
+	router := s.setupServiceRouter()
+	r := require.New(t)
+	feed, err := feedStore.Create(&types.Feed{
+		Provider:    "BBC",
+		Category:    "UK",
+		Address:     "https://example.com/rss",
+		Credentials: &types.Credentials{Username: "user", Password: "secret"},
+	})
+	r.NoError(err)
+	article, err := articleStore.Create(&types.Article{GUID: "guid", FeedID: feed.ID, Provider: feed.Provider})
+	r.NoError(err)
+	injected, err := articleStore.Create(&types.Article{GUID: "injected"})
+	r.NoError(err)
+
+	t.Run("embeds the source feed", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"?expand=feed", nil)
+		r.Equal(http.StatusOK, w.Code)
+		a.NotContains(w.Body.String(), "secret")
+		var got struct {
+			ID   string
+			Feed *types.Feed `json:"feed"`
+		}
+		r.NoError(json.NewDecoder(w.Body).Decode(&got))
+		a.Equal(article.ID, got.ID)
+		r.NotNil(got.Feed)
+		a.Equal(feed.ID, got.Feed.ID)
+		a.Equal(feed.Provider, got.Feed.Provider)
+		a.Equal(feed.Category, got.Feed.Category)
+		a.Equal(feed.Address, got.Feed.Address)
+	})
+
+	t.Run("not expanded by default", func(t *testing.T) {
+		w := performRequest(router, http.MethodGet, "/articles/"+article.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), `"feed"`)
+	})
+
+	t.Run("articles without feed", func(t *testing.T) {
+		w := performRequest(router, http.MethodGet, "/articles/"+injected.ID+"?expand=feed", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"feed":null`)
+	})
+
+	t.Run("unknown expansion", func(t *testing.T) {
+		w := performRequest(router, http.MethodGet, "/articles/"+article.ID+"?expand=author", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestMaxCategoryFilters(t *testing.T) {
+	categories := func(n int) string {
+		query := url.Values{}
+		for i := 0; i < n; i++ {
+			query.Add("cat", fmt.Sprintf("category_%d", i))
+		}
+		return query.Encode()
+	}
+	articleStore := store.NewArticleStore()
+	_, err := articleStore.Create(&types.Article{GUID: "guid", Categories: []string{"category_0"}})
+	require.NoError(t, err)
+
+	t.Run("more filters than the cap are rejected", func(t *testing.T) {
+		a := assert.New(t)
+		router := NewService(nil, nil, nil, articleStore, WithMaxCategoryFilters(3)).setupServiceRouter()
+		for _, path := range []string{"/articles?", "/articles/export.csv?", "/articles/grouped?by=provider&", "/articles/histogram?", "/articles/cursors?"} {
+			w := performRequest(router, http.MethodGet, path+categories(4), nil)
+			a.Equal(http.StatusBadRequest, w.Code, path)
+			a.Contains(w.Body.String(), "too many category filters", path)
+		}
+		w := performRequest(router, http.MethodGet, "/articles?"+categories(3), nil)
+		a.Equal(http.StatusOK, w.Code)
+	})
+
+	t.Run("default cap", func(t *testing.T) {
+		a := assert.New(t)
+		router := NewService(nil, nil, nil, articleStore).setupServiceRouter()
+		w := performRequest(router, http.MethodGet, "/articles?"+categories(defaultMaxCategoryFilters+1), nil)
+		a.Equal(http.StatusBadRequest, w.Code)
+		w = performRequest(router, http.MethodGet, "/articles?"+categories(defaultMaxCategoryFilters), nil)
+		a.Equal(http.StatusOK, w.Code)
+	})
+
+	t.Run("zero is unlimited", func(t *testing.T) {
+		router := NewService(nil, nil, nil, articleStore, WithMaxCategoryFilters(0)).setupServiceRouter()
+		w := performRequest(router, http.MethodGet, "/articles?"+categories(200), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestRefreshContent(t *testing.T) {
+	newArticles := func(r *require.Assertions, articleStore *store.ArticleStore, n int) []*types.Article {
+		var articles []*types.Article
+		for i := 0; i < n; i++ {
+			article, err := articleStore.Create(&types.Article{
+				GUID: fmt.Sprintf("guid_%d", i),
+				Link: fmt.Sprintf("https://example.com/story/%d", i),
+			})
+			r.NoError(err)
+			articles = append(articles, article)
+		}
+		return articles
+	}
+
+	t.Run("concurrency stays within the limit", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		var inFlight, maxInFlight int32
+		fetcher := contentFetcherFunc(func(ctx context.Context, url string) (string, error) {
+			current := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				observed := atomic.LoadInt32(&maxInFlight)
+				if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return "text of " + url, nil
+		})
+		articleStore := store.NewArticleStore()
+		articles := newArticles(r, articleStore, 12)
+		s := NewService(nil, nil, nil, articleStore, WithContentFetcher(fetcher), WithContentRefresh(3, time.Second))
+
+		refreshed, failed := s.refreshContent(context.Background(), articles)
+		a.Equal(12, refreshed)
+		a.Zero(failed)
+		a.LessOrEqual(atomic.LoadInt32(&maxInFlight), int32(3))
+		a.Equal(int32(3), atomic.LoadInt32(&maxInFlight), "the configured concurrency must be used")
+		stored, err := articleStore.Get(articles[5].ID)
+		r.NoError(err)
+		a.Equal("text of https://example.com/story/5", stored.FullText)
+	})
+
+	t.Run("cancelling stops the job promptly", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		var started int32
+		fetcher := contentFetcherFunc(func(ctx context.Context, url string) (string, error) {
+			atomic.AddInt32(&started, 1)
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		articleStore := store.NewArticleStore()
+		articles := newArticles(r, articleStore, 20)
+		s := NewService(nil, nil, nil, articleStore, WithContentFetcher(fetcher), WithContentRefresh(2, time.Minute))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		start := time.Now()
+		refreshed, failed := s.refreshContent(ctx, articles)
+		a.Less(time.Since(start), time.Second)
+		a.Zero(refreshed)
+		a.Zero(failed, "cancelled fetches must not count as failed")
+		a.Equal(int32(2), atomic.LoadInt32(&started), "no fetch must start after cancelling")
+	})
+
+	t.Run("fetches are limited by the timeout", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		fetcher := contentFetcherFunc(func(ctx context.Context, url string) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		articleStore := store.NewArticleStore()
+		articles := newArticles(r, articleStore, 3)
+		s := NewService(nil, nil, nil, articleStore, WithContentFetcher(fetcher), WithContentRefresh(1, 5*time.Millisecond))
+
+		refreshed, failed := s.refreshContent(context.Background(), articles)
+		a.Zero(refreshed)
+		a.Equal(3, failed)
+	})
+
+	t.Run("admin endpoint", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		fetcher := contentFetcherFunc(func(ctx context.Context, url string) (string, error) {
+			return "fetched", nil
+		})
+		articleStore := store.NewArticleStore()
+		articles := newArticles(r, articleStore, 3)
+		_, err := articleStore.UpdateFullText(articles[0].ID, "existing", "", false)
+		r.NoError(err)
+		_, err = articleStore.Create(&types.Article{GUID: "no_link"})
+		r.NoError(err)
+		router := NewService(nil, nil, nil, articleStore, WithContentFetcher(fetcher), WithAdminToken("secret")).setupServiceRouter()
+
+		w := performRequestWithHeader(router, http.MethodPost, "/admin/articles/refresh-content?missing=true", nil, http.Header{"Authorization": {"Bearer secret"}})
+		r.Equal(http.StatusOK, w.Code)
+		a.JSONEq(`{"refreshed":2,"failed":0}`, w.Body.String())
+		stored, err := articleStore.Get(articles[0].ID)
+		r.NoError(err)
+		a.Equal("existing", stored.FullText)
+
+		w = performRequest(router, http.MethodPost, "/admin/articles/refresh-content", nil)
+		a.Equal(http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiPath is the path the reader UI is served under when enabled.
+const uiPath = "/ui"
+
+// uiFiles holds the static files of the reader UI, a single page calling the API of the service.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiFS returns the file system serving the reader UI files from its root.
+func uiFS() http.FileSystem {
+	sub, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		// The embedded directory always exists, so this can't happen.
+		panic(err)
+	}
+	return http.FS(sub)
+}
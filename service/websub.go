@@ -0,0 +1,395 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// websubTimeout is the time allowed for a WebSub hub to answer a subscription request.
+const websubTimeout = 30 * time.Second
+
+// maxPushSize is the maximum size in bytes of the content pushed by a WebSub hub.
+const maxPushSize = 10 << 20
+
+// websubCallbackPath is the path WebSub hubs verify subscriptions at and push content to.
+const websubCallbackPath = "/websub/callback"
+
+// errInsecureWebSub is the error reported for WebSub hubs or callbacks with plain HTTP addresses when
+// only HTTPS is allowed.
+var errInsecureWebSub = errors.New("only https websub hubs and callbacks are allowed")
+
+// websubSubscription is a subscription to the WebSub hub of a feed, which only receives content once
+// the hub verified it. Subscriptions with a lease are renewed by the renewal timer before it expires.
+type websubSubscription struct {
+	feedID   string
+	hub      string
+	topic    string
+	secret   string
+	verified bool
+	expires  time.Time
+	renewal  *time.Timer
+}
+
+// websubSubscriptions holds the WebSub subscriptions of the feeds, keyed by feed ID.
+type websubSubscriptions struct {
+	mu   sync.Mutex
+	subs map[string]*websubSubscription
+}
+
+// newWebSubSubscriptions returns an empty set of subscriptions.
+func newWebSubSubscriptions() *websubSubscriptions {
+	return &websubSubscriptions{subs: map[string]*websubSubscription{}}
+}
+
+// add replaces the subscription of its feed with the provided one, cancelling the renewal of the
+// replaced one.
+func (ws *websubSubscriptions) add(sub *websubSubscription) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if old, ok := ws.subs[sub.feedID]; ok && old.renewal != nil {
+		old.renewal.Stop()
+	}
+	ws.subs[sub.feedID] = sub
+}
+
+// verify marks the subscription of the feed to the provided topic as verified by the hub for the
+// given lease, returning whether it exists.
+func (ws *websubSubscriptions) verify(feedID string, topic string, lease time.Duration) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	sub, ok := ws.subs[feedID]
+	if !ok || sub.topic != topic {
+		return false
+	}
+	sub.verified = true
+	if lease > 0 {
+		sub.expires = time.Now().Add(lease)
+	}
+	return true
+}
+
+// remove deletes the subscription of the feed to the provided topic, if any, cancelling its renewal.
+func (ws *websubSubscriptions) remove(feedID string, topic string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if sub, ok := ws.subs[feedID]; ok && sub.topic == topic {
+		if sub.renewal != nil {
+			sub.renewal.Stop()
+		}
+		delete(ws.subs, feedID)
+	}
+}
+
+// scheduleRenewal sets the provided function to renew the subscription of the feed to the provided
+// topic after the given delay, replacing any renewal scheduled before. Returns whether the
+// subscription exists.
+func (ws *websubSubscriptions) scheduleRenewal(feedID string, topic string, delay time.Duration, renew func()) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	sub, ok := ws.subs[feedID]
+	if !ok || sub.topic != topic {
+		return false
+	}
+	if sub.renewal != nil {
+		sub.renewal.Stop()
+	}
+	sub.renewal = time.AfterFunc(delay, renew)
+	return true
+}
+
+// websubRenewal returns the delay after which a subscription with the provided lease is renewed,
+// leaving a tenth of the lease for the hub to verify the renewal before the subscription expires.
+func websubRenewal(lease time.Duration) time.Duration {
+	return lease - lease/10
+}
+
+// active returns a copy of the verified subscription of the feed, or nil when it has none or its
+// lease expired.
+func (ws *websubSubscriptions) active(feedID string) *websubSubscription {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	sub, ok := ws.subs[feedID]
+	if !ok || !sub.verified || (!sub.expires.IsZero() && time.Now().After(sub.expires)) {
+		return nil
+	}
+	copied := *sub
+	return &copied
+}
+
+// subscribeFeed subscribes to the WebSub hub declared by a feed, so the hub pushes its updates to
+// the callback endpoint. The subscription is only active once the hub verifies it, which happens
+// asynchronously. When only HTTPS is allowed, the feed, the hub and the callback must all use it.
+func (s *Service) subscribeFeed(c *gin.Context) {
+	var args GetFeedArgs
+	if c.BindUri(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	if s.baseURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "websub requires the base URL of the service",
+		})
+		return
+	}
+	feed, err := s.feedStore.Get(args.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if s.insecure(feed.Address) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": errInsecureFeed.Error(),
+		})
+		return
+	}
+	channel, err := s.reader.Read(feed.Address, feed.ReadOptions())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if channel.Hub == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "feed declares no websub hub",
+		})
+		return
+	}
+	sub := &websubSubscription{
+		feedID: feed.ID,
+		hub:    channel.Hub,
+		topic:  channel.Topic,
+		secret: uuid.New().String(),
+	}
+	callback := s.websubCallback(feed.ID)
+	if s.insecure(sub.hub, callback) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": errInsecureWebSub.Error(),
+		})
+		return
+	}
+	// The subscription is kept before requesting it, since hubs may verify it before answering.
+	s.websub.add(sub)
+	if err := s.requestSubscription(sub, callback); err != nil {
+		s.websub.remove(sub.feedID, sub.topic)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"hub":      sub.hub,
+		"topic":    sub.topic,
+		"callback": callback,
+	})
+}
+
+// websubCallback returns the URL WebSub hubs verify the subscription of the feed at and push its
+// content to.
+func (s *Service) websubCallback(feedID string) string {
+	return s.baseURL + websubCallbackPath + "?feed=" + url.QueryEscape(feedID)
+}
+
+// renewSubscription requests the subscription of the feed again before its lease expires, logging
+// the failures since there is no client to report them to. Subscriptions that are no longer active,
+// or whose feed was deleted, are not renewed.
+func (s *Service) renewSubscription(feedID string) {
+	sub := s.websub.active(feedID)
+	if sub == nil {
+		return
+	}
+	if _, err := s.feedStore.Get(feedID); err != nil {
+		s.websub.remove(feedID, sub.topic)
+		return
+	}
+	if err := s.requestSubscription(sub, s.websubCallback(feedID)); err != nil {
+		log.Printf("could not renew websub subscription of feed %s: %v", feedID, err)
+	}
+}
+
+// requestSubscription asks the hub of the subscription to push the updates of its topic to the
+// provided callback, signing them with the secret of the subscription.
+func (s *Service) requestSubscription(sub *websubSubscription, callback string) error {
+	res, err := s.websubClient.PostForm(sub.hub, url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.topic":    {sub.topic},
+		"hub.callback": {callback},
+		"hub.secret":   {sub.secret},
+	})
+	if err != nil {
+		return fmt.Errorf("could not subscribe to the websub hub: %v", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("websub hub rejected the subscription with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// WebSubVerifyArgs represents the arguments sent by a WebSub hub when verifying a subscription.
+type WebSubVerifyArgs struct {
+	Feed      string `form:"feed" binding:"required"`
+	Mode      string `form:"hub.mode" binding:"required"`
+	Topic     string `form:"hub.topic" binding:"required"`
+	Challenge string `form:"hub.challenge"`
+	Lease     int    `form:"hub.lease_seconds"`
+}
+
+// verifyWebSub answers the verification of a subscription by a WebSub hub, echoing its challenge for
+// the subscriptions that were requested. Verified subscriptions with a lease are renewed before it
+// expires, and hubs denying a subscription get it removed.
+func (s *Service) verifyWebSub(c *gin.Context) {
+	var args WebSubVerifyArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	switch args.Mode {
+	case "subscribe":
+		lease := time.Duration(args.Lease) * time.Second
+		if args.Challenge == "" || !s.websub.verify(args.Feed, args.Topic, lease) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "unknown subscription",
+			})
+			return
+		}
+		if lease > 0 {
+			feedID := args.Feed
+			s.websub.scheduleRenewal(feedID, args.Topic, websubRenewal(lease), func() {
+				s.renewSubscription(feedID)
+			})
+		}
+		c.String(http.StatusOK, args.Challenge)
+	case "unsubscribe":
+		// Only the subscriptions that are no longer wanted, such as the ones of deleted feeds, are
+		// confirmed.
+		if s.websub.active(args.Feed) != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "subscription still wanted",
+			})
+			return
+		}
+		c.String(http.StatusOK, args.Challenge)
+	case "denied":
+		s.websub.remove(args.Feed, args.Topic)
+		c.Status(http.StatusOK)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+	}
+}
+
+// WebSubPushArgs represents the arguments of the content pushed by a WebSub hub.
+type WebSubPushArgs struct {
+	Feed string `form:"feed" binding:"required"`
+}
+
+// receiveWebSub ingests the feed content pushed by a WebSub hub for a verified subscription,
+// returning the summary of the articles stored. Content whose signature doesn't match is
+// acknowledged, as required by WebSub, but ignored.
+func (s *Service) receiveWebSub(c *gin.Context) {
+	var args WebSubPushArgs
+	if c.BindQuery(&args) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid arguments",
+		})
+		return
+	}
+	sub := s.websub.active(args.Feed)
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "unknown subscription",
+		})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPushSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid content",
+		})
+		return
+	}
+	if err := verifySignature(c.GetHeader("X-Hub-Signature"), sub.secret, body); err != nil {
+		log.Printf("ignoring websub content for feed %s: %v", sub.feedID, err)
+		c.Status(http.StatusAccepted)
+		return
+	}
+	feed, err := s.feedStore.Get(sub.feedID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	channel, err := s.reader.Parse(sub.topic, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	summary, err := s.feeder.Ingest(feed, channel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// verifySignature checks that the provided X-Hub-Signature header, such as "sha256=<hex>", holds the
+// HMAC of the body with the given secret.
+func verifySignature(header string, secret string, body []byte) error {
+	i := strings.Index(header, "=")
+	if i < 0 {
+		return errors.New("missing signature")
+	}
+	var newHash func() hash.Hash
+	switch header[:i] {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return fmt.Errorf("unsupported signature method %q", header[:i])
+	}
+	signature, err := hex.DecodeString(header[i+1:])
+	if err != nil {
+		return errors.New("malformed signature")
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
@@ -2,10 +2,16 @@ package store
 
 import (
 	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"../clock"
 	"../types"
 )
 
@@ -13,69 +19,239 @@ import (
 type ArticleStore struct {
 	mu            sync.RWMutex
 	a             []*types.Article
+	ingested      []*types.Article
 	m             map[string]*types.Article
+	state         map[string]articleState
+	version       uint64
+	versions      map[string]uint64
+	tombstones    map[string]*types.Tombstone
 	uuidNamespace uuid.UUID
+	clock         clock.Clock
+	wal           *os.File
+	walPath       string
+
+	categoryNormalization CategoryNormalization
+	idScheme              IDScheme
+	tombstoneRetention    time.Duration
+	compareFields         map[CompareField]bool
+	idTieBreak            bool
+	evictionPolicy        EvictionPolicy
+}
+
+// ArticleStoreOption configures optional behaviour of an ArticleStore.
+type ArticleStoreOption func(*ArticleStore)
+
+// WithClock sets the clock used by the store for time-based features, such as ingestion times.
+func WithClock(c clock.Clock) ArticleStoreOption {
+	return func(as *ArticleStore) {
+		as.clock = c
+	}
+}
+
+// WithIDTieBreak orders articles with the same publish date by ID instead of by the order they were
+// ingested in, so their relative order doesn't depend on the order feeds list them nor on the order
+// feeds are loaded in.
+func WithIDTieBreak(enabled bool) ArticleStoreOption {
+	return func(as *ArticleStore) {
+		as.idTieBreak = enabled
+	}
 }
 
 // NewArticleStore returns a new Article Store.
-func NewArticleStore() *ArticleStore {
-	return &ArticleStore{
+func NewArticleStore(opts ...ArticleStoreOption) *ArticleStore {
+	as := &ArticleStore{
 		a:             []*types.Article{},
+		ingested:      []*types.Article{},
 		m:             map[string]*types.Article{},
+		state:         map[string]articleState{},
+		versions:      map[string]uint64{},
+		tombstones:    map[string]*types.Tombstone{},
 		uuidNamespace: uuid.MustParse(uuidNamespace),
+		clock:         clock.New(),
 	}
+	for _, opt := range opts {
+		opt(as)
+	}
+	return as
 }
 
-// Reset clears the store to its initial state.
-func (as *ArticleStore) Reset() {
+// Reset clears the store to its initial state, returning the number of articles cleared. The
+// write-ahead log, if open, is compacted so that the cleared articles are not recovered from it.
+func (as *ArticleStore) Reset() (int, error) {
 	as.mu.Lock()
 	defer as.mu.Unlock()
+	cleared := len(as.m)
 	as.a = []*types.Article{}
+	as.ingested = []*types.Article{}
 	as.m = map[string]*types.Article{}
+	as.state = map[string]articleState{}
+	as.versions = map[string]uint64{}
+	as.tombstones = map[string]*types.Tombstone{}
+	if as.walPath == "" {
+		return cleared, nil
+	}
+	return cleared, as.compactWAL()
 }
 
 // Create stores the provided article in the store in the correct order by publish date and returns
-// the saved item. If the GUID is already present in the store, it will just return the existing
-// item, discarding the provided value.
+// the saved item, stamped with its ingestion time. If the GUID is already present in the store, it
+// will just return the existing item, discarding the provided value. Articles with a Scope are only
+// matched against those with the same GUID and scope. Articles that were deleted are not stored again
+// while their tombstone is retained, returning nil instead, as are articles evicted by the eviction
+// policy right after being created.
 func (as *ArticleStore) Create(article *types.Article) (*types.Article, error) {
 	if article == nil {
 		return nil, nil
 	}
-	generatedID := uuid.NewSHA1(as.uuidNamespace, []byte(article.GUID)).String()
+	generatedID := as.scopedArticleID(article.GUID, article.Scope)
+	as.mu.Lock()
+	defer as.mu.Unlock()
 	if a, ok := as.m[generatedID]; ok {
 		return a, nil
 	}
+	if as.tombstoned(generatedID) {
+		return nil, nil
+	}
 	article.ID = generatedID
-	as.mu.Lock()
-	defer as.mu.Unlock()
+	article.IngestedAt = as.clock.Now()
+	if as.categoryNormalization != NormalizeNone {
+		article.DisplayCategories = article.Categories
+	}
+	article.Categories = as.categoryNormalization.normalizeAll(article.Categories)
+	if err := as.appendWAL(walEntry{Op: walCreate, Article: article}); err != nil {
+		return nil, err
+	}
+	as.applyState(article)
+	as.insert(article)
+	as.evict(as.evictionPolicy, article)
+	if _, ok := as.m[article.ID]; !ok {
+		return nil, nil
+	}
+	return article, nil
+}
+
+// insert adds the article to the store, keeping the articles ordered by publish date. The caller
+// must hold the lock.
+func (as *ArticleStore) insert(article *types.Article) {
+	as.version++
+	as.versions[article.ID] = as.version
+	as.m[article.ID] = article
+	as.ingested = append(as.ingested, article)
 	// To maintain the order of the slice, whenever a new element is added, it is injected in order.
 	// This is an expensive operation for writes, but is optimal for reading.
 
 	// If it is already the newer item, append it to the end.
-	if len(as.a) == 0 || !as.a[len(as.a)-1].PublishDate.After(article.PublishDate) {
+	if len(as.a) == 0 || !as.before(article, as.a[len(as.a)-1]) {
 		as.a = append(as.a, article)
-		as.m[generatedID] = article
-		return article, nil
+		return
 	}
 
 	// If the article is the oldest one, append to the beginning.
-	if !article.PublishDate.After(as.a[0].PublishDate) {
+	if !as.before(as.a[0], article) {
 		as.a = append([]*types.Article{article}, as.a...)
-		as.m[generatedID] = article
-		return article, nil
+		return
 	}
 
 	// The check is done in backwards because it is likely that new articles will have newer publish
 	// dates.
 	for i := len(as.a) - 2; i >= 0; i-- {
-		if article.PublishDate.After(as.a[i].PublishDate) || i == 0 {
+		if as.before(as.a[i], article) || i == 0 {
 			as.a = append(as.a[:i+1], as.a[i:]...)
 			as.a[i+1] = article
-			as.m[generatedID] = article
 			break
 		}
 	}
-	return article, nil
+}
+
+// before reports whether article a is ordered before article b, which is by publish date and, when
+// the ID tie-break is enabled, by ID for articles with the same publish date.
+func (as *ArticleStore) before(a, b *types.Article) bool {
+	if !a.PublishDate.Equal(b.PublishDate) {
+		return a.PublishDate.Before(b.PublishDate)
+	}
+	return as.idTieBreak && a.ID < b.ID
+}
+
+// Upsert stores the provided article like Create does but, if an article with the same GUID is
+// already present, its mutable fields (Title, Description, Content and Categories) are updated with
+// the provided values instead. Updated articles keep their ID and position in the store. The
+// returned diff reports whether the article was created or which of its compared fields changed,
+// as set by WithCompareFields. Like with Create, deleted articles are not stored again and articles
+// evicted right away are not kept, returning nil instead.
+func (as *ArticleStore) Upsert(article *types.Article) (*types.Article, *types.ArticleDiff, error) {
+	if article == nil {
+		return nil, nil, nil
+	}
+	generatedID := as.scopedArticleID(article.GUID, article.Scope)
+	as.mu.Lock()
+	if existing, ok := as.m[generatedID]; ok {
+		defer as.mu.Unlock()
+		// Changes are made to a copy, so the existing article is left untouched if they can't be
+		// logged.
+		updated := *existing
+		diff := &types.ArticleDiff{}
+		changed := func(field CompareField) {
+			if as.compares(field) {
+				diff.Fields = append(diff.Fields, string(field))
+			}
+		}
+		if updated.Title != article.Title {
+			changed(CompareTitle)
+			updated.Title = article.Title
+		}
+		if updated.Description != article.Description {
+			changed(CompareDescription)
+			updated.Description = article.Description
+		}
+		if updated.Content != article.Content {
+			changed(CompareContent)
+			updated.Content = article.Content
+		}
+		categories := as.categoryNormalization.normalizeAll(article.Categories)
+		if !equalStrings(updated.Categories, categories) {
+			changed(CompareCategories)
+			updated.Categories = categories
+			if as.categoryNormalization != NormalizeNone {
+				updated.DisplayCategories = article.Categories
+			}
+		}
+		if diff.Changed() {
+			if err := as.appendWAL(walEntry{Op: walUpdate, Article: &updated}); err != nil {
+				return nil, nil, err
+			}
+			as.replace(map[*types.Article]*types.Article{existing: &updated})
+			return &updated, diff, nil
+		}
+		return existing, diff, nil
+	}
+	as.mu.Unlock()
+	created, err := as.Create(article)
+	if err != nil || created == nil {
+		return nil, nil, err
+	}
+	return created, &types.ArticleDiff{Created: true}, nil
+}
+
+// equalStrings reports whether both slices hold the same values in the same order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Snapshot returns a token capturing the articles currently in the store. Filtering by the token
+// selects only these articles, so clients paginating with it are not affected by articles created
+// afterwards.
+func (as *ArticleStore) Snapshot() string {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return strconv.FormatUint(as.version, 10)
 }
 
 // List reads articles from the store and returns the requested number of articles starting from the
@@ -85,44 +261,186 @@ func (as *ArticleStore) Create(article *types.Article) (*types.Article, error) {
 // filtering will bypass any news for any category provided. If pageSize is set to 0, the service
 // returns all records.
 func (as *ArticleStore) List(cursor string, pageSize int, feed string, categories ...string) ([]*types.Article, error) {
-	// Create a hashmap for filtering.
-	cat := make(map[string]struct{}, len(categories))
-	for _, c := range categories {
-		cat[c] = struct{}{}
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(types.ArticleFilter{Feed: feed, Categories: categories})
+	if err != nil {
+		return nil, err
+	}
+	return listArticles(as.a, cursor, pageSize, matcher)
+}
+
+// ListByIngestion works like List, but returns the articles in the order they were ingested into the
+// store instead of by publish date. Articles arriving late with an older publish date, such as in
+// backfills, are inserted before existing ones when ordering by publish date and are missed by
+// clients paginating forward from a later cursor. Ordering by ingestion appends them at the end, so
+// they are always seen, at the cost of the pages not being ordered by publish date.
+func (as *ArticleStore) ListByIngestion(cursor string, pageSize int, feed string, categories ...string) ([]*types.Article, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(types.ArticleFilter{Feed: feed, Categories: categories})
+	if err != nil {
+		return nil, err
 	}
+	return listArticles(as.ingested, cursor, pageSize, matcher)
+}
+
+// ListRange returns the articles ordered by publish date that lie strictly between the afterID and
+// beforeID cursors, both exclusive, such as for re-syncing the articles between two known cursors.
+// An empty afterID starts from the first article and an empty beforeID ends at the last one. Returns
+// an error if either cursor is unknown or the afterID article comes after the beforeID one. Feed and
+// categories filter the articles like in List.
+func (as *ArticleStore) ListRange(afterID, beforeID string, feed string, categories ...string) ([]*types.Article, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(types.ArticleFilter{Feed: feed, Categories: categories})
+	if err != nil {
+		return nil, err
+	}
+	start, ok := findArticleCursorIndex(as.a, afterID)
+	if !ok {
+		return nil, errors.New("could not find provided cursor")
+	}
+	end := len(as.a)
+	if beforeID != "" {
+		// The cursor index follows the article, which is excluded.
+		next, ok := findArticleCursorIndex(as.a, beforeID)
+		if !ok {
+			return nil, errors.New("could not find provided cursor")
+		}
+		end = next - 1
+	}
+	if start > end {
+		return nil, errors.New("invalid range: afterID comes after beforeID")
+	}
+	var res []*types.Article
+	for _, a := range as.a[start:end] {
+		if matcher.match(a) {
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
 
+// Neighbors returns the articles matching the filter that come right before and after the article
+// with the provided ID in order of publish date, which are nil at either end. The article itself
+// doesn't need to match the filter.
+func (as *ArticleStore) Neighbors(ID string, filter types.ArticleFilter) (*types.Article, *types.Article, error) {
 	as.mu.RLock()
 	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	index := -1
+	for i, a := range as.a {
+		if a.ID == ID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, nil, errors.New("resource not found")
+	}
+	var prev, next *types.Article
+	for i := index - 1; i >= 0 && prev == nil; i-- {
+		if matcher.match(as.a[i]) {
+			prev = as.a[i]
+		}
+	}
+	for i := index + 1; i < len(as.a) && next == nil; i++ {
+		if matcher.match(as.a[i]) {
+			next = as.a[i]
+		}
+	}
+	return prev, next, nil
+}
 
-	firstReturnIndex, ok := as.findArticleCursorIndex(cursor)
+// ForEach calls fn for each stored article in order of publish date, without copying them, and stops
+// on the first error, which is returned. The articles are read under the store lock, so fn must not
+// call methods changing the store, which would deadlock, nor modify the articles it is passed, which
+// is unsafe for concurrent readers.
+func (as *ArticleStore) ForEach(fn func(*types.Article) error) error {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	for _, a := range as.a {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListFiltered works like List, or like ListByIngestion when ordering by ingestion, selecting the
+// articles matching the provided filter. Articles can also be listed by title or in descending
+// order, which are computed on each call.
+func (as *ArticleStore) ListFiltered(cursor string, pageSize int, filter types.ArticleFilter, order types.ArticleOrder) ([]*types.Article, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+	return listArticles(as.ordered(order), cursor, pageSize, matcher)
+}
+
+// Latest returns up to n articles matching the filter, newest first by publish date. The articles are
+// read from the end of the ordered articles, stopping once n are found, so it is cheaper than listing
+// all of them in descending order. Fewer articles are returned when not enough match.
+func (as *ArticleStore) Latest(n int, filter types.ArticleFilter) ([]*types.Article, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+	res := []*types.Article{}
+	for i := len(as.a) - 1; i >= 0 && len(res) < n; i-- {
+		if matcher.match(as.a[i]) {
+			res = append(res, as.a[i])
+		}
+	}
+	return res, nil
+}
+
+// ordered returns the articles in the provided order. The caller must hold the lock.
+func (as *ArticleStore) ordered(order types.ArticleOrder) []*types.Article {
+	var articles []*types.Article
+	switch order &^ types.OrderDescending {
+	case types.OrderIngested:
+		articles = as.ingested
+	case types.OrderTitle:
+		// The sort is stable, so articles with the same title are kept by publish date.
+		articles = append([]*types.Article(nil), as.a...)
+		sort.SliceStable(articles, func(i, j int) bool {
+			return strings.ToLower(articles[i].Title) < strings.ToLower(articles[j].Title)
+		})
+	default:
+		articles = as.a
+	}
+	if order&types.OrderDescending == 0 {
+		return articles
+	}
+	reversed := make([]*types.Article, len(articles))
+	for i, a := range articles {
+		reversed[len(articles)-1-i] = a
+	}
+	return reversed
+}
+
+// listArticles returns up to pageSize articles matching the matcher from the provided slice,
+// starting after the cursor.
+func listArticles(articles []*types.Article, cursor string, pageSize int, matcher *articleMatcher) ([]*types.Article, error) {
+	firstReturnIndex, ok := findArticleCursorIndex(articles, cursor)
 	if !ok {
 		return nil, errors.New("could not find provided cursor")
 	}
 	found := 0
 	var res []*types.Article
-	for i := firstReturnIndex; i < len(as.a); i++ {
-		current := as.a[i]
-		if len(cat) > 0 {
-			// Must do some filtering on categories.
-			if len(current.Categories) == 0 {
-				continue
-			}
-			hasCategory := false
-			for _, c := range current.Categories {
-				if _, ok := cat[c]; ok {
-					hasCategory = true
-					break
-				}
-			}
-			if !hasCategory {
-				continue
-			}
-		}
-		if feed != "" {
-			// Must do filtering on feed.
-			if current.FeedID != feed {
-				continue
-			}
+	for i := firstReturnIndex; i < len(articles); i++ {
+		current := articles[i]
+		if !matcher.match(current) {
+			continue
 		}
 		res = append(res, current)
 		found++
@@ -134,6 +452,68 @@ func (as *ArticleStore) List(cursor string, pageSize int, feed string, categorie
 	return res, nil
 }
 
+// GroupByProvider returns the articles matching the provided filter grouped by their provider,
+// newest first. Each group holds up to pageSize articles, or all of them if pageSize is 0. Articles
+// without a provider are grouped under "unknown".
+func (as *ArticleStore) GroupByProvider(pageSize int, filter types.ArticleFilter) (map[string][]*types.Article, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+	groups := map[string][]*types.Article{}
+	for i := len(as.a) - 1; i >= 0; i-- {
+		current := as.a[i]
+		if !matcher.match(current) {
+			continue
+		}
+		provider := current.Provider
+		if provider == "" {
+			provider = unknownProvider
+		}
+		if pageSize > 0 && len(groups[provider]) == pageSize {
+			continue
+		}
+		groups[provider] = append(groups[provider], current)
+	}
+	return groups, nil
+}
+
+// FirstCursor returns the ID of the oldest article matching the provided filter, which bounds the
+// start of the filtered set. Returns an empty string if no articles match.
+func (as *ArticleStore) FirstCursor(filter types.ArticleFilter) (string, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(filter)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < len(as.a); i++ {
+		if matcher.match(as.a[i]) {
+			return as.a[i].ID, nil
+		}
+	}
+	return "", nil
+}
+
+// LastCursor returns the ID of the newest article matching the provided filter, which bounds the
+// end of the filtered set. Returns an empty string if no articles match.
+func (as *ArticleStore) LastCursor(filter types.ArticleFilter) (string, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(filter)
+	if err != nil {
+		return "", err
+	}
+	for i := len(as.a) - 1; i >= 0; i-- {
+		if matcher.match(as.a[i]) {
+			return as.a[i].ID, nil
+		}
+	}
+	return "", nil
+}
+
 // Get returns an article from the store based on its GUID if it exists. Returns an error otherwise.
 func (as *ArticleStore) Get(ID string) (*types.Article, error) {
 	if ID == "" {
@@ -147,13 +527,293 @@ func (as *ArticleStore) Get(ID string) (*types.Article, error) {
 	return as.m[ID], nil
 }
 
-// findArticleCursorIndex returns the slice index for the article that has the cursor as its ID. If
-// it fails to find the article, the second return argument will be false.
-func (as *ArticleStore) findArticleCursorIndex(cursor string) (int, bool) {
+// GetMany returns the articles with the provided IDs in the same order. Articles that don't exist
+// are returned as nil, so positions are kept.
+func (as *ArticleStore) GetMany(IDs []string) ([]*types.Article, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	res := make([]*types.Article, len(IDs))
+	for i, ID := range IDs {
+		res[i] = as.m[ID]
+	}
+	return res, nil
+}
+
+// Evict removes the articles selected by the provided policy, such as MaxIngestAge for expiring
+// articles, returning the number of removed articles. The policy is applied once, outside of article
+// creation, so it receives no created article. Like with the eviction policy of the store, evicted
+// articles leave no tombstone.
+func (as *ArticleStore) Evict(policy EvictionPolicy) int {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.evict(policy, nil)
+}
+
+// remove deletes the articles with the provided IDs from the store. The caller must hold the lock.
+func (as *ArticleStore) remove(IDs ...string) {
+	if len(IDs) == 0 {
+		return
+	}
+	for _, ID := range IDs {
+		delete(as.m, ID)
+		delete(as.versions, ID)
+	}
+	kept := make([]*types.Article, 0, len(as.a))
+	for _, a := range as.a {
+		if _, ok := as.m[a.ID]; ok {
+			kept = append(kept, a)
+		}
+	}
+	as.a = kept
+	ingested := make([]*types.Article, 0, len(kept))
+	for _, a := range as.ingested {
+		if _, ok := as.m[a.ID]; ok {
+			ingested = append(ingested, a)
+		}
+	}
+	as.ingested = ingested
+}
+
+// replace swaps the stored articles with their updated copies, keyed by the stored ones. Stored
+// articles are never modified once stored, since the pointers returned by the store are read without
+// holding the lock, such as when serializing them. The caller must hold the lock.
+func (as *ArticleStore) replace(replaced map[*types.Article]*types.Article) {
+	if len(replaced) == 0 {
+		return
+	}
+	for _, updated := range replaced {
+		as.m[updated.ID] = updated
+	}
+	for i, a := range as.a {
+		if updated, ok := replaced[a]; ok {
+			as.a[i] = updated
+		}
+	}
+	for i, a := range as.ingested {
+		if updated, ok := replaced[a]; ok {
+			as.ingested[i] = updated
+		}
+	}
+}
+
+// Reindex sorts the articles by publish date again, keeping articles with the same publish date in
+// ingestion order, or by ID when the ID tie-break is enabled, and rebuilds the index by ID,
+// repairing them if they were left out of order.
+// Returns the number of articles that were out of place.
+func (as *ArticleStore) Reindex() (int, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	a := append([]*types.Article(nil), as.ingested...)
+	sort.SliceStable(a, func(i, j int) bool {
+		return as.before(a[i], a[j])
+	})
+	moved := 0
+	order := walEntry{Op: walOrder, IDs: make([]string, 0, len(a))}
+	for i, article := range a {
+		if i >= len(as.a) || as.a[i] != article {
+			moved++
+		}
+		order.IDs = append(order.IDs, article.ID)
+	}
+	if err := as.appendWAL(order); err != nil {
+		return 0, err
+	}
+	as.a = a
+	as.m = make(map[string]*types.Article, len(a))
+	for _, article := range a {
+		as.m[article.ID] = article
+	}
+	return moved, nil
+}
+
+// MoveFeed reassigns all articles of the source feed to the target feed, taking its ID and provider.
+// Returns the number of moved articles.
+func (as *ArticleStore) MoveFeed(sourceID string, target *types.Feed) (int, error) {
+	if target == nil {
+		return 0, errors.New("invalid target feed provided")
+	}
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	replaced := map[*types.Article]*types.Article{}
+	// Articles moved before a failure are kept moved, as their update is already logged.
+	defer as.replace(replaced)
+	for _, a := range as.a {
+		if a.FeedID != sourceID {
+			continue
+		}
+		updated := *a
+		updated.FeedID = target.ID
+		updated.Provider = target.Provider
+		if err := as.appendWAL(walEntry{Op: walUpdate, Article: &updated}); err != nil {
+			return len(replaced), err
+		}
+		replaced[a] = &updated
+	}
+	return len(replaced), nil
+}
+
+// UpdateFullText sets the full text of the article with the provided ID, such as when it is fetched
+// from the article link, along with the canonical link declared by its page, and returns the updated
+// article. An empty canonical link keeps the one already stored. Articles whose full text was cut are
+// flagged as truncated, while the others keep their flag, since their content may have been cut.
+func (as *ArticleStore) UpdateFullText(ID string, fullText string, canonicalLink string, truncated bool) (*types.Article, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	existing, ok := as.m[ID]
+	if !ok {
+		return nil, errors.New("resource not found")
+	}
+	updated := *existing
+	updated.FullText = fullText
+	updated.Truncated = updated.Truncated || truncated
+	if canonicalLink != "" {
+		updated.CanonicalLink = canonicalLink
+	}
+	if err := as.appendWAL(walEntry{Op: walUpdate, Article: &updated}); err != nil {
+		return nil, err
+	}
+	as.replace(map[*types.Article]*types.Article{existing: &updated})
+	return &updated, nil
+}
+
+// dayFormat is the format of the days counted by CountByDay.
+const dayFormat = "2006-01-02"
+
+// CountByDay returns the number of articles matching the filter for each UTC day, keyed as
+// YYYY-MM-DD, counting articles published from the from time, inclusive, until the to time,
+// exclusive. Zero times leave the range unbounded. Days without articles are not included.
+func (as *ArticleStore) CountByDay(from, to time.Time, filter types.ArticleFilter) (map[string]int, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	matcher, err := as.newArticleMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, a := range as.a {
+		if !from.IsZero() && a.PublishDate.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !a.PublishDate.Before(to) {
+			// Articles are ordered by publish date, so the rest are out of range too.
+			break
+		}
+		if matcher.match(a) {
+			counts[a.PublishDate.UTC().Format(dayFormat)]++
+		}
+	}
+	return counts, nil
+}
+
+// TrendingCategories returns up to limit categories having the most articles matching the filter
+// among the ones published within the provided window until now, with their counts. Categories are
+// ordered by count, highest first, and by name when tied. Zero limit returns all categories.
+func (as *ArticleStore) TrendingCategories(window time.Duration, limit int, filter types.ArticleFilter) ([]*types.CategoryCount, error) {
+	from := as.clock.Now().Add(-window)
+	as.mu.RLock()
+	matcher, err := as.newArticleMatcher(filter)
+	if err != nil {
+		as.mu.RUnlock()
+		return nil, err
+	}
+	counts := map[string]int{}
+	// Articles are ordered by publish date, so only the most recent ones are visited.
+	for i := len(as.a) - 1; i >= 0 && !as.a[i].PublishDate.Before(from); i-- {
+		if matcher.match(as.a[i]) {
+			for _, c := range as.a[i].Categories {
+				counts[c]++
+			}
+		}
+	}
+	as.mu.RUnlock()
+	res := make([]*types.CategoryCount, 0, len(counts))
+	for category, count := range counts {
+		res = append(res, &types.CategoryCount{Category: category, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Category < res[j].Category
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// UnreadCountsByCategory returns the number of unread articles for each category. Articles without
+// any category are counted under the "uncategorized" bucket.
+func (as *ArticleStore) UnreadCountsByCategory() map[string]int {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	counts := map[string]int{}
+	for _, a := range as.a {
+		if a.Read {
+			continue
+		}
+		if len(a.Categories) == 0 {
+			counts[uncategorized]++
+			continue
+		}
+		for _, c := range a.Categories {
+			counts[c]++
+		}
+	}
+	return counts
+}
+
+// FeedsForCategory returns the distinct IDs of the feeds having articles in the provided category,
+// which is normalized like the stored ones, in ascending order. Articles without a feed are ignored.
+func (as *ArticleStore) FeedsForCategory(category string) []string {
+	category = as.categoryNormalization.normalize(category)
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	seen := map[string]struct{}{}
+	feeds := []string{}
+	for _, a := range as.a {
+		if _, ok := seen[a.FeedID]; ok || a.FeedID == "" {
+			continue
+		}
+		for _, c := range a.Categories {
+			if c == category {
+				seen[a.FeedID] = struct{}{}
+				feeds = append(feeds, a.FeedID)
+				break
+			}
+		}
+	}
+	sort.Strings(feeds)
+	return feeds
+}
+
+// newArticleMatcher returns a matcher for the provided filter, normalizing its categories like the
+// stored ones. Returns an error if the filter holds an invalid snapshot token. The caller must hold
+// the lock for as long as the matcher is used, since snapshots read the versions of the store.
+func (as *ArticleStore) newArticleMatcher(filter types.ArticleFilter) (*articleMatcher, error) {
+	filter.Categories = as.categoryNormalization.normalizeAll(filter.Categories)
+	m := newArticleMatcher(filter)
+	if filter.HideFuture {
+		m.now = as.clock.Now()
+	}
+	if filter.Snapshot != "" {
+		version, err := strconv.ParseUint(filter.Snapshot, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid snapshot provided")
+		}
+		m.snapshot = version
+		m.versions = as.versions
+	}
+	return m, nil
+}
+
+// findArticleCursorIndex returns the index following the article that has the cursor as its ID in
+// the provided slice. If it fails to find the article, the second return argument will be false.
+func findArticleCursorIndex(articles []*types.Article, cursor string) (int, bool) {
 	if cursor == "" {
 		return 0, true
 	}
-	for i, a := range as.a {
+	for i, a := range articles {
 		if a.ID == cursor {
 			return i + 1, true
 		}
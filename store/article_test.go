@@ -1,12 +1,18 @@
 package store
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"../clock"
 	"../types"
 )
 
@@ -443,7 +449,9 @@ func TestArticleStoreReset(t *testing.T) {
 		r.NoError(err)
 		a.Equal("dbefb2be-dfe0-5513-b23a-cc04c551221e", article.ID)
 
-		store.Reset()
+		cleared, err := store.Reset()
+		r.NoError(err)
+		a.Equal(1, cleared)
 
 		articles, err := store.List("", 2, "")
 		r.NoError(err)
@@ -454,4 +462,1132 @@ func TestArticleStoreReset(t *testing.T) {
 		a.Contains(err.Error(), "resource not found")
 		a.Nil(art)
 	})
+
+	t.Run("cleared articles are not recovered from the write-ahead log", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		path := filepath.Join(t.TempDir(), "articles.wal")
+		store := NewArticleStore()
+		r.NoError(store.OpenWAL(path))
+		_, err := store.Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		_, err = store.Reset()
+		r.NoError(err)
+
+		recovered := NewArticleStore()
+		r.NoError(recovered.OpenWAL(path))
+		defer recovered.Close()
+		articles, err := recovered.List("", 0, "")
+		r.NoError(err)
+		a.Empty(articles)
+	})
+}
+
+func TestArticleStoreMarkRead(t *testing.T) {
+	t.Run("errors if ID not found", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		article, err := store.MarkRead("invalid_id", true)
+		r.Nil(article)
+		r.Error(err)
+		a.Contains(err.Error(), "resource not found")
+	})
+
+	t.Run("sets and clears the read state", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		created, err := store.Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+
+		article, err := store.MarkRead(created.ID, true)
+		r.NoError(err)
+		a.True(article.Read)
+
+		article, err = store.MarkRead(created.ID, false)
+		r.NoError(err)
+		a.False(article.Read)
+	})
+}
+
+func TestArticleStoreUnreadCountsByCategory(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	a := assert.New(t)
+
+	articles := []*types.Article{
+		&types.Article{GUID: "first", Categories: []string{"cat_1", "cat_2"}},
+		&types.Article{GUID: "second", Categories: []string{"cat_1"}},
+		&types.Article{GUID: "third", Categories: []string{"cat_2"}},
+		&types.Article{GUID: "fourth"},
+		&types.Article{GUID: "fifth"},
+	}
+	for _, article := range articles {
+		_, err := store.Create(article)
+		r.NoError(err)
+	}
+	a.Equal(map[string]int{"cat_1": 2, "cat_2": 2, uncategorized: 2}, store.UnreadCountsByCategory())
+
+	_, err := store.MarkRead(articles[0].ID, true)
+	r.NoError(err)
+	_, err = store.MarkRead(articles[3].ID, true)
+	r.NoError(err)
+	a.Equal(map[string]int{"cat_1": 1, "cat_2": 1, uncategorized: 1}, store.UnreadCountsByCategory())
+
+	_, err = store.MarkRead(articles[1].ID, true)
+	r.NoError(err)
+	a.Equal(map[string]int{"cat_2": 1, uncategorized: 1}, store.UnreadCountsByCategory())
+}
+
+func TestArticleStoreIngestedAt(t *testing.T) {
+	t.Run("stamps articles with the store clock", func(t *testing.T) {
+		fakeClock := clock.NewFake(time.Unix(100, 0).UTC())
+		store := NewArticleStore(WithClock(fakeClock))
+		r := require.New(t)
+		a := assert.New(t)
+
+		first, err := store.Create(&types.Article{GUID: "first"})
+		r.NoError(err)
+		fakeClock.Advance(time.Minute)
+		second, err := store.Create(&types.Article{GUID: "second"})
+		r.NoError(err)
+
+		a.Equal(time.Unix(100, 0).UTC(), first.IngestedAt)
+		a.Equal(time.Unix(160, 0).UTC(), second.IngestedAt)
+	})
+
+	t.Run("existent article keeps its ingestion time", func(t *testing.T) {
+		fakeClock := clock.NewFake(time.Unix(100, 0).UTC())
+		store := NewArticleStore(WithClock(fakeClock))
+		r := require.New(t)
+		a := assert.New(t)
+
+		_, err := store.Create(&types.Article{GUID: "first"})
+		r.NoError(err)
+		fakeClock.Advance(time.Minute)
+		article, err := store.Create(&types.Article{GUID: "first"})
+		r.NoError(err)
+		a.Equal(time.Unix(100, 0).UTC(), article.IngestedAt)
+	})
+}
+
+func TestArticleStoreEvictIngestAge(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(0, 0).UTC())
+	store := NewArticleStore(WithClock(fakeClock))
+	r := require.New(t)
+	a := assert.New(t)
+
+	_, err := store.Create(&types.Article{GUID: "first", PublishDate: time.Unix(0, 1).UTC()})
+	r.NoError(err)
+	fakeClock.Advance(time.Hour)
+	_, err = store.Create(&types.Article{GUID: "second", PublishDate: time.Unix(0, 2).UTC()})
+	r.NoError(err)
+	fakeClock.Advance(time.Hour)
+	_, err = store.Create(&types.Article{GUID: "third", PublishDate: time.Unix(0, 3).UTC()})
+	r.NoError(err)
+
+	// Nothing was ingested more than three hours ago.
+	a.Equal(0, store.Evict(MaxIngestAge(3*time.Hour)))
+
+	fakeClock.Advance(30 * time.Minute)
+	a.Equal(2, store.Evict(MaxIngestAge(time.Hour)))
+
+	articles, err := store.List("", 0, "")
+	r.NoError(err)
+	r.Len(articles, 1, "unexpected number of articles")
+	a.Equal("third", articles[0].GUID)
+
+	_, err = store.Get("461b4f1d-0d71-5a3c-96e8-a2654b90d1ea")
+	r.Error(err)
+	a.Contains(err.Error(), "resource not found")
+}
+
+func TestArticleStoreCursors(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	for i, article := range []*types.Article{
+		&types.Article{FeedID: "feed_id", GUID: "first", Categories: []string{"cat_1"}},
+		&types.Article{FeedID: "feed_id2", GUID: "second", Categories: []string{"cat_2"}},
+		&types.Article{FeedID: "feed_id", GUID: "third", Categories: []string{"cat_2"}},
+		&types.Article{FeedID: "feed_id2", GUID: "fourth", Categories: []string{"cat_1"}},
+	} {
+		article.PublishDate = time.Unix(0, int64(i+1)).UTC()
+		_, err := store.Create(article)
+		r.NoError(err)
+	}
+
+	cursors := func(filter types.ArticleFilter) (string, string) {
+		first, err := store.FirstCursor(filter)
+		r.NoError(err)
+		last, err := store.LastCursor(filter)
+		r.NoError(err)
+		return first, last
+	}
+
+	t.Run("bound the whole set without filter", func(t *testing.T) {
+		a := assert.New(t)
+		first, last := cursors(types.ArticleFilter{})
+		a.Equal(store.a[0].ID, first)
+		a.Equal(store.a[3].ID, last)
+		article, err := store.Get(first)
+		r.NoError(err)
+		a.Equal("first", article.GUID)
+	})
+
+	t.Run("bound the set filtered by category", func(t *testing.T) {
+		a := assert.New(t)
+		first, last := cursors(types.ArticleFilter{Categories: []string{"cat_2"}})
+		a.Equal(store.a[1].ID, first)
+		a.Equal(store.a[2].ID, last)
+	})
+
+	t.Run("bound the set filtered by feed and category", func(t *testing.T) {
+		a := assert.New(t)
+		first, last := cursors(types.ArticleFilter{Feed: "feed_id2", Categories: []string{"cat_1"}})
+		a.Equal(store.a[3].ID, first)
+		a.Equal(store.a[3].ID, last)
+	})
+
+	t.Run("empty when no articles match", func(t *testing.T) {
+		a := assert.New(t)
+		first, last := cursors(types.ArticleFilter{Categories: []string{"cat_invalid"}})
+		a.Empty(first)
+		a.Empty(last)
+	})
+}
+
+func TestArticleStoreUpsert(t *testing.T) {
+	t.Run("nil article returns nil and no error", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+
+		article, diff, err := store.Upsert(nil)
+		r.NoError(err)
+		a.Nil(article)
+		a.Nil(diff)
+	})
+
+	t.Run("creates new articles", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+
+		article, diff, err := store.Upsert(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		a.True(diff.Created)
+		a.False(diff.Changed())
+		a.Equal("dbefb2be-dfe0-5513-b23a-cc04c551221e", article.ID)
+		articles, err := store.List("", 0, "")
+		r.NoError(err)
+		a.Len(articles, 1, "unexpected number of articles")
+	})
+
+	t.Run("updates mutable fields keeping ID and position", func(t *testing.T) {
+		store := NewArticleStore(WithCompareFields(CompareTitle, CompareDescription, CompareContent, CompareCategories))
+		r := require.New(t)
+		a := assert.New(t)
+
+		for i, guid := range []string{"first", "second", "third"} {
+			_, err := store.Create(&types.Article{
+				GUID:        guid,
+				Title:       "title",
+				Link:        "link",
+				PublishDate: time.Unix(0, int64(i+1)).UTC(),
+				Categories:  []string{"cat_1"},
+			})
+			r.NoError(err)
+		}
+
+		article, diff, err := store.Upsert(&types.Article{
+			GUID:        "second",
+			Title:       "new_title",
+			Link:        "new_link",
+			Description: "new_description",
+			Content:     "new_content",
+			PublishDate: time.Unix(0, 10).UTC(),
+			Categories:  []string{"cat_2"},
+		})
+		r.NoError(err)
+		a.False(diff.Created)
+		a.Equal([]string{"Title", "Description", "Content", "Categories"}, diff.Fields)
+		a.Equal("461b4f1d-0d71-5a3c-96e8-a2654b90d1ea", article.ID)
+		a.Equal("new_title", article.Title)
+		a.Equal("new_description", article.Description)
+		a.Equal("new_content", article.Content)
+		a.Equal([]string{"cat_2"}, article.Categories)
+		// Immutable fields are kept.
+		a.Equal("link", article.Link)
+		a.Equal(time.Unix(0, 2).UTC(), article.PublishDate)
+
+		articles, err := store.List("", 0, "")
+		r.NoError(err)
+		r.Len(articles, 3, "unexpected number of articles")
+		a.Equal("first", articles[0].GUID)
+		a.Equal("second", articles[1].GUID)
+		a.Equal("new_title", articles[1].Title)
+		a.Equal("third", articles[2].GUID)
+	})
+}
+
+func TestArticleStoreUpsertDiff(t *testing.T) {
+	t.Run("reports a title change", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, _, err := store.Upsert(&types.Article{GUID: "guid", Title: "title", Categories: []string{"cat_1"}})
+		r.NoError(err)
+
+		_, diff, err := store.Upsert(&types.Article{GUID: "guid", Title: "new_title", Categories: []string{"cat_1"}})
+		r.NoError(err)
+		a.False(diff.Created)
+		a.True(diff.Changed())
+		a.Equal([]string{"Title"}, diff.Fields)
+	})
+
+	t.Run("reports no changes for an identical article", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, _, err := store.Upsert(&types.Article{GUID: "guid", Title: "title", Categories: []string{"cat_1"}})
+		r.NoError(err)
+
+		_, diff, err := store.Upsert(&types.Article{GUID: "guid", Title: "title", Categories: []string{"cat_1"}})
+		r.NoError(err)
+		a.False(diff.Created)
+		a.False(diff.Changed())
+		a.Empty(diff.Fields)
+	})
+
+	t.Run("ignores category changes by default", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, _, err := store.Upsert(&types.Article{GUID: "guid", Title: "title", Categories: []string{"cat_1"}})
+		r.NoError(err)
+
+		article, diff, err := store.Upsert(&types.Article{GUID: "guid", Title: "title", Categories: []string{"cat_2"}})
+		r.NoError(err)
+		a.False(diff.Changed())
+		a.Empty(diff.Fields)
+		a.Equal([]string{"cat_1"}, article.Categories, "ignored changes must not be stored")
+	})
+
+	t.Run("ignores changes to fields not compared", func(t *testing.T) {
+		store := NewArticleStore(WithCompareFields(CompareTitle))
+		r := require.New(t)
+		a := assert.New(t)
+		_, _, err := store.Upsert(&types.Article{GUID: "guid", Title: "title", Content: "content"})
+		r.NoError(err)
+
+		article, diff, err := store.Upsert(&types.Article{GUID: "guid", Title: "title", Content: "new_content"})
+		r.NoError(err)
+		a.False(diff.Changed())
+		a.Equal("content", article.Content, "ignored changes must not be stored")
+	})
+
+	t.Run("stores ignored fields along with a compared change", func(t *testing.T) {
+		store := NewArticleStore(WithCompareFields(CompareTitle))
+		r := require.New(t)
+		a := assert.New(t)
+		_, _, err := store.Upsert(&types.Article{GUID: "guid", Title: "title", Content: "content"})
+		r.NoError(err)
+
+		article, diff, err := store.Upsert(&types.Article{GUID: "guid", Title: "new_title", Content: "new_content"})
+		r.NoError(err)
+		a.Equal([]string{"Title"}, diff.Fields)
+		a.Equal("new_content", article.Content)
+	})
+}
+
+func TestArticleStoreListByIngestion(t *testing.T) {
+	t.Run("late arrivals are returned after the cursor", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		for i := 1; i <= 3; i++ {
+			_, err := store.Create(&types.Article{
+				GUID:        fmt.Sprintf("guid_%d", i),
+				PublishDate: time.Unix(int64(i*10), 0).UTC(),
+			})
+			r.NoError(err)
+		}
+
+		page, err := store.ListByIngestion("", 2, "")
+		r.NoError(err)
+		r.Len(page, 2, "unexpected number of articles")
+		cursor := page[1].ID
+
+		// An old-dated article arrives after pagination started.
+		_, err = store.Create(&types.Article{GUID: "late", PublishDate: time.Unix(5, 0).UTC()})
+		r.NoError(err)
+
+		page, err = store.List(cursor, 2, "")
+		r.NoError(err)
+		r.Len(page, 1, "unexpected number of articles")
+		a.Equal("guid_3", page[0].GUID, "late arrival is missed when ordering by publish date")
+
+		page, err = store.ListByIngestion(cursor, 2, "")
+		r.NoError(err)
+		r.Len(page, 2, "unexpected number of articles")
+		a.Equal("guid_3", page[0].GUID)
+		a.Equal("late", page[1].GUID)
+	})
+
+	t.Run("applies filters", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := store.Create(&types.Article{GUID: "first", FeedID: "feed_1", Categories: []string{"cat_1"}})
+		r.NoError(err)
+		_, err = store.Create(&types.Article{GUID: "second", FeedID: "feed_2", Categories: []string{"cat_1"}})
+		r.NoError(err)
+		_, err = store.Create(&types.Article{GUID: "third", FeedID: "feed_2", Categories: []string{"cat_2"}})
+		r.NoError(err)
+
+		articles, err := store.ListByIngestion("", 0, "feed_2", "cat_1")
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("second", articles[0].GUID)
+	})
+
+	t.Run("error for unknown cursor", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		_, err := store.ListByIngestion("unknown", 0, "")
+		r.Error(err)
+	})
+
+	t.Run("expired articles are removed", func(t *testing.T) {
+		c := clock.NewFake(time.Unix(0, 0))
+		store := NewArticleStore(WithClock(c))
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := store.Create(&types.Article{GUID: "old"})
+		r.NoError(err)
+		c.Advance(time.Hour)
+		_, err = store.Create(&types.Article{GUID: "new"})
+		r.NoError(err)
+
+		a.Equal(1, store.Evict(MaxIngestAge(30*time.Minute)))
+		articles, err := store.ListByIngestion("", 0, "")
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("new", articles[0].GUID)
+	})
+}
+
+func TestArticleStoreGetMany(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	a := assert.New(t)
+	first, err := store.Create(&types.Article{GUID: "first"})
+	r.NoError(err)
+	second, err := store.Create(&types.Article{GUID: "second"})
+	r.NoError(err)
+
+	articles, err := store.GetMany([]string{second.ID, "missing", first.ID, ""})
+	r.NoError(err)
+	r.Len(articles, 4, "unexpected number of articles")
+	a.Equal(second, articles[0])
+	a.Nil(articles[1])
+	a.Equal(first, articles[2])
+	a.Nil(articles[3])
+
+	articles, err = store.GetMany(nil)
+	r.NoError(err)
+	a.Empty(articles)
+}
+
+func TestArticleStoreListEnclosureType(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	a := assert.New(t)
+	image, err := store.Create(&types.Article{
+		GUID:        "image",
+		PublishDate: time.Now().Add(-3 * time.Hour),
+		Enclosures:  []*types.Enclosure{{URL: "http://localhost/image.jpg", Type: "image/jpeg"}},
+	})
+	r.NoError(err)
+	audio, err := store.Create(&types.Article{
+		GUID:        "audio",
+		PublishDate: time.Now().Add(-2 * time.Hour),
+		Enclosures: []*types.Enclosure{
+			{URL: "http://localhost/file.pdf", Type: "application/pdf"},
+			{URL: "http://localhost/audio.mp3", Type: "Audio/MPEG"},
+		},
+	})
+	r.NoError(err)
+	_, err = store.Create(&types.Article{
+		GUID:        "none",
+		PublishDate: time.Now().Add(-time.Hour),
+	})
+	r.NoError(err)
+
+	tests := []struct {
+		enclosureType string
+		expected      []*types.Article
+	}{
+		{"image/", []*types.Article{image}},
+		{"audio/", []*types.Article{audio}},
+		{"audio/mpeg", []*types.Article{audio}},
+		{"video/", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.enclosureType, func(t *testing.T) {
+			articles, err := store.ListFiltered("", 0, types.ArticleFilter{EnclosureType: tt.enclosureType}, types.OrderPublished)
+			r.NoError(err)
+			a.Equal(tt.expected, articles)
+		})
+	}
+
+	t.Run("empty type applies no filtering", func(t *testing.T) {
+		articles, err := store.ListFiltered("", 0, types.ArticleFilter{}, types.OrderPublished)
+		r.NoError(err)
+		a.Len(articles, 3, "unexpected number of articles")
+	})
+}
+
+func TestArticleStoreSnapshot(t *testing.T) {
+	t.Run("pages only return articles present at snapshot time", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		for i := 0; i < 4; i++ {
+			_, err := store.Create(&types.Article{GUID: fmt.Sprintf("guid_%d", i), PublishDate: time.Unix(int64(10*i), 0)})
+			r.NoError(err)
+		}
+		filter := types.ArticleFilter{Snapshot: store.Snapshot()}
+		page, err := store.ListFiltered("", 2, filter, types.OrderPublished)
+		r.NoError(err)
+		a.Equal([]string{"guid_0", "guid_1"}, []string{page[0].GUID, page[1].GUID})
+
+		// An article is created between pages, concurrently with the client paginating.
+		created := make(chan struct{})
+		go func() {
+			defer close(created)
+			_, err := store.Create(&types.Article{GUID: "new_guid", PublishDate: time.Unix(15, 0)})
+			a.NoError(err)
+		}()
+		<-created
+
+		page, err = store.ListFiltered(page[1].ID, 2, filter, types.OrderPublished)
+		r.NoError(err)
+		r.Len(page, 2, "unexpected number of articles")
+		a.Equal([]string{"guid_2", "guid_3"}, []string{page[0].GUID, page[1].GUID})
+
+		articles, err := store.ListFiltered("", 0, types.ArticleFilter{Snapshot: store.Snapshot()}, types.OrderPublished)
+		r.NoError(err)
+		a.Len(articles, 5, "a fresh snapshot must return the new article")
+	})
+
+	t.Run("errors for an invalid snapshot", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := store.ListFiltered("", 0, types.ArticleFilter{Snapshot: "invalid"}, types.OrderPublished)
+		r.Error(err)
+		a.Contains(err.Error(), "invalid snapshot")
+	})
+
+	t.Run("listing a snapshot does not race with resets", func(t *testing.T) {
+		// Only meaningful with the race detector enabled.
+		store := NewArticleStore()
+		filter := types.ArticleFilter{Snapshot: store.Snapshot()}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				store.Create(&types.Article{GUID: fmt.Sprintf("guid_%d", i)})
+				store.Reset()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				store.ListFiltered("", 0, filter, types.OrderPublished)
+			}
+		}()
+		wg.Wait()
+	})
+}
+
+func TestArticleStoreGroupByProvider(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	a := assert.New(t)
+	for i, provider := range []string{"BBC", "CNN", "BBC", "", "BBC", "CNN"} {
+		_, err := store.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			Provider:    provider,
+			PublishDate: time.Unix(int64(10*i), 0),
+			Categories:  []string{fmt.Sprintf("cat_%d", i%2)},
+		})
+		r.NoError(err)
+	}
+
+	t.Run("groups are ordered newest first and capped", func(t *testing.T) {
+		groups, err := store.GroupByProvider(2, types.ArticleFilter{})
+		r.NoError(err)
+		r.Len(groups, 3, "unexpected number of groups")
+		a.Equal([]string{"guid_4", "guid_2"}, guids(groups["BBC"]))
+		a.Equal([]string{"guid_5", "guid_1"}, guids(groups["CNN"]))
+		a.Equal([]string{"guid_3"}, guids(groups[unknownProvider]))
+	})
+
+	t.Run("zero page size returns all articles", func(t *testing.T) {
+		groups, err := store.GroupByProvider(0, types.ArticleFilter{})
+		r.NoError(err)
+		a.Equal([]string{"guid_4", "guid_2", "guid_0"}, guids(groups["BBC"]))
+	})
+
+	t.Run("applies filters", func(t *testing.T) {
+		groups, err := store.GroupByProvider(0, types.ArticleFilter{Categories: []string{"cat_1"}})
+		r.NoError(err)
+		r.Len(groups, 2, "unexpected number of groups")
+		a.Equal([]string{"guid_5", "guid_1"}, guids(groups["CNN"]))
+		a.Equal([]string{"guid_3"}, guids(groups[unknownProvider]))
+	})
+}
+
+func TestArticleStoreListSorted(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	for _, article := range []*types.Article{
+		{GUID: "b", Title: "banana", PublishDate: time.Unix(30, 0)},
+		{GUID: "a", Title: "Apple", PublishDate: time.Unix(20, 0)},
+		{GUID: "c", Title: "cherry", PublishDate: time.Unix(10, 0)},
+		{GUID: "B", Title: "Banana", PublishDate: time.Unix(40, 0)},
+	} {
+		_, err := store.Create(article)
+		r.NoError(err)
+	}
+
+	tests := []struct {
+		name     string
+		order    types.ArticleOrder
+		expected []string
+	}{
+		{"publish date", types.OrderPublished, []string{"c", "a", "b", "B"}},
+		{"publish date descending", types.OrderPublished | types.OrderDescending, []string{"B", "b", "a", "c"}},
+		{"ingestion", types.OrderIngested, []string{"b", "a", "c", "B"}},
+		{"ingestion descending", types.OrderIngested | types.OrderDescending, []string{"B", "c", "a", "b"}},
+		// Titles are compared ignoring case, keeping equal titles by publish date.
+		{"title", types.OrderTitle, []string{"a", "b", "B", "c"}},
+		{"title descending", types.OrderTitle | types.OrderDescending, []string{"c", "B", "b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles, err := store.ListFiltered("", 0, types.ArticleFilter{}, tt.order)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, guids(articles))
+		})
+	}
+
+	t.Run("cursor follows the order", func(t *testing.T) {
+		articles, err := store.ListFiltered("", 2, types.ArticleFilter{}, types.OrderTitle)
+		r.NoError(err)
+		articles, err = store.ListFiltered(articles[1].ID, 2, types.ArticleFilter{}, types.OrderTitle)
+		r.NoError(err)
+		assert.Equal(t, []string{"B", "c"}, guids(articles))
+	})
+}
+
+func TestArticleStoreCountByDay(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	a := assert.New(t)
+	day := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, publishDate := range []time.Time{
+		day.Add(time.Hour),
+		day.Add(23 * time.Hour),
+		day.Add(25 * time.Hour),
+		// Publish dates are counted on their UTC day.
+		day.Add(47 * time.Hour).In(time.FixedZone("UTC+3", 3*60*60)),
+		day.Add(49 * time.Hour),
+	} {
+		_, err := store.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			PublishDate: publishDate,
+			Categories:  []string{fmt.Sprintf("cat_%d", i%2)},
+		})
+		r.NoError(err)
+	}
+
+	t.Run("counts all days without bounds", func(t *testing.T) {
+		counts, err := store.CountByDay(time.Time{}, time.Time{}, types.ArticleFilter{})
+		r.NoError(err)
+		a.Equal(map[string]int{"2021-01-01": 2, "2021-01-02": 2, "2021-01-03": 1}, counts)
+	})
+
+	t.Run("counts within the range", func(t *testing.T) {
+		counts, err := store.CountByDay(day.AddDate(0, 0, 1), day.AddDate(0, 0, 2), types.ArticleFilter{})
+		r.NoError(err)
+		a.Equal(map[string]int{"2021-01-02": 2}, counts)
+	})
+
+	t.Run("applies filters", func(t *testing.T) {
+		counts, err := store.CountByDay(time.Time{}, time.Time{}, types.ArticleFilter{Categories: []string{"cat_0"}})
+		r.NoError(err)
+		a.Equal(map[string]int{"2021-01-01": 1, "2021-01-02": 1, "2021-01-03": 1}, counts)
+	})
+}
+
+func TestArticleStoreMoveFeed(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	a := assert.New(t)
+	for i, feedID := range []string{"source", "other", "source"} {
+		_, err := store.Create(&types.Article{GUID: fmt.Sprintf("guid_%d", i), FeedID: feedID, Provider: feedID})
+		r.NoError(err)
+	}
+
+	moved, err := store.MoveFeed("source", &types.Feed{ID: "target", Provider: "target_provider"})
+	r.NoError(err)
+	a.Equal(2, moved)
+	articles, err := store.List("", 0, "target")
+	r.NoError(err)
+	r.Len(articles, 2, "unexpected number of articles")
+	for _, article := range articles {
+		a.Equal("target_provider", article.Provider)
+	}
+	articles, err = store.List("", 0, "source")
+	r.NoError(err)
+	a.Empty(articles)
+	articles, err = store.List("", 0, "other")
+	r.NoError(err)
+	a.Len(articles, 1, "unexpected number of articles")
+}
+
+func TestArticleStoreFeedsForCategory(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	store := NewArticleStore(WithCategoryNormalization(NormalizeLowercase))
+	for i, article := range []*types.Article{
+		{FeedID: "feed_b", Categories: []string{"Sports", "UK"}},
+		{FeedID: "feed_a", Categories: []string{"sports"}},
+		{FeedID: "feed_b", Categories: []string{"sports"}},
+		{FeedID: "feed_c", Categories: []string{"UK"}},
+		{Categories: []string{"sports"}},
+	} {
+		article.GUID = fmt.Sprintf("guid_%d", i)
+		_, err := store.Create(article)
+		r.NoError(err)
+	}
+
+	a.Equal([]string{"feed_a", "feed_b"}, store.FeedsForCategory("SPORTS"))
+	a.Equal([]string{"feed_b", "feed_c"}, store.FeedsForCategory("uk"))
+	a.Empty(store.FeedsForCategory("unknown"))
+}
+
+func TestArticleStoreReindex(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	store := NewArticleStore()
+	for i, seconds := range []int64{30, 10, 20, 15, 40} {
+		_, err := store.Create(&types.Article{GUID: fmt.Sprintf("guid_%d", i), PublishDate: time.Unix(seconds, 0).UTC()})
+		r.NoError(err)
+	}
+	expected := []string{"guid_1", "guid_3", "guid_2", "guid_0", "guid_4"}
+	moved, err := store.Reindex()
+	r.NoError(err)
+	a.Zero(moved, "ordered articles must not be moved")
+
+	// The order is scrambled as a bug could leave it.
+	store.a[0], store.a[3] = store.a[3], store.a[0]
+	store.a[1], store.a[4] = store.a[4], store.a[1]
+	moved, err = store.Reindex()
+	r.NoError(err)
+	a.Equal(4, moved)
+
+	var listed []*types.Article
+	cursor := ""
+	for {
+		page, err := store.List(cursor, 2, "")
+		r.NoError(err)
+		if len(page) == 0 {
+			break
+		}
+		listed = append(listed, page...)
+		cursor = page[len(page)-1].ID
+	}
+	a.Equal(expected, guids(listed))
+}
+
+func TestArticleStoreListHasFullText(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	store := NewArticleStore()
+	for i, article := range []*types.Article{
+		{FeedID: "feed_1", FullText: "full text"},
+		{FeedID: "feed_1"},
+		{FeedID: "feed_1", FullText: " \n "},
+		{FeedID: "feed_2", FullText: "other full text"},
+	} {
+		article.GUID = fmt.Sprintf("guid_%d", i)
+		article.PublishDate = time.Unix(int64(i), 0).UTC()
+		_, err := store.Create(article)
+		r.NoError(err)
+	}
+
+	articles, err := store.ListFiltered("", 0, types.ArticleFilter{HasFullText: true}, types.OrderPublished)
+	r.NoError(err)
+	a.Equal([]string{"guid_0", "guid_3"}, guids(articles))
+	articles, err = store.ListFiltered("", 0, types.ArticleFilter{HasFullText: true, Feed: "feed_1"}, types.OrderPublished)
+	r.NoError(err)
+	a.Equal([]string{"guid_0"}, guids(articles))
+	articles, err = store.ListFiltered("", 0, types.ArticleFilter{}, types.OrderPublished)
+	r.NoError(err)
+	a.Len(articles, 4, "unexpected number of articles")
+}
+
+func TestArticleStoreForEach(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	store := NewArticleStore()
+	for i := 1; i <= 4; i++ {
+		enclosures := make([]*types.Enclosure, i)
+		_, err := store.Create(&types.Article{GUID: fmt.Sprintf("guid_%d", i), Enclosures: enclosures})
+		r.NoError(err)
+	}
+
+	total := 0
+	r.NoError(store.ForEach(func(article *types.Article) error {
+		total += len(article.Enclosures)
+		return nil
+	}))
+	a.Equal(10, total)
+
+	visited := 0
+	err := store.ForEach(func(article *types.Article) error {
+		visited++
+		if visited == 2 {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	a.EqualError(err, "stop")
+	a.Equal(2, visited, "iteration must stop on the first error")
+}
+
+func TestArticleStoreTrendingCategories(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	now := time.Unix(100000, 0).UTC()
+	store := NewArticleStore(WithClock(clock.NewFake(now)))
+	for i, article := range []*types.Article{
+		// Old articles are outside the window, whatever their categories.
+		{Categories: []string{"old"}, PublishDate: now.Add(-48 * time.Hour)},
+		{Categories: []string{"old"}, PublishDate: now.Add(-47 * time.Hour)},
+		{Categories: []string{"old", "sports"}, PublishDate: now.Add(-25 * time.Hour)},
+		{Categories: []string{"sports", "uk"}, PublishDate: now.Add(-3 * time.Hour)},
+		{Categories: []string{"sports"}, PublishDate: now.Add(-2 * time.Hour), FeedID: "feed"},
+		{Categories: []string{"sports", "world"}, PublishDate: now.Add(-time.Hour)},
+		{Categories: []string{"uk"}, PublishDate: now.Add(-time.Hour), FeedID: "feed"},
+		{Categories: []string{"world"}, PublishDate: now},
+		{Categories: []string{"business"}, PublishDate: now},
+	} {
+		article.GUID = fmt.Sprintf("guid_%d", i)
+		_, err := store.Create(article)
+		r.NoError(err)
+	}
+
+	categories, err := store.TrendingCategories(24*time.Hour, 3, types.ArticleFilter{})
+	r.NoError(err)
+	a.Equal([]*types.CategoryCount{
+		{Category: "sports", Count: 3},
+		{Category: "uk", Count: 2},
+		{Category: "world", Count: 2},
+	}, categories)
+
+	categories, err = store.TrendingCategories(72*time.Hour, 1, types.ArticleFilter{})
+	r.NoError(err)
+	a.Equal([]*types.CategoryCount{{Category: "sports", Count: 4}}, categories)
+
+	categories, err = store.TrendingCategories(24*time.Hour, 0, types.ArticleFilter{Feed: "feed"})
+	r.NoError(err)
+	a.Equal([]*types.CategoryCount{{Category: "sports", Count: 1}, {Category: "uk", Count: 1}}, categories)
+}
+
+func TestArticleStoreUpdateFullText(t *testing.T) {
+	t.Run("errors if ID not found", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		_, err := store.UpdateFullText("invalid_id", "text", "", false)
+		r.Error(err)
+	})
+
+	t.Run("sets the full text", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		article, err := store.Create(&types.Article{GUID: "guid"})
+		r.NoError(err)
+
+		_, err = store.UpdateFullText(article.ID, "text", "", false)
+		r.NoError(err)
+		article, err = store.Get(article.ID)
+		r.NoError(err)
+		a.Equal("text", article.FullText)
+	})
+
+	t.Run("sets the canonical link", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		article, err := store.Create(&types.Article{GUID: "guid"})
+		r.NoError(err)
+
+		_, err = store.UpdateFullText(article.ID, "text", "https://example.com/canonical", false)
+		r.NoError(err)
+		_, err = store.UpdateFullText(article.ID, "other text", "", false)
+		r.NoError(err)
+		article, err = store.Get(article.ID)
+		r.NoError(err)
+		a.Equal("other text", article.FullText)
+		a.Equal("https://example.com/canonical", article.CanonicalLink, "empty canonical links must keep the stored one")
+	})
+
+	t.Run("flags truncated full texts", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		article, err := store.Create(&types.Article{GUID: "guid"})
+		r.NoError(err)
+		truncated, err := store.Create(&types.Article{GUID: "truncated", Truncated: true})
+		r.NoError(err)
+
+		article, err = store.UpdateFullText(article.ID, "text", "", true)
+		r.NoError(err)
+		a.True(article.Truncated)
+		truncated, err = store.UpdateFullText(truncated.ID, "text", "", false)
+		r.NoError(err)
+		a.True(truncated.Truncated, "the flag of truncated content must be kept")
+	})
+}
+
+func TestArticleStoreListRange(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	var ids []string
+	for i := 1; i <= 5; i++ {
+		article, err := store.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			FeedID:      fmt.Sprintf("feed_%d", i%2),
+			PublishDate: time.Unix(int64(i), 0).UTC(),
+		})
+		r.NoError(err)
+		ids = append(ids, article.ID)
+	}
+
+	t.Run("returns the articles strictly between the cursors", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := store.ListRange(ids[0], ids[4], "")
+		r.NoError(err)
+		r.Len(articles, 3, "unexpected number of articles")
+		a.Equal("guid_2", articles[0].GUID)
+		a.Equal("guid_4", articles[2].GUID)
+
+		articles, err = store.ListRange(ids[0], ids[4], "feed_0")
+		r.NoError(err)
+		r.Len(articles, 2, "unexpected number of articles")
+		a.Equal("guid_2", articles[0].GUID)
+		a.Equal("guid_4", articles[1].GUID)
+	})
+
+	t.Run("empty cursors leave the range open", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		articles, err := store.ListRange("", ids[2], "")
+		r.NoError(err)
+		r.Len(articles, 2, "unexpected number of articles")
+		a.Equal("guid_1", articles[0].GUID)
+
+		articles, err = store.ListRange(ids[2], "", "")
+		r.NoError(err)
+		r.Len(articles, 2, "unexpected number of articles")
+		a.Equal("guid_4", articles[0].GUID)
+	})
+
+	t.Run("adjacent cursors return no articles", func(t *testing.T) {
+		r := require.New(t)
+		articles, err := store.ListRange(ids[1], ids[2], "")
+		r.NoError(err)
+		r.Empty(articles)
+	})
+
+	t.Run("errors for an inverted range", func(t *testing.T) {
+		r := require.New(t)
+		_, err := store.ListRange(ids[3], ids[1], "")
+		r.Error(err)
+		_, err = store.ListRange(ids[2], ids[2], "")
+		r.Error(err)
+	})
+
+	t.Run("errors for an unknown cursor", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := store.ListRange("unknown", ids[2], "")
+		r.Error(err)
+		a.Contains(err.Error(), "could not find provided cursor")
+		_, err = store.ListRange(ids[0], "unknown", "")
+		r.Error(err)
+	})
+}
+
+func TestArticleStoreNeighbors(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	var ids []string
+	for i := 1; i <= 5; i++ {
+		article, err := store.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			FeedID:      fmt.Sprintf("feed_%d", i%2),
+			PublishDate: time.Unix(int64(i), 0).UTC(),
+		})
+		r.NoError(err)
+		ids = append(ids, article.ID)
+	}
+
+	t.Run("returns the adjacent articles", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		prev, next, err := store.Neighbors(ids[2], types.ArticleFilter{})
+		r.NoError(err)
+		a.Equal("guid_2", prev.GUID)
+		a.Equal("guid_4", next.GUID)
+	})
+
+	t.Run("returns nil at the ends", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		prev, next, err := store.Neighbors(ids[0], types.ArticleFilter{})
+		r.NoError(err)
+		a.Nil(prev)
+		a.Equal("guid_2", next.GUID)
+
+		prev, next, err = store.Neighbors(ids[4], types.ArticleFilter{})
+		r.NoError(err)
+		a.Equal("guid_4", prev.GUID)
+		a.Nil(next)
+	})
+
+	t.Run("stays within the filter", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		prev, next, err := store.Neighbors(ids[2], types.ArticleFilter{Feed: "feed_1"})
+		r.NoError(err)
+		a.Equal("guid_1", prev.GUID)
+		a.Equal("guid_5", next.GUID)
+
+		prev, next, err = store.Neighbors(ids[1], types.ArticleFilter{Feed: "feed_0"})
+		r.NoError(err)
+		a.Nil(prev)
+		a.Equal("guid_4", next.GUID)
+	})
+
+	t.Run("errors if ID not found", func(t *testing.T) {
+		r := require.New(t)
+		_, _, err := store.Neighbors("invalid_id", types.ArticleFilter{})
+		r.Error(err)
+	})
+}
+
+func TestArticleStoreIDTieBreak(t *testing.T) {
+	date := time.Unix(100, 0).UTC()
+	items := func() []*types.Article {
+		return []*types.Article{
+			{GUID: "guid_c", Title: "c", PublishDate: date},
+			{GUID: "guid_a", Title: "a", PublishDate: date},
+			{GUID: "older", Title: "older", PublishDate: date.Add(-time.Hour)},
+			{GUID: "guid_b", Title: "b", PublishDate: date},
+		}
+	}
+	load := func(r *require.Assertions, store *ArticleStore, articles []*types.Article) []string {
+		for _, article := range articles {
+			_, _, err := store.Upsert(article)
+			r.NoError(err)
+		}
+		listed, err := store.List("", 0, "")
+		r.NoError(err)
+		IDs := []string{}
+		for _, article := range listed {
+			IDs = append(IDs, article.ID)
+		}
+		return IDs
+	}
+	reversed := func(articles []*types.Article) []*types.Article {
+		for i, j := 0, len(articles)-1; i < j; i, j = i+1, j-1 {
+			articles[i], articles[j] = articles[j], articles[i]
+		}
+		return articles
+	}
+
+	t.Run("reloading the same items keeps their order", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(WithIDTieBreak(true))
+		first := load(r, store, items())
+		r.Len(first, 4, "unexpected number of articles")
+		a.True(sort.StringsAreSorted(first[1:]), "articles with the same publish date must be ordered by ID")
+
+		modified := items()
+		modified[0].Title = "changed"
+		a.Equal(first, load(r, store, modified))
+		a.Equal(first, load(r, store, reversed(items())))
+	})
+
+	t.Run("order doesn't depend on the ingestion order", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		a.Equal(
+			load(r, NewArticleStore(WithIDTieBreak(true)), items()),
+			load(r, NewArticleStore(WithIDTieBreak(true)), reversed(items())),
+		)
+	})
+
+	t.Run("reindex keeps the order", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(WithIDTieBreak(true))
+		before := load(r, store, items())
+		moved, err := store.Reindex()
+		r.NoError(err)
+		a.Zero(moved)
+		a.Equal(before, load(r, store, nil))
+	})
+}
+
+func TestArticleStoreLatest(t *testing.T) {
+	store := NewArticleStore()
+	r := require.New(t)
+	for i := 0; i < 5; i++ {
+		_, err := store.Create(&types.Article{
+			GUID:        fmt.Sprintf("guid_%d", i),
+			FeedID:      fmt.Sprintf("feed_%d", i%2),
+			PublishDate: time.Unix(int64(100-i*10), 0).UTC(),
+		})
+		r.NoError(err)
+	}
+
+	for name, tc := range map[string]struct {
+		n        int
+		filter   types.ArticleFilter
+		expected []string
+	}{
+		"newest first":                    {n: 3, expected: []string{"guid_0", "guid_1", "guid_2"}},
+		"fewer when the store is smaller": {n: 10, expected: []string{"guid_0", "guid_1", "guid_2", "guid_3", "guid_4"}},
+		"matching the filter":             {n: 2, filter: types.ArticleFilter{Feed: "feed_0"}, expected: []string{"guid_0", "guid_2"}},
+		"zero returns none":               {n: 0, expected: []string{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			articles, err := store.Latest(tc.n, tc.filter)
+			r.NoError(err)
+			assert.Equal(t, tc.expected, guids(articles))
+		})
+	}
 }
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"time"
+
+	"../types"
+)
+
+// MaxPerCategory returns a policy limiting the number of articles retained in each category to n,
+// so no single category dominates the store. The oldest articles by publish date beyond the cap in
+// any of the categories of the article created are evicted, since the other categories didn't grow.
+// Evicted articles may be stored again if their feed still holds them, only to be evicted once more
+// if they are still among the oldest.
+func MaxPerCategory(n int) EvictionPolicy {
+	return evictionFunc(func(articles []*types.Article, created *types.Article, now time.Time) []string {
+		if created == nil {
+			return nil
+		}
+		evicted := map[string]bool{}
+		var IDs []string
+		for _, category := range created.Categories {
+			count := 0
+			// Articles are ordered by publish date, so the newest ones are counted first.
+			for i := len(articles) - 1; i >= 0; i-- {
+				a := articles[i]
+				if evicted[a.ID] || !containsString(a.Categories, category) {
+					continue
+				}
+				count++
+				if count <= n {
+					continue
+				}
+				evicted[a.ID] = true
+				IDs = append(IDs, a.ID)
+			}
+		}
+		return IDs
+	})
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../types"
+)
+
+func TestMaxPerCategory(t *testing.T) {
+	create := func(r *require.Assertions, store *ArticleStore) {
+		for _, article := range []*types.Article{
+			{GUID: "sports_2", PublishDate: time.Unix(20, 0).UTC(), Categories: []string{"Sports"}},
+			{GUID: "news_1", PublishDate: time.Unix(10, 0).UTC(), Categories: []string{"News"}},
+			{GUID: "sports_1", PublishDate: time.Unix(10, 0).UTC(), Categories: []string{"Sports"}},
+			{GUID: "sports_4", PublishDate: time.Unix(40, 0).UTC(), Categories: []string{"Sports"}},
+			{GUID: "sports_3", PublishDate: time.Unix(30, 0).UTC(), Categories: []string{"Sports", "News"}},
+		} {
+			_, err := store.Create(article)
+			r.NoError(err)
+		}
+	}
+
+	t.Run("oldest articles beyond the cap are evicted", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(WithEvictionPolicy(MaxPerCategory(2)))
+		create(r, store)
+
+		sports, err := store.List("", 0, "", "Sports")
+		r.NoError(err)
+		a.Equal([]string{"sports_3", "sports_4"}, guids(sports))
+		news, err := store.List("", 0, "", "News")
+		r.NoError(err)
+		a.Equal([]string{"news_1", "sports_3"}, guids(news), "other categories must not be affected")
+	})
+
+	t.Run("no policy keeps all articles", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore()
+		create(r, store)
+
+		articles, err := store.List("", 0, "")
+		r.NoError(err)
+		a.Len(articles, 5, "unexpected number of articles")
+	})
+
+	t.Run("articles evicted on creation are not returned as stored", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(WithEvictionPolicy(MaxPerCategory(1)))
+		_, err := store.Create(&types.Article{GUID: "new", PublishDate: time.Unix(20, 0).UTC(), Categories: []string{"News"}})
+		r.NoError(err)
+
+		article, err := store.Create(&types.Article{GUID: "old", PublishDate: time.Unix(10, 0).UTC(), Categories: []string{"News"}})
+		r.NoError(err)
+		a.Nil(article)
+		article, diff, err := store.Upsert(&types.Article{GUID: "old", PublishDate: time.Unix(10, 0).UTC(), Categories: []string{"News"}})
+		r.NoError(err)
+		a.Nil(article)
+		a.Nil(diff)
+	})
+
+	t.Run("evictions are recovered from the write-ahead log", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		path := filepath.Join(t.TempDir(), "articles.wal")
+		store := NewArticleStore(WithEvictionPolicy(MaxPerCategory(2)))
+		r.NoError(store.OpenWAL(path))
+		create(r, store)
+
+		// The first store is not closed, recovering the articles as if the process crashed.
+		recovered := NewArticleStore()
+		r.NoError(recovered.OpenWAL(path))
+		defer recovered.Close()
+		articles, err := recovered.List("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"news_1", "sports_3", "sports_4"}, guids(articles))
+	})
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CategoryNormalization describes how article categories are normalized when stored, collapsing
+// the variations used by different feeds for the same category into a single value.
+type CategoryNormalization int
+
+const (
+	// NormalizeNone keeps categories as provided by the feeds.
+	NormalizeNone CategoryNormalization = iota
+	// NormalizeLowercase trims categories, collapses their inner spaces and lowercases them.
+	NormalizeLowercase
+	// NormalizeSlug turns categories into lowercase slugs, such as "world-news".
+	NormalizeSlug
+)
+
+// WithCategoryNormalization normalizes the categories of the stored articles, keeping the values
+// provided by the feeds as their display categories. Filters are normalized the same way, so any
+// variation of a category matches its articles.
+func WithCategoryNormalization(n CategoryNormalization) ArticleStoreOption {
+	return func(as *ArticleStore) {
+		as.categoryNormalization = n
+	}
+}
+
+// normalize returns the normalized form of a single category.
+func (n CategoryNormalization) normalize(category string) string {
+	switch n {
+	case NormalizeLowercase:
+		return strings.ToLower(strings.Join(strings.Fields(category), " "))
+	case NormalizeSlug:
+		words := strings.FieldsFunc(strings.ToLower(category), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		return strings.Join(words, "-")
+	}
+	return category
+}
+
+// normalizeAll normalizes the provided categories into a set, removing duplicates while keeping the
+// order in which categories first appear. Empty values are removed too unless categories are kept
+// as provided.
+func (n CategoryNormalization) normalizeAll(categories []string) []string {
+	if categories == nil {
+		return nil
+	}
+	res := make([]string, 0, len(categories))
+	seen := make(map[string]struct{}, len(categories))
+	for _, c := range categories {
+		c = n.normalize(c)
+		if _, ok := seen[c]; ok || (c == "" && n != NormalizeNone) {
+			continue
+		}
+		seen[c] = struct{}{}
+		res = append(res, c)
+	}
+	return res
+}
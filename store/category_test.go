@@ -0,0 +1,98 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../types"
+)
+
+func TestCategoryNormalization(t *testing.T) {
+	t.Run("normalizes single categories", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(" Sports  News", NormalizeNone.normalize(" Sports  News"))
+		a.Equal("sports news", NormalizeLowercase.normalize(" Sports  News"))
+		a.Equal("sports-news", NormalizeSlug.normalize(" Sports & News!"))
+		a.Equal("café-olé", NormalizeSlug.normalize("Café Olé"))
+	})
+
+	t.Run("removes duplicates and empty values", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal([]string{"sports", "uk"}, NormalizeLowercase.normalizeAll([]string{"Sports", "sports ", "", "SPORTS", "UK"}))
+		a.Equal([]string{"Sports", "sports"}, NormalizeNone.normalizeAll([]string{"Sports", "sports"}))
+	})
+
+	t.Run("mixed case categories collapse under filtering", func(t *testing.T) {
+		store := NewArticleStore(WithCategoryNormalization(NormalizeLowercase))
+		r := require.New(t)
+		a := assert.New(t)
+		for guid, category := range map[string]string{"first": "Sports", "second": "sports", "third": " SPORTS", "fourth": "UK"} {
+			_, err := store.Create(&types.Article{GUID: guid, Categories: []string{category}})
+			r.NoError(err)
+		}
+
+		articles, err := store.List("", 0, "", "Sports")
+		r.NoError(err)
+		a.Len(articles, 3, "unexpected number of articles")
+		for _, article := range articles {
+			a.Equal([]string{"sports"}, article.Categories)
+		}
+		a.Equal(map[string]int{"sports": 3, "uk": 1}, store.UnreadCountsByCategory())
+
+		article, err := store.Get(articles[0].ID)
+		r.NoError(err)
+		a.Len(article.DisplayCategories, 1, "display categories must be kept")
+	})
+
+	t.Run("categories are kept without normalization", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := store.Create(&types.Article{GUID: "first", Categories: []string{"Sports"}})
+		r.NoError(err)
+		_, err = store.Create(&types.Article{GUID: "second", Categories: []string{"sports"}})
+		r.NoError(err)
+
+		articles, err := store.List("", 0, "", "Sports")
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Nil(articles[0].DisplayCategories)
+	})
+
+	t.Run("upsert compares normalized categories", func(t *testing.T) {
+		store := NewArticleStore(WithCategoryNormalization(NormalizeSlug), WithCompareFields(CompareCategories))
+		r := require.New(t)
+		a := assert.New(t)
+		_, _, err := store.Upsert(&types.Article{GUID: "guid", Categories: []string{"World News"}})
+		r.NoError(err)
+
+		_, diff, err := store.Upsert(&types.Article{GUID: "guid", Categories: []string{"world  news"}})
+		r.NoError(err)
+		a.False(diff.Changed())
+
+		article, diff, err := store.Upsert(&types.Article{GUID: "guid", Categories: []string{"UK News"}})
+		r.NoError(err)
+		a.Equal([]string{"Categories"}, diff.Fields)
+		a.Equal([]string{"uk-news"}, article.Categories)
+		a.Equal([]string{"UK News"}, article.DisplayCategories)
+	})
+	t.Run("upsert keeps categories as a set", func(t *testing.T) {
+		store := NewArticleStore(WithCompareFields(CompareCategories))
+		r := require.New(t)
+		a := assert.New(t)
+		article, _, err := store.Upsert(&types.Article{GUID: "guid", Categories: []string{"world", "uk", "world"}})
+		r.NoError(err)
+		a.Equal([]string{"world", "uk"}, article.Categories)
+
+		article, diff, err := store.Upsert(&types.Article{GUID: "guid", Categories: []string{"uk", "world", "uk", "politics"}})
+		r.NoError(err)
+		a.Equal([]string{"Categories"}, diff.Fields)
+		a.Equal([]string{"uk", "world", "politics"}, article.Categories)
+
+		_, diff, err = store.Upsert(&types.Article{GUID: "guid", Categories: []string{"uk", "uk", "world", "politics", "world"}})
+		r.NoError(err)
+		a.False(diff.Changed(), "duplicates must not be reported as changes")
+	})
+}
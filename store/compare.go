@@ -0,0 +1,46 @@
+package store
+
+// CompareField is a mutable field of the articles that Upsert compares to detect whether an article
+// changed.
+type CompareField string
+
+const (
+	// CompareTitle detects changes in the title of the articles.
+	CompareTitle CompareField = "Title"
+	// CompareDescription detects changes in the description of the articles.
+	CompareDescription CompareField = "Description"
+	// CompareContent detects changes in the content of the articles.
+	CompareContent CompareField = "Content"
+	// CompareCategories detects changes in the categories of the articles.
+	CompareCategories CompareField = "Categories"
+)
+
+// defaultCompareFields are the fields compared when no fields are configured. Categories are left
+// out, since feeds often reorder or retag them without changing the article itself.
+var defaultCompareFields = []CompareField{CompareTitle, CompareDescription, CompareContent}
+
+// WithCompareFields sets the fields Upsert compares to decide whether an article changed, so
+// cosmetic edits to other fields don't cause updates. Edits to fields not compared are only stored
+// along with a change to a compared field. By default, the title, description and content are
+// compared, and categories are compared only when provided.
+func WithCompareFields(fields ...CompareField) ArticleStoreOption {
+	return func(as *ArticleStore) {
+		as.compareFields = map[CompareField]bool{}
+		for _, field := range fields {
+			as.compareFields[field] = true
+		}
+	}
+}
+
+// compares reports whether the provided field is compared to detect changes.
+func (as *ArticleStore) compares(field CompareField) bool {
+	if as.compareFields == nil {
+		for _, f := range defaultCompareFields {
+			if f == field {
+				return true
+			}
+		}
+		return false
+	}
+	return as.compareFields[field]
+}
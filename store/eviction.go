@@ -0,0 +1,134 @@
+package store
+
+import (
+	"time"
+
+	"../types"
+)
+
+// EvictionPolicy decides which articles are evicted from the store after an article is created.
+// Evict receives the stored articles ordered by publish date, oldest first, along with the article
+// just created and the current time, and returns the IDs of the articles to evict. The created
+// article is nil when the policy is applied through ArticleStore.Evict. The articles must not be
+// modified, and the store lock is held while it runs.
+type EvictionPolicy interface {
+	Evict(articles []*types.Article, created *types.Article, now time.Time) []string
+}
+
+// evictionFunc adapts a function into an EvictionPolicy.
+type evictionFunc func(articles []*types.Article, created *types.Article, now time.Time) []string
+
+func (f evictionFunc) Evict(articles []*types.Article, created *types.Article, now time.Time) []string {
+	return f(articles, created, now)
+}
+
+// WithEvictionPolicy sets the policy consulted after each article is created to evict articles from
+// the store. Evicted articles leave no tombstone, and Create returns nil for an article evicted right
+// away. Without a policy, articles are never evicted.
+func WithEvictionPolicy(policy EvictionPolicy) ArticleStoreOption {
+	return func(as *ArticleStore) {
+		as.evictionPolicy = policy
+	}
+}
+
+// MaxAge returns a policy evicting the articles published longer ago than the provided age. Articles
+// older than that are evicted right after being created, so they are not stored.
+func MaxAge(age time.Duration) EvictionPolicy {
+	return evictionFunc(func(articles []*types.Article, created *types.Article, now time.Time) []string {
+		cutoff := now.Add(-age)
+		var IDs []string
+		for _, a := range articles {
+			if !a.PublishDate.Before(cutoff) {
+				break
+			}
+			IDs = append(IDs, a.ID)
+		}
+		return IDs
+	})
+}
+
+// MaxIngestAge returns a policy evicting the articles ingested longer ago than the provided ttl, so
+// articles expire regardless of their publish date.
+func MaxIngestAge(ttl time.Duration) EvictionPolicy {
+	return evictionFunc(func(articles []*types.Article, created *types.Article, now time.Time) []string {
+		cutoff := now.Add(-ttl)
+		var IDs []string
+		for _, a := range articles {
+			if a.IngestedAt.Before(cutoff) {
+				IDs = append(IDs, a.ID)
+			}
+		}
+		return IDs
+	})
+}
+
+// MaxCount returns a policy keeping only the n newest articles by publish date in the store.
+func MaxCount(n int) EvictionPolicy {
+	return evictionFunc(func(articles []*types.Article, created *types.Article, now time.Time) []string {
+		var IDs []string
+		for i := 0; i < len(articles)-n; i++ {
+			IDs = append(IDs, articles[i].ID)
+		}
+		return IDs
+	})
+}
+
+// MaxPerFeed returns a policy keeping only the n newest articles by publish date of each feed. Only
+// the feed of the article created is checked, since the others didn't grow.
+func MaxPerFeed(n int) EvictionPolicy {
+	return evictionFunc(func(articles []*types.Article, created *types.Article, now time.Time) []string {
+		if created == nil {
+			return nil
+		}
+		var IDs []string
+		count := 0
+		for i := len(articles) - 1; i >= 0; i-- {
+			if articles[i].FeedID != created.FeedID {
+				continue
+			}
+			count++
+			if count > n {
+				IDs = append(IDs, articles[i].ID)
+			}
+		}
+		return IDs
+	})
+}
+
+// Policies returns a policy evicting the articles evicted by any of the provided policies.
+func Policies(policies ...EvictionPolicy) EvictionPolicy {
+	return evictionFunc(func(articles []*types.Article, created *types.Article, now time.Time) []string {
+		seen := map[string]bool{}
+		var IDs []string
+		for _, policy := range policies {
+			for _, ID := range policy.Evict(articles, created, now) {
+				if !seen[ID] {
+					seen[ID] = true
+					IDs = append(IDs, ID)
+				}
+			}
+		}
+		return IDs
+	})
+}
+
+// evict removes the articles selected by the provided policy after the provided article was
+// created, returning the number of removed articles. Articles whose removal can't be written to the
+// write-ahead log are kept. The caller must hold the lock.
+func (as *ArticleStore) evict(policy EvictionPolicy, created *types.Article) int {
+	if policy == nil {
+		return 0
+	}
+	var IDs []string
+	for _, ID := range policy.Evict(as.a, created, as.clock.Now()) {
+		if _, ok := as.m[ID]; !ok {
+			continue
+		}
+		if err := as.appendWAL(walEntry{Op: walDelete, ID: ID}); err != nil {
+			break
+		}
+		IDs = append(IDs, ID)
+	}
+	as.remove(IDs...)
+	return len(IDs)
+}
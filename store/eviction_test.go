@@ -0,0 +1,106 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../clock"
+	"../types"
+)
+
+func TestArticleStoreEvictionPolicy(t *testing.T) {
+	create := func(r *require.Assertions, store *ArticleStore, feed string, count int) {
+		for i := 0; i < count; i++ {
+			_, err := store.Create(&types.Article{
+				GUID:        fmt.Sprintf("%s_%d", feed, i),
+				FeedID:      feed,
+				PublishDate: time.Unix(int64(i*10), 0).UTC(),
+			})
+			r.NoError(err)
+		}
+	}
+
+	t.Run("count policy evicts the oldest articles past the threshold", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(WithEvictionPolicy(MaxCount(3)))
+		create(r, store, "feed", 3)
+		articles, err := store.List("", 0, "")
+		r.NoError(err)
+		a.Len(articles, 3, "no article must be evicted up to the threshold")
+
+		create(r, store, "other", 2)
+		articles, err = store.List("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"other_1", "feed_1", "feed_2"}, guids(articles))
+		_, err = store.Get(store.articleID("feed_0"))
+		a.Error(err)
+		a.Empty(store.ListTombstones(), "evicted articles must not leave tombstones")
+	})
+
+	t.Run("per feed policy only evicts articles of the same feed", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(WithEvictionPolicy(MaxPerFeed(2)))
+		create(r, store, "feed", 4)
+		create(r, store, "other", 1)
+		articles, err := store.List("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"other_0", "feed_2", "feed_3"}, guids(articles))
+	})
+
+	t.Run("age policy evicts articles published before the cutoff", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(
+			WithClock(clock.NewFake(time.Unix(35, 0))),
+			WithEvictionPolicy(MaxAge(20*time.Second)),
+		)
+		create(r, store, "feed", 4)
+		articles, err := store.List("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"feed_2", "feed_3"}, guids(articles))
+	})
+
+	t.Run("composed policies evict what any of them evicts", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(WithEvictionPolicy(Policies(MaxCount(4), MaxPerFeed(1))))
+		create(r, store, "feed", 3)
+		create(r, store, "other", 5)
+		articles, err := store.List("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"feed_2", "other_4"}, guids(articles))
+	})
+
+	t.Run("no policy keeps all articles", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore()
+		create(r, store, "feed", 5)
+		articles, err := store.List("", 0, "")
+		r.NoError(err)
+		a.Len(articles, 5, "unexpected number of articles")
+	})
+
+	t.Run("evictions survive a restart", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		path := filepath.Join(t.TempDir(), "articles.wal")
+		store := NewArticleStore(WithEvictionPolicy(MaxCount(2)))
+		r.NoError(store.OpenWAL(path))
+		create(r, store, "feed", 3)
+
+		restored := NewArticleStore()
+		r.NoError(restored.OpenWAL(path))
+		defer restored.Close()
+		articles, err := restored.List("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"feed_1", "feed_2"}, guids(articles))
+	})
+}
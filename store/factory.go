@@ -0,0 +1,35 @@
+package store
+
+import (
+	"fmt"
+)
+
+// Backend identifies the implementation used for storing feeds and articles.
+type Backend string
+
+// BackendMemory keeps all data in memory, losing it when the service stops unless the write-ahead
+// log of the article store is enabled.
+const BackendMemory Backend = "memory"
+
+// Config holds the settings used for building the stores. WALPath optionally enables the
+// write-ahead log of the memory article store at the provided path.
+type Config struct {
+	Backend Backend
+	WALPath string
+}
+
+// New returns the feed and article stores for the configured backend, defaulting to memory when
+// none is set. The article store options are applied to the built article store.
+func New(cfg Config, opts ...ArticleStoreOption) (*FeedStore, *ArticleStore, error) {
+	switch cfg.Backend {
+	case "", BackendMemory:
+		articleStore := NewArticleStore(opts...)
+		if cfg.WALPath != "" {
+			if err := articleStore.OpenWAL(cfg.WALPath); err != nil {
+				return nil, nil, err
+			}
+		}
+		return NewFeedStore(), articleStore, nil
+	}
+	return nil, nil, fmt.Errorf("unknown store backend %q", cfg.Backend)
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../types"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("memory stores by default", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		for _, backend := range []Backend{"", BackendMemory} {
+			feedStore, articleStore, err := New(Config{Backend: backend})
+			r.NoError(err)
+			a.IsType(&FeedStore{}, feedStore)
+			a.IsType(&ArticleStore{}, articleStore)
+		}
+	})
+
+	t.Run("error for unknown backend", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		for _, backend := range []Backend{"unknown", "bolt", "file"} {
+			_, _, err := New(Config{Backend: backend})
+			r.Error(err)
+			a.Contains(err.Error(), "unknown store backend")
+		}
+	})
+}
+
+func TestNewWAL(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	path := filepath.Join(t.TempDir(), "articles.wal")
+	_, articleStore, err := New(Config{WALPath: path})
+	r.NoError(err)
+	_, err = articleStore.Create(&types.Article{GUID: "guid"})
+	r.NoError(err)
+
+	_, recovered, err := New(Config{WALPath: path})
+	r.NoError(err)
+	articles, err := recovered.List("", 0, "")
+	r.NoError(err)
+	a.Len(articles, 1, "unexpected number of articles")
+}
@@ -2,17 +2,24 @@ package store
 
 import (
 	"errors"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"../types"
 
 	"github.com/google/uuid"
 )
 
+// loadHistorySize is the number of recent loads of each feed used for computing its health.
+const loadHistorySize = 20
+
 // FeedStore stores information about feeds.
 type FeedStore struct {
 	mu            sync.RWMutex
 	m             map[string]*types.Feed
+	loads         map[string][]bool
 	uuidNamespace uuid.UUID
 }
 
@@ -20,6 +27,7 @@ type FeedStore struct {
 func NewFeedStore() *FeedStore {
 	return &FeedStore{
 		m:             map[string]*types.Feed{},
+		loads:         map[string][]bool{},
 		uuidNamespace: uuid.MustParse(uuidNamespace),
 	}
 }
@@ -29,24 +37,64 @@ func (fs *FeedStore) Reset() {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 	fs.m = map[string]*types.Feed{}
+	fs.loads = map[string][]bool{}
 }
 
-// Create stores a new feed.
+// Create stores a new feed. Feeds having the same address and category as a stored one, including
+// ones whose category was set after being created, return the stored feed instead.
 func (fs *FeedStore) Create(feed *types.Feed) (*types.Feed, error) {
 	if feed == nil {
 		return nil, nil
 	}
-	generatedID := uuid.NewSHA1(fs.uuidNamespace, []byte(feed.Address)).String()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if existing := fs.find(feed.Address, feed.Category); existing != nil {
+		return existing, nil
+	}
+	generatedID := fs.deriveID(feed.Address, feed.Category)
 	if a, ok := fs.m[generatedID]; ok {
 		return a, nil
 	}
 	feed.ID = generatedID
-	fs.mu.Lock()
-	defer fs.mu.Unlock()
 	fs.m[generatedID] = feed
 	return feed, nil
 }
 
+// ComputeID returns the ID a feed with the provided address and category receives when created, so
+// feeds with the same address in different categories can coexist. The ID of feeds without category
+// only depends on their address. Changing the category of a feed afterwards keeps its ID, so the ID
+// of a stored feed having the address and category is returned when there is one, such as a feed
+// created without category that took it from its channel.
+func (fs *FeedStore) ComputeID(address string, category string) string {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	if existing := fs.find(address, category); existing != nil {
+		return existing.ID
+	}
+	return fs.deriveID(address, category)
+}
+
+// find returns the stored feed with the provided address and category, or nil if there is none. The
+// caller must hold the lock.
+func (fs *FeedStore) find(address string, category string) *types.Feed {
+	for _, feed := range fs.m {
+		if feed.Address == address && feed.Category == category {
+			return feed
+		}
+	}
+	return nil
+}
+
+// deriveID returns the ID derived from the provided address and category.
+func (fs *FeedStore) deriveID(address string, category string) string {
+	name := address
+	if category != "" {
+		// Addresses can't hold line breaks, so no other address and category share the same name.
+		name += "\n" + category
+	}
+	return uuid.NewSHA1(fs.uuidNamespace, []byte(name)).String()
+}
+
 // List reads feeds from the store and returns all available feeds. The order of the results is not
 // guaranteed between calls.
 func (fs *FeedStore) List() ([]*types.Feed, error) {
@@ -61,6 +109,51 @@ func (fs *FeedStore) List() ([]*types.Feed, error) {
 	return res, nil
 }
 
+// ListPage returns up to pageSize feeds ordered by ID, starting after the feed whose ID is the
+// provided cursor, or all of them if pageSize is 0. Unlike List, the order is stable, so the feeds
+// can be paginated by passing the ID of the last feed returned as the next cursor. If a provider is
+// provided, only its feeds are returned.
+func (fs *FeedStore) ListPage(provider string, cursor string, pageSize int) ([]*types.Feed, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	var res []*types.Feed
+	for _, feed := range fs.m {
+		if (provider == "" || feed.Provider == provider) && feed.ID > cursor {
+			res = append(res, feed)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	if pageSize > 0 && len(res) > pageSize {
+		res = res[:pageSize]
+	}
+	return res, nil
+}
+
+// Search returns the feeds whose provider, category or address contain the provided query, ignoring
+// case, ordered by ID.
+func (fs *FeedStore) Search(query string) ([]*types.Feed, error) {
+	query = strings.ToLower(query)
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	res := []*types.Feed{}
+	for _, feed := range fs.m {
+		for _, field := range []string{feed.Provider, feed.Category, feed.Address} {
+			if strings.Contains(strings.ToLower(field), query) {
+				res = append(res, feed)
+				break
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
 // Get returns a feed from the store based on its GUID if it exists. Returns an error otherwise.
 func (fs *FeedStore) Get(ID string) (*types.Feed, error) {
 	if ID == "" {
@@ -73,3 +166,118 @@ func (fs *FeedStore) Get(ID string) (*types.Feed, error) {
 	}
 	return fs.m[ID], nil
 }
+
+// GetByAddress returns the feed with the provided primary address if it exists, which is any of
+// them when the address is used by feeds in different categories. Returns an error otherwise.
+func (fs *FeedStore) GetByAddress(address string) (*types.Feed, error) {
+	if address == "" {
+		return nil, errors.New("invalid address provided")
+	}
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	for _, feed := range fs.m {
+		if feed.Address == address {
+			return feed, nil
+		}
+	}
+	return nil, errors.New("resource not found")
+}
+
+// UpdateTimeout changes the time allowed for reading the feed with the provided ID, where zero
+// means the default timeout is used. Returns the updated feed.
+func (fs *FeedStore) UpdateTimeout(ID string, timeout time.Duration) (*types.Feed, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.update(ID, func(feed *types.Feed) {
+		feed.Timeout = timeout
+	})
+}
+
+// UpdateAddress changes the address of the feed with the provided ID, keeping its ID unchanged so
+// existing references to the feed remain valid. Returns the updated feed.
+func (fs *FeedStore) UpdateAddress(ID string, address string) (*types.Feed, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.update(ID, func(feed *types.Feed) {
+		feed.Address = address
+	})
+}
+
+// RenameCategory changes the category of all feeds in the old category to the new one, returning
+// the number of feeds changed. Articles are not affected, as their categories come from the feeds
+// items instead.
+func (fs *FeedStore) RenameCategory(old, new string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	renamed := 0
+	for ID, feed := range fs.m {
+		if feed.Category == old {
+			fs.update(ID, func(feed *types.Feed) {
+				feed.Category = new
+			})
+			renamed++
+		}
+	}
+	return renamed
+}
+
+// UpdateCategory changes the category of the feed with the provided ID. Returns the updated feed.
+func (fs *FeedStore) UpdateCategory(ID string, category string) (*types.Feed, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.update(ID, func(feed *types.Feed) {
+		feed.Category = category
+	})
+}
+
+// Delete removes the feed with the provided ID from the store.
+func (fs *FeedStore) Delete(ID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.m[ID]; !ok {
+		return errors.New("resource not found")
+	}
+	delete(fs.m, ID)
+	delete(fs.loads, ID)
+	return nil
+}
+
+// RecordLoad adds the outcome of a load of the feed with the provided ID to its recent history,
+// which keeps the latest loads only, and updates the health of the feed accordingly. Returns the
+// updated feed.
+func (fs *FeedStore) RecordLoad(ID string, success bool) (*types.Feed, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.m[ID]; !ok {
+		return nil, errors.New("resource not found")
+	}
+	loads := append(fs.loads[ID], success)
+	if len(loads) > loadHistorySize {
+		loads = loads[len(loads)-loadHistorySize:]
+	}
+	fs.loads[ID] = loads
+	succeeded := 0
+	for _, ok := range loads {
+		if ok {
+			succeeded++
+		}
+	}
+	return fs.update(ID, func(feed *types.Feed) {
+		feed.Loads = len(loads)
+		feed.Health = float64(succeeded) / float64(len(loads))
+	})
+}
+
+// update replaces the feed with the provided ID with a copy changed by the provided function,
+// returning the copy. Stored feeds are never modified, since the pointers returned by the store are
+// read without holding the lock, such as when serializing them. The caller must hold the lock.
+func (fs *FeedStore) update(ID string, change func(feed *types.Feed)) (*types.Feed, error) {
+	feed, ok := fs.m[ID]
+	if !ok {
+		return nil, errors.New("resource not found")
+	}
+	updated := *feed
+	change(&updated)
+	fs.m[ID] = &updated
+	return &updated, nil
+}
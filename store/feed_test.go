@@ -1,7 +1,12 @@
 package store
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,6 +37,25 @@ func TestFeedStoreCreate(t *testing.T) {
 		a.Equal("dbefb2be-dfe0-5513-b23a-cc04c551221e", feed.ID)
 	})
 
+	t.Run("same address in different categories gets distinct IDs", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+
+		world, err := store.Create(&types.Feed{Address: "test_guid", Category: "world"})
+		r.NoError(err)
+		tech, err := store.Create(&types.Feed{Address: "test_guid", Category: "tech"})
+		r.NoError(err)
+		a.NotEqual(world.ID, tech.ID)
+		a.NotEqual("dbefb2be-dfe0-5513-b23a-cc04c551221e", world.ID)
+		a.Equal(store.ComputeID("test_guid", "world"), world.ID)
+		a.Equal(store.ComputeID("test_guid", "tech"), tech.ID)
+
+		feeds, err := store.List()
+		r.NoError(err)
+		a.Len(feeds, 2)
+	})
+
 	t.Run("all fields are stored correctly", func(t *testing.T) {
 		store := NewFeedStore()
 		r := require.New(t)
@@ -67,6 +91,24 @@ func TestFeedStoreCreate(t *testing.T) {
 		r.Len(feeds, 1, "unexpected number of feeds")
 		a.Equal("dbefb2be-dfe0-5513-b23a-cc04c551221e", feeds[0].ID)
 	})
+
+	t.Run("feed categorized after creation is not duplicated", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		created, err := store.Create(&types.Feed{Address: "test_guid"})
+		r.NoError(err)
+		_, err = store.UpdateCategory(created.ID, "world")
+		r.NoError(err)
+
+		a.Equal(created.ID, store.ComputeID("test_guid", "world"))
+		feed, err := store.Create(&types.Feed{Address: "test_guid", Category: "world"})
+		r.NoError(err)
+		a.Equal(created.ID, feed.ID)
+		feeds, err := store.List()
+		r.NoError(err)
+		a.Len(feeds, 1, "unexpected number of feeds")
+	})
 }
 
 func TestFeedStoreList(t *testing.T) {
@@ -93,6 +135,65 @@ func TestFeedStoreList(t *testing.T) {
 	})
 }
 
+func TestFeedStoreListPage(t *testing.T) {
+	store := NewFeedStore()
+	r := require.New(t)
+	for i := 1; i <= 3; i++ {
+		_, err := store.Create(&types.Feed{Provider: "BBC", Address: fmt.Sprintf("bbc_%d", i)})
+		r.NoError(err)
+		_, err = store.Create(&types.Feed{Provider: "CNN", Address: fmt.Sprintf("cnn_%d", i)})
+		r.NoError(err)
+	}
+
+	t.Run("lists all feeds ordered by ID", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		feeds, err := store.ListPage("", "", 0)
+		r.NoError(err)
+		r.Len(feeds, 6, "unexpected number of feeds")
+		a.True(sort.SliceIsSorted(feeds, func(i, j int) bool { return feeds[i].ID < feeds[j].ID }))
+	})
+
+	t.Run("paginates the feeds of a provider", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		first, err := store.ListPage("BBC", "", 2)
+		r.NoError(err)
+		r.Len(first, 2, "unexpected number of feeds")
+		second, err := store.ListPage("BBC", first[1].ID, 2)
+		r.NoError(err)
+		r.Len(second, 1, "unexpected number of feeds")
+		for _, feed := range append(first, second...) {
+			a.Equal("BBC", feed.Provider)
+		}
+		a.True(first[1].ID < second[0].ID)
+	})
+}
+
+func TestFeedStoreSearch(t *testing.T) {
+	store := NewFeedStore()
+	r := require.New(t)
+	bbc, err := store.Create(&types.Feed{Provider: "BBC News", Category: "UK", Address: "https://feeds.bbci.co.uk/news/uk/rss.xml"})
+	r.NoError(err)
+	cnn, err := store.Create(&types.Feed{Provider: "CNN", Category: "World", Address: "https://rss.cnn.com/rss/edition.rss"})
+	r.NoError(err)
+
+	for query, expected := range map[string][]*types.Feed{
+		"bbc news": {bbc},
+		"world":    {cnn},
+		"CNN.COM":  {cnn},
+		"rss":      {bbc, cnn},
+		"missing":  {},
+	} {
+		t.Run(query, func(t *testing.T) {
+			r := require.New(t)
+			feeds, err := store.Search(query)
+			r.NoError(err)
+			assert.ElementsMatch(t, expected, feeds)
+		})
+	}
+}
+
 func TestFeedStoreGet(t *testing.T) {
 	store := NewFeedStore()
 	r := require.New(t)
@@ -153,3 +254,304 @@ func TestFeedStoreReset(t *testing.T) {
 		a.Len(feeds, 0, "unexpected number of feeds")
 	})
 }
+
+func TestFeedStoreUpdateAddress(t *testing.T) {
+	t.Run("errors if ID not found", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		feed, err := store.UpdateAddress("invalid_id", "new_address")
+		r.Nil(feed)
+		r.Error(err)
+		a.Contains(err.Error(), "resource not found")
+	})
+
+	t.Run("updates the address keeping the ID", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := store.Create(&types.Feed{
+			Address: "test_guid",
+		})
+		r.NoError(err)
+
+		feed, err := store.UpdateAddress("dbefb2be-dfe0-5513-b23a-cc04c551221e", "new_address")
+		r.NoError(err)
+		a.Equal("new_address", feed.Address)
+
+		feed, err = store.Get("dbefb2be-dfe0-5513-b23a-cc04c551221e")
+		r.NoError(err)
+		a.Equal("new_address", feed.Address)
+	})
+
+	t.Run("updates do not race with readers of the addresses", func(t *testing.T) {
+		// Only meaningful with the race detector enabled.
+		r := require.New(t)
+		store := NewFeedStore()
+		feed, err := store.Create(&types.Feed{Address: "address"})
+		r.NoError(err)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				store.UpdateAddress(feed.ID, fmt.Sprintf("address_%d", i))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if feed, err := store.Get(feed.ID); err == nil {
+					feed.Addresses()
+				}
+			}
+		}()
+		wg.Wait()
+	})
+}
+
+func TestFeedStoreUpdateCategory(t *testing.T) {
+	t.Run("errors if ID not found", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		feed, err := store.UpdateCategory("invalid_id", "world")
+		r.Nil(feed)
+		r.Error(err)
+		a.Contains(err.Error(), "resource not found")
+	})
+
+	t.Run("updates the category", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := store.Create(&types.Feed{
+			Address: "test_guid",
+		})
+		r.NoError(err)
+
+		feed, err := store.UpdateCategory("dbefb2be-dfe0-5513-b23a-cc04c551221e", "world")
+		r.NoError(err)
+		a.Equal("world", feed.Category)
+
+		feed, err = store.Get("dbefb2be-dfe0-5513-b23a-cc04c551221e")
+		r.NoError(err)
+		a.Equal("world", feed.Category)
+	})
+
+	t.Run("returned feeds are not modified by later updates", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		created, err := store.Create(&types.Feed{
+			Address:  "test_guid",
+			Category: "UK",
+		})
+		r.NoError(err)
+
+		updated, err := store.UpdateCategory(created.ID, "world")
+		r.NoError(err)
+		a.Equal("UK", created.Category)
+		a.NotSame(created, updated)
+	})
+}
+
+func TestFeedStoreUpdateTimeout(t *testing.T) {
+	t.Run("errors if ID not found", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		feed, err := store.UpdateTimeout("invalid_id", time.Second)
+		r.Nil(feed)
+		r.Error(err)
+		a.Contains(err.Error(), "resource not found")
+	})
+
+	t.Run("updates the timeout", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		_, err := store.Create(&types.Feed{
+			Address: "test_guid",
+		})
+		r.NoError(err)
+
+		feed, err := store.UpdateTimeout("dbefb2be-dfe0-5513-b23a-cc04c551221e", time.Second)
+		r.NoError(err)
+		a.Equal(time.Second, feed.Timeout)
+
+		feed, err = store.Get("dbefb2be-dfe0-5513-b23a-cc04c551221e")
+		r.NoError(err)
+		a.Equal(time.Second, feed.Timeout)
+	})
+
+	t.Run("returned feeds are not modified by later updates", func(t *testing.T) {
+		store := NewFeedStore()
+		r := require.New(t)
+		a := assert.New(t)
+		created, err := store.Create(&types.Feed{
+			Address: "test_guid",
+		})
+		r.NoError(err)
+
+		updated, err := store.UpdateTimeout(created.ID, time.Second)
+		r.NoError(err)
+		a.Equal(time.Duration(0), created.Timeout)
+		a.NotSame(created, updated)
+	})
+}
+
+func TestFeedStoreDelete(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	store := NewFeedStore()
+	feed, err := store.Create(&types.Feed{Address: "address"})
+	r.NoError(err)
+
+	r.NoError(store.Delete(feed.ID))
+	_, err = store.Get(feed.ID)
+	a.Error(err)
+
+	err = store.Delete(feed.ID)
+	r.Error(err)
+	a.Contains(err.Error(), "resource not found")
+}
+
+func TestFeedStoreRecordLoad(t *testing.T) {
+	t.Run("health is the ratio of successful loads", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewFeedStore()
+		feed, err := store.Create(&types.Feed{Address: "address"})
+		r.NoError(err)
+		a.Zero(feed.Health)
+
+		for _, success := range []bool{true, false, true, true} {
+			_, err = store.RecordLoad(feed.ID, success)
+			r.NoError(err)
+		}
+		feed, err = store.Get(feed.ID)
+		r.NoError(err)
+		a.Equal(0.75, feed.Health)
+		a.Equal(4, feed.Loads)
+	})
+
+	t.Run("only the recent loads are kept", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewFeedStore()
+		feed, err := store.Create(&types.Feed{Address: "address"})
+		r.NoError(err)
+		for i := 0; i < loadHistorySize; i++ {
+			_, err = store.RecordLoad(feed.ID, false)
+			r.NoError(err)
+		}
+		for i := 0; i < loadHistorySize/2; i++ {
+			feed, err = store.RecordLoad(feed.ID, true)
+			r.NoError(err)
+		}
+		a.Equal(0.5, feed.Health)
+		a.Equal(loadHistorySize, feed.Loads)
+	})
+
+	t.Run("errors for unknown feeds", func(t *testing.T) {
+		_, err := NewFeedStore().RecordLoad("unknown", true)
+		assert.Error(t, err)
+	})
+
+	t.Run("returned feeds are not modified by later loads", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewFeedStore()
+		feed, err := store.Create(&types.Feed{Address: "address"})
+		r.NoError(err)
+		_, err = store.RecordLoad(feed.ID, true)
+		r.NoError(err)
+		_, err = store.RecordLoad(feed.ID, false)
+		r.NoError(err)
+
+		a.Zero(feed.Loads)
+		stored, err := store.Get(feed.ID)
+		r.NoError(err)
+		a.Equal(2, stored.Loads)
+		a.Equal(0.5, stored.Health)
+	})
+
+	t.Run("loads do not race with readers serializing feeds", func(t *testing.T) {
+		// Only meaningful with the race detector enabled.
+		r := require.New(t)
+		store := NewFeedStore()
+		feed, err := store.Create(&types.Feed{Address: "address"})
+		r.NoError(err)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				store.RecordLoad(feed.ID, i%2 == 0)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if feed, err := store.Get(feed.ID); err == nil {
+					json.Marshal(feed)
+				}
+			}
+		}()
+		wg.Wait()
+	})
+}
+
+func TestFeedStoreGetByAddress(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	store := NewFeedStore()
+	created, err := store.Create(&types.Feed{Address: "address"})
+	r.NoError(err)
+	_, err = store.UpdateAddress(created.ID, "moved_address")
+	r.NoError(err)
+
+	feed, err := store.GetByAddress("moved_address")
+	r.NoError(err)
+	a.Equal(created.ID, feed.ID)
+	_, err = store.GetByAddress("address")
+	a.Error(err)
+	_, err = store.GetByAddress("")
+	a.Error(err)
+}
+
+func TestFeedStoreRenameCategory(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	store := NewFeedStore()
+	var renamed *types.Feed
+	for _, feed := range []*types.Feed{
+		{Address: "address_1", Category: "UK"},
+		{Address: "address_2", Category: "UK"},
+		{Address: "address_3", Category: "World"},
+	} {
+		created, err := store.Create(feed)
+		r.NoError(err)
+		if renamed == nil {
+			renamed = created
+		}
+	}
+
+	a.Equal(2, store.RenameCategory("UK", "United Kingdom"))
+	a.Equal("UK", renamed.Category, "feeds returned before renaming are not modified")
+	feeds, err := store.List()
+	r.NoError(err)
+	categories := map[string]string{}
+	for _, feed := range feeds {
+		categories[feed.Address] = feed.Category
+	}
+	a.Equal(map[string]string{
+		"address_1": "United Kingdom",
+		"address_2": "United Kingdom",
+		"address_3": "World",
+	}, categories)
+	a.Zero(store.RenameCategory("UK", "Other"))
+}
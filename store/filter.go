@@ -0,0 +1,116 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"../types"
+)
+
+// articleMatcher checks whether articles satisfy the conditions of an article filter.
+type articleMatcher struct {
+	feed          string
+	categories    map[string]struct{}
+	labels        map[string]struct{}
+	enclosureType string
+	noEnclosures  bool
+	hasFullText   bool
+	hasImage      bool
+	batch         string
+	// now is the current time of the store, after which articles are skipped when hideFuture is set.
+	hideFuture bool
+	now        time.Time
+	// snapshot is the store version up to which articles are selected, with versions holding the
+	// version of each article. Snapshots are only checked when versions is set.
+	snapshot uint64
+	versions map[string]uint64
+}
+
+func newArticleMatcher(filter types.ArticleFilter) *articleMatcher {
+	return &articleMatcher{
+		feed:          filter.Feed,
+		categories:    toSet(filter.Categories),
+		labels:        toSet(filter.Labels),
+		enclosureType: strings.ToLower(filter.EnclosureType),
+		noEnclosures:  filter.NoEnclosures,
+		hasFullText:   filter.HasFullText,
+		hasImage:      filter.HasImage,
+		hideFuture:    filter.HideFuture,
+		batch:         filter.Batch,
+	}
+}
+
+// match returns whether the provided article satisfies all conditions of the filter.
+func (m *articleMatcher) match(a *types.Article) bool {
+	if len(m.categories) > 0 && !containsAny(m.categories, a.Categories) {
+		// Must do some filtering on categories.
+		return false
+	}
+	if len(m.labels) > 0 && !containsAny(m.labels, a.Labels) {
+		// Must do some filtering on labels.
+		return false
+	}
+	if m.feed != "" && a.FeedID != m.feed {
+		// Must do filtering on feed.
+		return false
+	}
+	if m.enclosureType != "" && !m.hasEnclosureType(a) {
+		// Must do filtering on enclosure types.
+		return false
+	}
+	if m.noEnclosures && len(a.Enclosures) > 0 {
+		// Must skip articles with enclosures.
+		return false
+	}
+	if m.hasFullText && strings.TrimSpace(a.FullText) == "" {
+		// Must skip articles without full text.
+		return false
+	}
+	if m.hasImage && strings.TrimSpace(a.ImageURL) == "" {
+		// Must skip articles without image.
+		return false
+	}
+	if m.hideFuture && a.PublishDate.After(m.now) {
+		// Must skip articles published in the future.
+		return false
+	}
+	if m.batch != "" && a.BatchID != m.batch {
+		// Must do filtering on batch.
+		return false
+	}
+	if m.versions != nil && m.versions[a.ID] > m.snapshot {
+		// Must skip articles created after the snapshot.
+		return false
+	}
+	return true
+}
+
+// hasEnclosureType returns whether any enclosure of the article has a type starting with the
+// filtered one. Media types are compared case-insensitively.
+func (m *articleMatcher) hasEnclosureType(a *types.Article) bool {
+	for _, e := range a.Enclosures {
+		if e != nil && strings.HasPrefix(strings.ToLower(e.Type), m.enclosureType) {
+			return true
+		}
+	}
+	return false
+}
+
+// toSet creates a hashmap with the provided values for filtering.
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// containsAny returns whether any of the values is in the set.
+func containsAny(set map[string]struct{}, values []string) bool {
+	for _, v := range values {
+		if _, ok := set[v]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"encoding/base32"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// IDScheme describes how the IDs of the articles are generated from their GUIDs. The IDs are stable,
+// so the same GUID always gets the same ID.
+type IDScheme int
+
+const (
+	// IDSchemeUUID generates name-based UUIDs, such as "7b485edd-4f46-56c9-8c08-1db5dda37624".
+	IDSchemeUUID IDScheme = iota
+	// IDSchemeHash generates short prefixed IDs from the hash of the GUID, such as
+	// "art_3px3fpw74bkrhmr2".
+	IDSchemeHash
+)
+
+// hashIDPrefix is the prefix of the IDs generated by the hash scheme.
+const hashIDPrefix = "art_"
+
+// hashIDLength is the number of bytes of the hash encoded in the IDs generated by the hash scheme.
+const hashIDLength = 10
+
+// hashIDEncoding encodes the hash in the IDs generated by the hash scheme.
+var hashIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// WithIDScheme sets how the IDs of the articles are generated. Changing the scheme of a store holding
+// articles, such as one recovered from a write-ahead log, makes their GUIDs map to different IDs.
+func WithIDScheme(scheme IDScheme) ArticleStoreOption {
+	return func(as *ArticleStore) {
+		as.idScheme = scheme
+	}
+}
+
+// articleID returns the ID of the article with the provided GUID.
+func (as *ArticleStore) articleID(GUID string) string {
+	return as.scopedArticleID(GUID, "")
+}
+
+// scopedArticleID returns the ID of the article with the provided GUID within the provided scope,
+// so the same GUID gets a different ID in each scope. An empty scope gives the ID from articleID.
+func (as *ArticleStore) scopedArticleID(GUID string, scope string) string {
+	name := GUID
+	if scope != "" {
+		name = scope + "/" + GUID
+	}
+	u := uuid.NewSHA1(as.uuidNamespace, []byte(name))
+	if as.idScheme == IDSchemeHash {
+		return hashIDPrefix + strings.ToLower(hashIDEncoding.EncodeToString(u[:hashIDLength]))
+	}
+	return u.String()
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../types"
+)
+
+func TestArticleStoreIDScheme(t *testing.T) {
+	t.Run("uuid is the default scheme", func(t *testing.T) {
+		r := require.New(t)
+		article, err := NewArticleStore().Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		assert.Equal(t, "dbefb2be-dfe0-5513-b23a-cc04c551221e", article.ID)
+	})
+
+	t.Run("hash scheme generates short prefixed IDs", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore(WithIDScheme(IDSchemeHash))
+		article, err := store.Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		a.Regexp(regexp.MustCompile(`^art_[a-z2-7]{16}$`), article.ID)
+		a.Equal("art_3px3fpw74bkrhmr2", article.ID)
+
+		// The same GUID gets the same ID, in any store using the scheme.
+		existing, err := store.Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		a.Same(article, existing)
+		other, err := NewArticleStore(WithIDScheme(IDSchemeHash)).Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		a.Equal(article.ID, other.ID)
+		fetched, err := store.Get(article.ID)
+		r.NoError(err)
+		a.Same(article, fetched)
+	})
+
+	t.Run("scoped articles get an ID for each scope", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		store := NewArticleStore()
+		article, err := store.Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		scoped, err := store.Create(&types.Article{GUID: "test_guid", Scope: "clone"})
+		r.NoError(err)
+		a.NotEqual(article.ID, scoped.ID)
+
+		existing, err := store.Create(&types.Article{GUID: "test_guid", Scope: "clone"})
+		r.NoError(err)
+		a.Same(scoped, existing)
+		other, err := store.Create(&types.Article{GUID: "test_guid", Scope: "other_clone"})
+		r.NoError(err)
+		a.NotEqual(scoped.ID, other.ID)
+	})
+}
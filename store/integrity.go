@@ -0,0 +1,42 @@
+package store
+
+import (
+	"fmt"
+)
+
+// CheckIntegrity verifies the invariants of the store, which are that the articles are ordered by
+// publish date, that the index by ID holds exactly the listed articles and that no ID is listed
+// twice. Returns an error describing the first violation found, or nil when the store is consistent.
+// Violations are bugs, which Reindex may repair.
+func (as *ArticleStore) CheckIntegrity() error {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	seen := make(map[string]int, len(as.a))
+	for i, a := range as.a {
+		if a == nil {
+			return fmt.Errorf("nil article at position %d", i)
+		}
+		if j, ok := seen[a.ID]; ok {
+			return fmt.Errorf("article %s is listed at positions %d and %d", a.ID, j, i)
+		}
+		seen[a.ID] = i
+		if i > 0 && as.before(a, as.a[i-1]) {
+			return fmt.Errorf("article %s at position %d is ordered before the previous article %s", a.ID, i, as.a[i-1].ID)
+		}
+		indexed, ok := as.m[a.ID]
+		if !ok {
+			return fmt.Errorf("article %s at position %d is missing from the index", a.ID, i)
+		}
+		if indexed != a {
+			return fmt.Errorf("article %s at position %d differs from the indexed one", a.ID, i)
+		}
+	}
+	if len(as.m) != len(as.a) {
+		for ID := range as.m {
+			if _, ok := seen[ID]; !ok {
+				return fmt.Errorf("indexed article %s is not listed", ID)
+			}
+		}
+	}
+	return nil
+}
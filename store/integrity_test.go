@@ -0,0 +1,109 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../types"
+)
+
+func TestArticleStoreCheckIntegrity(t *testing.T) {
+	newStore := func(r *require.Assertions) *ArticleStore {
+		store := NewArticleStore()
+		for i := 1; i <= 3; i++ {
+			_, err := store.Create(&types.Article{
+				GUID:        fmt.Sprintf("guid_%d", i),
+				PublishDate: time.Unix(int64(i*10), 0).UTC(),
+			})
+			r.NoError(err)
+		}
+		return store
+	}
+	// corrupt changes the internal state of the store, which is otherwise kept consistent.
+	corrupt := func(store *ArticleStore, change func(as *ArticleStore)) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		change(store)
+	}
+
+	t.Run("consistent store", func(t *testing.T) {
+		r := require.New(t)
+		store := newStore(r)
+		r.NoError(store.CheckIntegrity())
+		r.NoError(NewArticleStore().CheckIntegrity())
+
+		_, err := store.Delete(store.articleID("guid_2"))
+		r.NoError(err)
+		_, err = store.Reset()
+		r.NoError(err)
+		r.NoError(store.CheckIntegrity())
+	})
+
+	for name, tc := range map[string]struct {
+		change   func(as *ArticleStore)
+		expected string
+	}{
+		"unsorted articles": {
+			change: func(as *ArticleStore) {
+				as.a[0], as.a[2] = as.a[2], as.a[0]
+			},
+			expected: "is ordered before the previous article",
+		},
+		"listed article missing from the index": {
+			change: func(as *ArticleStore) {
+				delete(as.m, as.a[1].ID)
+			},
+			expected: "is missing from the index",
+		},
+		"indexed article not listed": {
+			change: func(as *ArticleStore) {
+				as.a = as.a[:2]
+			},
+			expected: "is not listed",
+		},
+		"index holding another article": {
+			change: func(as *ArticleStore) {
+				copied := *as.a[1]
+				as.m[copied.ID] = &copied
+			},
+			expected: "differs from the indexed one",
+		},
+		"duplicated ID": {
+			change: func(as *ArticleStore) {
+				as.a = append(as.a, as.a[2])
+			},
+			expected: "is listed at positions 2 and 3",
+		},
+		"nil article": {
+			change: func(as *ArticleStore) {
+				as.a[1] = nil
+			},
+			expected: "nil article at position 1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			store := newStore(r)
+			corrupt(store, tc.change)
+			err := store.CheckIntegrity()
+			r.Error(err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+
+	t.Run("reindex repairs the order", func(t *testing.T) {
+		r := require.New(t)
+		store := newStore(r)
+		corrupt(store, func(as *ArticleStore) {
+			as.a[0], as.a[2] = as.a[2], as.a[0]
+		})
+		r.Error(store.CheckIntegrity())
+		_, err := store.Reindex()
+		r.NoError(err)
+		r.NoError(store.CheckIntegrity())
+	})
+}
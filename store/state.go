@@ -0,0 +1,134 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"../types"
+)
+
+// articleState holds the user state of an article. It is kept apart from the articles, keyed by
+// article ID, so that it can be persisted without the article content and applied again once the
+// same articles are ingested.
+type articleState struct {
+	Read    bool     `json:"read,omitempty"`
+	Starred bool     `json:"starred,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+}
+
+// isZero returns whether the state holds no information.
+func (st *articleState) isZero() bool {
+	return !st.Read && !st.Starred && len(st.Labels) == 0
+}
+
+// MarkRead sets the read state of the article with the provided ID and returns the updated article.
+func (as *ArticleStore) MarkRead(ID string, read bool) (*types.Article, error) {
+	return as.updateState(ID, func(st *articleState) {
+		st.Read = read
+	})
+}
+
+// MarkStarred sets the starred state of the article with the provided ID and returns the updated
+// article.
+func (as *ArticleStore) MarkStarred(ID string, starred bool) (*types.Article, error) {
+	return as.updateState(ID, func(st *articleState) {
+		st.Starred = starred
+	})
+}
+
+// AddLabels applies the provided labels to the article with the provided ID, ignoring the ones it
+// already has, and returns the updated article.
+func (as *ArticleStore) AddLabels(ID string, labels ...string) (*types.Article, error) {
+	return as.updateState(ID, func(st *articleState) {
+		for _, label := range labels {
+			if label != "" && !containsString(st.Labels, label) {
+				st.Labels = append(st.Labels, label)
+			}
+		}
+	})
+}
+
+// RemoveLabel removes the provided label from the article with the provided ID and returns the
+// updated article.
+func (as *ArticleStore) RemoveLabel(ID string, label string) (*types.Article, error) {
+	return as.updateState(ID, func(st *articleState) {
+		labels := make([]string, 0, len(st.Labels))
+		for _, l := range st.Labels {
+			if l != label {
+				labels = append(labels, l)
+			}
+		}
+		st.Labels = labels
+	})
+}
+
+// SaveState writes the user state of all articles as JSON to the provided writer.
+func (as *ArticleStore) SaveState(w io.Writer) error {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	if err := json.NewEncoder(w).Encode(as.state); err != nil {
+		return fmt.Errorf("could not save state: %v", err)
+	}
+	return nil
+}
+
+// LoadState reads user state previously written by SaveState, replacing the current state. The
+// loaded state is applied to stored articles and to any article with a matching ID created later.
+func (as *ArticleStore) LoadState(r io.Reader) error {
+	state := map[string]articleState{}
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("could not load state: %v", err)
+	}
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.state = state
+	replaced := make(map[*types.Article]*types.Article, len(as.a))
+	for _, a := range as.a {
+		updated := *a
+		as.applyState(&updated)
+		replaced[a] = &updated
+	}
+	as.replace(replaced)
+	return nil
+}
+
+// updateState changes the state of the article with the provided ID using the update function.
+func (as *ArticleStore) updateState(ID string, update func(st *articleState)) (*types.Article, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	article, ok := as.m[ID]
+	if !ok {
+		return nil, errors.New("resource not found")
+	}
+	st := as.state[ID]
+	update(&st)
+	if st.isZero() {
+		delete(as.state, ID)
+	} else {
+		as.state[ID] = st
+	}
+	updated := *article
+	as.applyState(&updated)
+	as.replace(map[*types.Article]*types.Article{article: &updated})
+	return &updated, nil
+}
+
+// applyState copies the stored user state into the provided article, which must not be shared yet,
+// such as a copy of a stored one. The caller must hold the lock.
+func (as *ArticleStore) applyState(article *types.Article) {
+	st := as.state[article.ID]
+	article.Read = st.Read
+	article.Starred = st.Starred
+	article.Labels = append([]string(nil), st.Labels...)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
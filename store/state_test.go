@@ -0,0 +1,266 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../types"
+)
+
+func TestArticleStoreMarkStarred(t *testing.T) {
+	t.Run("errors if ID not found", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		article, err := store.MarkStarred("invalid_id", true)
+		r.Nil(article)
+		r.Error(err)
+		a.Contains(err.Error(), "resource not found")
+	})
+
+	t.Run("sets starred without changing read state", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		created, err := store.Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		_, err = store.MarkRead(created.ID, true)
+		r.NoError(err)
+
+		article, err := store.MarkStarred(created.ID, true)
+		r.NoError(err)
+		a.True(article.Starred)
+		a.True(article.Read)
+	})
+}
+
+func TestArticleStoreCopyOnWrite(t *testing.T) {
+	t.Run("returned articles are not modified by later updates", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		created, err := store.Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+		before, err := store.Get(created.ID)
+		r.NoError(err)
+
+		_, err = store.MarkRead(created.ID, true)
+		r.NoError(err)
+		_, err = store.AddLabels(created.ID, "later")
+		r.NoError(err)
+		_, err = store.UpdateFullText(created.ID, "full text", "", false)
+		r.NoError(err)
+
+		a.False(before.Read)
+		a.Empty(before.Labels)
+		a.Empty(before.FullText)
+		after, err := store.Get(created.ID)
+		r.NoError(err)
+		a.True(after.Read)
+		a.Equal([]string{"later"}, after.Labels)
+		a.Equal("full text", after.FullText)
+		listed, err := store.List("", 0, "")
+		r.NoError(err)
+		r.Len(listed, 1)
+		a.Same(after, listed[0], "the list must hold the updated article")
+	})
+
+	t.Run("updates do not race with readers serializing articles", func(t *testing.T) {
+		// Only meaningful with the race detector enabled.
+		store := NewArticleStore()
+		r := require.New(t)
+		created, err := store.Create(&types.Article{GUID: "test_guid"})
+		r.NoError(err)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				store.MarkRead(created.ID, i%2 == 0)
+				store.AddLabels(created.ID, "label")
+				store.RemoveLabel(created.ID, "label")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if article, err := store.Get(created.ID); err == nil {
+					json.Marshal(article)
+				}
+			}
+		}()
+		wg.Wait()
+	})
+}
+
+func TestArticleStoreState(t *testing.T) {
+	t.Run("restores flags over re-ingested articles", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		first, err := store.Create(&types.Article{GUID: "first"})
+		r.NoError(err)
+		second, err := store.Create(&types.Article{GUID: "second"})
+		r.NoError(err)
+		_, err = store.Create(&types.Article{GUID: "third"})
+		r.NoError(err)
+		_, err = store.MarkRead(first.ID, true)
+		r.NoError(err)
+		_, err = store.MarkStarred(second.ID, true)
+		r.NoError(err)
+
+		var buf bytes.Buffer
+		r.NoError(store.SaveState(&buf))
+		store.Reset()
+
+		// Re-ingested articles have no state until it is loaded.
+		first, err = store.Create(&types.Article{GUID: "first"})
+		r.NoError(err)
+		a.False(first.Read)
+		r.NoError(store.LoadState(&buf))
+		// Stored articles are copied on write, so the loaded state is only seen by new reads.
+		first, err = store.Get(first.ID)
+		r.NoError(err)
+		second, err = store.Create(&types.Article{GUID: "second"})
+		r.NoError(err)
+		third, err := store.Create(&types.Article{GUID: "third"})
+		r.NoError(err)
+
+		a.True(first.Read)
+		a.False(first.Starred)
+		a.False(second.Read)
+		a.True(second.Starred)
+		a.False(third.Read)
+		a.False(third.Starred)
+	})
+
+	t.Run("reset clears state", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		article, err := store.Create(&types.Article{GUID: "first"})
+		r.NoError(err)
+		_, err = store.MarkRead(article.ID, true)
+		r.NoError(err)
+
+		store.Reset()
+		article, err = store.Create(&types.Article{GUID: "first"})
+		r.NoError(err)
+		a.False(article.Read)
+	})
+
+	t.Run("saved state does not include article content", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		article, err := store.Create(&types.Article{GUID: "first", Content: "large_content"})
+		r.NoError(err)
+		_, err = store.MarkRead(article.ID, true)
+		r.NoError(err)
+
+		var buf bytes.Buffer
+		r.NoError(store.SaveState(&buf))
+		a.NotContains(buf.String(), "large_content")
+		a.Contains(buf.String(), article.ID)
+	})
+
+	t.Run("errors for invalid state", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		err := store.LoadState(strings.NewReader("invalid"))
+		r.Error(err)
+		a.Contains(err.Error(), "could not load state")
+	})
+}
+
+func TestArticleStoreLabels(t *testing.T) {
+	t.Run("adds and removes labels", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		created, err := store.Create(&types.Article{GUID: "guid", Categories: []string{"cat_1"}})
+		r.NoError(err)
+
+		article, err := store.AddLabels(created.ID, "work", "to-read", "work", "")
+		r.NoError(err)
+		a.Equal([]string{"work", "to-read"}, article.Labels)
+		a.Equal([]string{"cat_1"}, article.Categories, "categories must be kept apart from labels")
+
+		article, err = store.AddLabels(created.ID, "to-read", "later")
+		r.NoError(err)
+		a.Equal([]string{"work", "to-read", "later"}, article.Labels)
+
+		article, err = store.RemoveLabel(created.ID, "to-read")
+		r.NoError(err)
+		a.Equal([]string{"work", "later"}, article.Labels)
+
+		article, err = store.RemoveLabel(created.ID, "unknown")
+		r.NoError(err)
+		a.Equal([]string{"work", "later"}, article.Labels)
+	})
+
+	t.Run("error for unknown article", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		_, err := store.AddLabels("unknown", "work")
+		r.Error(err)
+		_, err = store.RemoveLabel("unknown", "work")
+		r.Error(err)
+	})
+
+	t.Run("filters by labels apart from categories", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		first, err := store.Create(&types.Article{GUID: "first", Categories: []string{"work"}})
+		r.NoError(err)
+		second, err := store.Create(&types.Article{GUID: "second"})
+		r.NoError(err)
+		_, err = store.Create(&types.Article{GUID: "third"})
+		r.NoError(err)
+		_, err = store.AddLabels(second.ID, "work")
+		r.NoError(err)
+		_, err = store.AddLabels(first.ID, "later")
+		r.NoError(err)
+
+		articles, err := store.ListFiltered("", 0, types.ArticleFilter{Labels: []string{"work"}}, types.OrderPublished)
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("second", articles[0].GUID)
+
+		articles, err = store.ListFiltered("", 0, types.ArticleFilter{Labels: []string{"work", "later"}}, types.OrderIngested)
+		r.NoError(err)
+		a.Len(articles, 2, "unexpected number of articles")
+
+		articles, err = store.ListFiltered("", 0, types.ArticleFilter{Categories: []string{"work"}, Labels: []string{"later"}}, types.OrderPublished)
+		r.NoError(err)
+		r.Len(articles, 1, "unexpected number of articles")
+		a.Equal("first", articles[0].GUID)
+	})
+
+	t.Run("labels are saved and loaded with the state", func(t *testing.T) {
+		store := NewArticleStore()
+		r := require.New(t)
+		a := assert.New(t)
+		created, err := store.Create(&types.Article{GUID: "guid"})
+		r.NoError(err)
+		_, err = store.AddLabels(created.ID, "work")
+		r.NoError(err)
+		var buf bytes.Buffer
+		r.NoError(store.SaveState(&buf))
+
+		restored := NewArticleStore()
+		r.NoError(restored.LoadState(&buf))
+		article, err := restored.Create(&types.Article{GUID: "guid"})
+		r.NoError(err)
+		a.Equal([]string{"work"}, article.Labels)
+	})
+}
@@ -4,3 +4,9 @@ package store
 // uuidNamespace is a randomly generated UUID that is used as namespace when generating hashes for
 // the resources IDs.
 const uuidNamespace = "cabe9f84-ab7e-494c-bf53-7499adeb30ac"
+
+// uncategorized is the bucket under which articles without categories are counted.
+const uncategorized = "uncategorized"
+
+// unknownProvider is the group under which articles without a provider are listed.
+const unknownProvider = "unknown"
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"../types"
+)
+
+// WithTombstoneRetention sets how long deleted articles are prevented from being stored again. Zero
+// means tombstones are kept until cleared.
+func WithTombstoneRetention(d time.Duration) ArticleStoreOption {
+	return func(as *ArticleStore) {
+		as.tombstoneRetention = d
+	}
+}
+
+// Delete removes the article with the provided ID from the store, leaving a tombstone so it is not
+// stored again when its feed is loaded. Returns the tombstone.
+func (as *ArticleStore) Delete(ID string) (*types.Tombstone, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	article, ok := as.m[ID]
+	if !ok {
+		return nil, errors.New("resource not found")
+	}
+	tombstone := &types.Tombstone{ID: ID, GUID: article.GUID, DeletedAt: as.clock.Now()}
+	if err := as.appendWAL(walEntry{Op: walDelete, ID: ID, Tombstone: tombstone}); err != nil {
+		return nil, err
+	}
+	as.remove(ID)
+	delete(as.state, ID)
+	as.tombstones[ID] = tombstone
+	return tombstone, nil
+}
+
+// ListTombstones returns the tombstones of the deleted articles that are still retained, ordered by
+// deletion time.
+func (as *ArticleStore) ListTombstones() []*types.Tombstone {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	res := []*types.Tombstone{}
+	for ID, tombstone := range as.tombstones {
+		if as.tombstoned(ID) {
+			res = append(res, tombstone)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].DeletedAt.Before(res[j].DeletedAt)
+	})
+	return res
+}
+
+// ClearTombstones removes the tombstones with the provided IDs, or all of them if none is provided,
+// so that the articles can be stored again. Returns the number of removed tombstones.
+func (as *ArticleStore) ClearTombstones(IDs ...string) (int, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if err := as.appendWAL(walEntry{Op: walClear, IDs: IDs}); err != nil {
+		return 0, err
+	}
+	return as.clearTombstones(IDs...), nil
+}
+
+// clearTombstones removes the tombstones with the provided IDs, or all of them if none is provided.
+// The caller must hold the lock.
+func (as *ArticleStore) clearTombstones(IDs ...string) int {
+	if len(IDs) == 0 {
+		cleared := len(as.tombstones)
+		as.tombstones = map[string]*types.Tombstone{}
+		return cleared
+	}
+	cleared := 0
+	for _, ID := range IDs {
+		if _, ok := as.tombstones[ID]; ok {
+			delete(as.tombstones, ID)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// tombstoned returns whether the article with the provided ID was deleted and its tombstone is still
+// retained. The caller must hold the lock.
+func (as *ArticleStore) tombstoned(ID string) bool {
+	tombstone, ok := as.tombstones[ID]
+	if !ok {
+		return false
+	}
+	return as.tombstoneRetention <= 0 || as.clock.Now().Sub(tombstone.DeletedAt) < as.tombstoneRetention
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../clock"
+	"../types"
+)
+
+func TestArticleStoreDelete(t *testing.T) {
+	t.Run("deleted articles stay deleted until the tombstone expires", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		fakeClock := clock.NewFake(time.Unix(100, 0).UTC())
+		store := NewArticleStore(WithClock(fakeClock), WithTombstoneRetention(time.Hour))
+		article, err := store.Create(&types.Article{GUID: "deleted"})
+		r.NoError(err)
+		_, err = store.MarkRead(article.ID, true)
+		r.NoError(err)
+
+		tombstone, err := store.Delete(article.ID)
+		r.NoError(err)
+		a.Equal(&types.Tombstone{ID: article.ID, GUID: "deleted", DeletedAt: fakeClock.Now()}, tombstone)
+		_, err = store.Get(article.ID)
+		a.Error(err)
+		stored, err := store.Create(&types.Article{GUID: "deleted"})
+		r.NoError(err)
+		a.Nil(stored)
+		stored, _, err = store.Upsert(&types.Article{GUID: "deleted"})
+		r.NoError(err)
+		a.Nil(stored)
+		a.Equal([]*types.Tombstone{tombstone}, store.ListTombstones())
+
+		fakeClock.Advance(time.Hour)
+		a.Empty(store.ListTombstones())
+		stored, err = store.Create(&types.Article{GUID: "deleted"})
+		r.NoError(err)
+		r.NotNil(stored)
+		a.Equal(article.ID, stored.ID)
+		a.False(stored.Read, "state must not survive the deletion")
+	})
+
+	t.Run("zero retention keeps tombstones", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		fakeClock := clock.NewFake(time.Unix(100, 0).UTC())
+		store := NewArticleStore(WithClock(fakeClock))
+		article, err := store.Create(&types.Article{GUID: "deleted"})
+		r.NoError(err)
+		_, err = store.Delete(article.ID)
+		r.NoError(err)
+
+		fakeClock.Advance(365 * 24 * time.Hour)
+		stored, err := store.Create(&types.Article{GUID: "deleted"})
+		r.NoError(err)
+		a.Nil(stored)
+	})
+
+	t.Run("errors for unknown articles", func(t *testing.T) {
+		_, err := NewArticleStore().Delete("unknown")
+		assert.Error(t, err)
+	})
+}
+
+func TestArticleStoreClearTombstones(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	store := NewArticleStore()
+	var IDs []string
+	for _, guid := range []string{"first", "second", "third"} {
+		article, err := store.Create(&types.Article{GUID: guid})
+		r.NoError(err)
+		_, err = store.Delete(article.ID)
+		r.NoError(err)
+		IDs = append(IDs, article.ID)
+	}
+
+	cleared, err := store.ClearTombstones(IDs[0], "unknown")
+	r.NoError(err)
+	a.Equal(1, cleared)
+	a.Len(store.ListTombstones(), 2, "unexpected number of tombstones")
+	stored, err := store.Create(&types.Article{GUID: "first"})
+	r.NoError(err)
+	a.NotNil(stored)
+
+	cleared, err = store.ClearTombstones()
+	r.NoError(err)
+	a.Equal(2, cleared)
+	a.Empty(store.ListTombstones())
+}
+
+func TestArticleStoreTombstonesWAL(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	path := filepath.Join(t.TempDir(), "articles.wal")
+	store := NewArticleStore()
+	r.NoError(store.OpenWAL(path))
+	var IDs []string
+	for _, guid := range []string{"kept", "deleted", "cleared"} {
+		article, err := store.Create(&types.Article{GUID: guid})
+		r.NoError(err)
+		IDs = append(IDs, article.ID)
+	}
+	for _, ID := range IDs[1:] {
+		_, err := store.Delete(ID)
+		r.NoError(err)
+	}
+	_, err := store.ClearTombstones(IDs[2])
+	r.NoError(err)
+
+	recovered := NewArticleStore()
+	r.NoError(recovered.OpenWAL(path))
+	articles, err := recovered.List("", 0, "")
+	r.NoError(err)
+	a.Equal([]string{"kept"}, guids(articles))
+	tombstones := recovered.ListTombstones()
+	r.Len(tombstones, 1, "unexpected number of tombstones")
+	a.Equal(IDs[1], tombstones[0].ID)
+
+	// Tombstones are kept when compacting.
+	r.NoError(recovered.Close())
+	reopened := NewArticleStore()
+	r.NoError(reopened.OpenWAL(path))
+	a.Len(reopened.ListTombstones(), 1, "unexpected number of tombstones")
+}
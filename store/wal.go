@@ -0,0 +1,242 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"../types"
+)
+
+// walOp identifies the change recorded by a write-ahead log entry.
+type walOp string
+
+const (
+	// walCreate adds an article to the store.
+	walCreate walOp = "create"
+	// walUpdate replaces the fields of an existing article.
+	walUpdate walOp = "update"
+	// walDelete removes an article from the store, leaving the tombstone when provided.
+	walDelete walOp = "delete"
+	// walClear removes the tombstones with the provided IDs, or all of them if none is provided.
+	walClear walOp = "clear"
+	// walOrder sets the order of the articles by publish date, as the order in which they are
+	// created is kept as their ingestion order. It is written when compacting and reindexing.
+	walOrder walOp = "order"
+)
+
+// walEntry is a single change recorded in the write-ahead log, written as a line of JSON.
+type walEntry struct {
+	Op        walOp            `json:"op"`
+	Article   *types.Article   `json:"article,omitempty"`
+	ID        string           `json:"id,omitempty"`
+	IDs       []string         `json:"ids,omitempty"`
+	Tombstone *types.Tombstone `json:"tombstone,omitempty"`
+}
+
+// OpenWAL enables the write-ahead log at the provided path, so that the articles can be recovered
+// when the process crashes. Any articles recorded by a previous log at the path are replayed into
+// the store, which must be empty, and the log is compacted before appending new changes to it.
+// Close must be called on clean shutdown to compact the log again.
+func (as *ArticleStore) OpenWAL(path string) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if as.wal != nil {
+		return errors.New("write-ahead log is already open")
+	}
+	if err := as.replayWAL(path); err != nil {
+		return err
+	}
+	as.walPath = path
+	return as.compactWAL()
+}
+
+// CompactWAL rewrites the write-ahead log with the minimum entries needed to rebuild the current
+// articles, discarding the history of changes. It does nothing if the log is not open.
+func (as *ArticleStore) CompactWAL() error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if as.walPath == "" {
+		return nil
+	}
+	return as.compactWAL()
+}
+
+// Close compacts and closes the write-ahead log, if open. Changes made afterwards are not logged.
+func (as *ArticleStore) Close() error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if as.walPath == "" {
+		return nil
+	}
+	err := as.compactWAL()
+	if closeErr := as.wal.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("could not close write-ahead log: %v", closeErr)
+	}
+	as.wal = nil
+	as.walPath = ""
+	return err
+}
+
+// appendWAL writes the entry to the write-ahead log, if open. The caller must hold the lock.
+func (as *ArticleStore) appendWAL(entry walEntry) error {
+	if as.wal == nil {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not write to write-ahead log: %v", err)
+	}
+	if _, err := as.wal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("could not write to write-ahead log: %v", err)
+	}
+	return nil
+}
+
+// replayWAL applies the entries of the log at the provided path to the store. A missing log is
+// treated as empty, and an incomplete last entry, left by a crash while it was written, is ignored.
+// The caller must hold the lock.
+func (as *ArticleStore) replayWAL(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not open write-ahead log: %v", err)
+	}
+	defer f.Close()
+	if len(as.m) > 0 {
+		return errors.New("write-ahead log can only be replayed into an empty store")
+	}
+	dec := json.NewDecoder(f)
+	for {
+		var entry walEntry
+		err := dec.Decode(&entry)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not replay write-ahead log: %v", err)
+		}
+		if err := as.applyWAL(entry); err != nil {
+			return fmt.Errorf("could not replay write-ahead log: %v", err)
+		}
+	}
+}
+
+// applyWAL applies a single log entry to the store. The caller must hold the lock.
+func (as *ArticleStore) applyWAL(entry walEntry) error {
+	switch entry.Op {
+	case walCreate:
+		if entry.Article == nil {
+			return errors.New("create entry without article")
+		}
+		if _, ok := as.m[entry.Article.ID]; ok {
+			return nil
+		}
+		as.applyState(entry.Article)
+		as.insert(entry.Article)
+	case walUpdate:
+		if entry.Article == nil {
+			return errors.New("update entry without article")
+		}
+		if existing, ok := as.m[entry.Article.ID]; ok {
+			updated := *entry.Article
+			as.applyState(&updated)
+			as.replace(map[*types.Article]*types.Article{existing: &updated})
+		}
+	case walDelete:
+		as.remove(entry.ID)
+		if entry.Tombstone != nil {
+			delete(as.state, entry.ID)
+			as.tombstones[entry.ID] = entry.Tombstone
+		}
+	case walClear:
+		as.clearTombstones(entry.IDs...)
+	case walOrder:
+		if len(entry.IDs) != len(as.a) {
+			return errors.New("order entry does not match the articles")
+		}
+		a := make([]*types.Article, 0, len(entry.IDs))
+		for _, ID := range entry.IDs {
+			article, ok := as.m[ID]
+			if !ok {
+				return fmt.Errorf("order entry holds unknown article %q", ID)
+			}
+			a = append(a, article)
+		}
+		as.a = a
+	default:
+		return fmt.Errorf("unknown entry %q", entry.Op)
+	}
+	return nil
+}
+
+// compactWAL writes the current articles to a new log, which atomically replaces the one at the
+// log path, and reopens it for appending. The caller must hold the lock.
+func (as *ArticleStore) compactWAL() error {
+	tmpPath := as.walPath + ".tmp"
+	if err := as.writeWAL(tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not compact write-ahead log: %v", err)
+	}
+	if as.wal != nil {
+		as.wal.Close()
+		as.wal = nil
+	}
+	if err := os.Rename(tmpPath, as.walPath); err != nil {
+		return fmt.Errorf("could not compact write-ahead log: %v", err)
+	}
+	f, err := os.OpenFile(as.walPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("could not open write-ahead log: %v", err)
+	}
+	as.wal = f
+	return nil
+}
+
+// writeWAL writes a log creating the current articles in ingestion order, followed by their order
+// by publish date and the retained tombstones, to the provided path. The caller must hold the lock.
+func (as *ArticleStore) writeWAL(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, a := range as.ingested {
+		if err := enc.Encode(walEntry{Op: walCreate, Article: a}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	order := walEntry{Op: walOrder, IDs: make([]string, 0, len(as.a))}
+	for _, a := range as.a {
+		order.IDs = append(order.IDs, a.ID)
+	}
+	if err := enc.Encode(order); err != nil {
+		f.Close()
+		return err
+	}
+	for ID, tombstone := range as.tombstones {
+		if !as.tombstoned(ID) {
+			continue
+		}
+		if err := enc.Encode(walEntry{Op: walDelete, ID: ID, Tombstone: tombstone}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
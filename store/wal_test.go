@@ -0,0 +1,142 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"../clock"
+	"../types"
+)
+
+// guids returns the GUIDs of the provided articles in order.
+func guids(articles []*types.Article) []string {
+	res := []string{}
+	for _, a := range articles {
+		res = append(res, a.GUID)
+	}
+	return res
+}
+
+func TestArticleStoreWAL(t *testing.T) {
+	t.Run("recovers articles after a crash", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		path := filepath.Join(t.TempDir(), "articles.wal")
+		fakeClock := clock.NewFake(time.Unix(100, 0).UTC())
+		store := NewArticleStore(WithClock(fakeClock))
+		r.NoError(store.OpenWAL(path))
+		_, err := store.Create(&types.Article{GUID: "expired", PublishDate: time.Unix(25, 0).UTC()})
+		r.NoError(err)
+		fakeClock.Advance(time.Minute)
+		for _, article := range []*types.Article{
+			{GUID: "middle", PublishDate: time.Unix(20, 0).UTC()},
+			{GUID: "newest", PublishDate: time.Unix(30, 0).UTC()},
+			{GUID: "oldest", PublishDate: time.Unix(10, 0).UTC()},
+			// Articles with the same publish date keep their relative order.
+			{GUID: "same_date", PublishDate: time.Unix(20, 0).UTC()},
+		} {
+			_, err := store.Create(article)
+			r.NoError(err)
+		}
+		_, _, err = store.Upsert(&types.Article{GUID: "middle", Title: "updated"})
+		r.NoError(err)
+		r.Equal(1, store.Evict(MaxIngestAge(30*time.Second)))
+		_, err = store.Create(&types.Article{GUID: "late", PublishDate: time.Unix(5, 0).UTC()})
+		r.NoError(err)
+		expected, err := store.List("", 0, "")
+		r.NoError(err)
+		expectedIngested, err := store.ListByIngestion("", 0, "")
+		r.NoError(err)
+
+		// The first store is abandoned without closing the log, as on a crash.
+		recovered := NewArticleStore()
+		r.NoError(recovered.OpenWAL(path))
+		articles, err := recovered.List("", 0, "")
+		r.NoError(err)
+		a.Equal(guids(expected), guids(articles))
+		a.Equal(expected, articles)
+		articles, err = recovered.ListByIngestion("", 0, "")
+		r.NoError(err)
+		a.Equal(guids(expectedIngested), guids(articles))
+	})
+
+	t.Run("recovers articles after compaction", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		path := filepath.Join(t.TempDir(), "articles.wal")
+		store := NewArticleStore()
+		r.NoError(store.OpenWAL(path))
+		_, err := store.Create(&types.Article{GUID: "newer", PublishDate: time.Unix(20, 0).UTC()})
+		r.NoError(err)
+		_, err = store.Create(&types.Article{GUID: "older", PublishDate: time.Unix(10, 0).UTC()})
+		r.NoError(err)
+		r.NoError(store.Close())
+
+		reopened := NewArticleStore()
+		r.NoError(reopened.OpenWAL(path))
+		_, err = reopened.Create(&types.Article{GUID: "newest", PublishDate: time.Unix(30, 0).UTC()})
+		r.NoError(err)
+
+		recovered := NewArticleStore()
+		r.NoError(recovered.OpenWAL(path))
+		articles, err := recovered.List("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"older", "newer", "newest"}, guids(articles))
+		articles, err = recovered.ListByIngestion("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"newer", "older", "newest"}, guids(articles))
+	})
+
+	t.Run("ignores an incomplete last entry", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		path := filepath.Join(t.TempDir(), "articles.wal")
+		store := NewArticleStore()
+		r.NoError(store.OpenWAL(path))
+		_, err := store.Create(&types.Article{GUID: "kept"})
+		r.NoError(err)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		r.NoError(err)
+		_, err = f.WriteString(`{"op":"create","article":{"GU`)
+		r.NoError(err)
+		r.NoError(f.Close())
+
+		recovered := NewArticleStore()
+		r.NoError(recovered.OpenWAL(path))
+		articles, err := recovered.List("", 0, "")
+		r.NoError(err)
+		a.Equal([]string{"kept"}, guids(articles))
+	})
+
+	t.Run("errors for a corrupted log", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		path := filepath.Join(t.TempDir(), "articles.wal")
+		r.NoError(os.WriteFile(path, []byte("{\"op\":\"unknown\"}\n"), 0644))
+		err := NewArticleStore().OpenWAL(path)
+		r.Error(err)
+		a.Contains(err.Error(), "could not replay write-ahead log")
+	})
+
+	t.Run("changes after closing are not logged", func(t *testing.T) {
+		r := require.New(t)
+		a := assert.New(t)
+		path := filepath.Join(t.TempDir(), "articles.wal")
+		store := NewArticleStore()
+		r.NoError(store.OpenWAL(path))
+		r.NoError(store.Close())
+		_, err := store.Create(&types.Article{GUID: "unlogged"})
+		r.NoError(err)
+
+		recovered := NewArticleStore()
+		r.NoError(recovered.OpenWAL(path))
+		articles, err := recovered.List("", 0, "")
+		r.NoError(err)
+		a.Empty(articles)
+	})
+}
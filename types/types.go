@@ -6,12 +6,55 @@ import (
 	"time"
 )
 
-// Feed holds information about a feed address.
+// Feed holds information about a feed address. Fallbacks are optional mirror addresses that are
+// tried in order whenever the primary address can't be loaded. Credentials are only set for feeds
+// requiring authentication and are never serialized. Timeout limits the time for reading the feed,
+// where zero means the default timeout of the reader is used. It is serialized in nanoseconds, while
+// the API accepts it as a duration string such as "10s". Health is the ratio of successful loads
+// among the most recent ones, whose number is held in Loads, and is zero until the feed is loaded.
+// ClonedFrom holds the ID of the feed a clone was created from, and is empty for other feeds.
 type Feed struct {
-	ID       string
-	Provider string
-	Category string
-	Address  string
+	ID          string
+	Provider    string
+	Category    string
+	Address     string
+	Fallbacks   []string
+	Credentials *Credentials `json:"-"`
+	Timeout     time.Duration
+	Health      float64
+	Loads       int
+	ClonedFrom  string `json:",omitempty"`
+}
+
+// Credentials holds the username and password used for authenticating against a feed.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// RawFeed holds the body of a feed as received from its address, along with its content type.
+type RawFeed struct {
+	ContentType string
+	Body        []byte
+}
+
+// ReadOptions holds the settings used when reading a feed address. Credentials are optional and a
+// zero timeout means the default timeout of the reader is used. Paginate follows the links to the
+// next pages of the feed, up to the maximum number of pages of the reader.
+type ReadOptions struct {
+	Credentials *Credentials
+	Timeout     time.Duration
+	Paginate    bool
+}
+
+// ReadOptions returns the options used for reading the feed addresses.
+func (f *Feed) ReadOptions() ReadOptions {
+	return ReadOptions{Credentials: f.Credentials, Timeout: f.Timeout}
+}
+
+// Addresses returns all addresses of the feed, starting by the primary one followed by fallbacks.
+func (f *Feed) Addresses() []string {
+	return append([]string{f.Address}, f.Fallbacks...)
 }
 
 //Enclosure struct for each Item Enclosure
@@ -35,4 +78,163 @@ type Article struct {
 	Author      string
 	Content     string
 	FullText    string
+	Truncated   bool
+	Read        bool
+	Starred     bool
+	IngestedAt  time.Time
+
+	// DisplayCategories holds the categories as provided by the feed when they are normalized.
+	DisplayCategories []string
+	// Labels holds the labels applied by users, which are independent from the feed categories.
+	Labels []string
+	// Provider holds the provider of the feed the article was loaded from.
+	Provider string
+	// BatchID holds the ID of the feed load that ingested the article, when batch IDs are enabled.
+	BatchID string
+	// ImageURL holds the URL of the image representing the article, taken from its first image
+	// enclosure.
+	ImageURL string
+	// SelfURL holds the link to the article within the service, which is only set when rendered.
+	SelfURL string
+	// CanonicalLink holds the canonical URL declared by the page of the article, which is only set
+	// once its full text is fetched. Articles sharing it are collapsed as duplicates when listed.
+	CanonicalLink string
+	// Scope holds the ID of the feed the identity of the article is scoped to, which is set for the
+	// articles of cloned feeds so they are stored apart from those of the feed they were cloned from.
+	Scope string `json:"-"`
+}
+
+// Tombstone records an article that was deleted, so that it is not stored again when its feed is
+// loaded.
+type Tombstone struct {
+	ID        string
+	GUID      string
+	DeletedAt time.Time
+}
+
+// Channel holds the information read from a feed address along with its converted articles. The
+// address is the one the content was finally read from after following redirects, and moved is set
+// when the feed has permanently moved to it. Skipped counts the items of the feed that were not
+// converted into articles for being invalid, and Attempts the number of times the address was
+// requested, which is more than one when transient failures were retried. Hub is the WebSub hub
+// declared by the feed, if any, and Topic the address the feed is published at on that hub.
+type Channel struct {
+	Title    string
+	Address  string
+	Moved    bool
+	Articles []*Article
+	Skipped  int
+	Attempts int
+	Hub      string
+	Topic    string
+}
+
+// ArticleFilter holds the conditions used to select articles from a store. Empty fields apply no
+// filtering. When categories or labels are provided, articles having any of them are selected.
+// EnclosureType selects articles having at least one enclosure whose type starts with it, such as
+// "image/" or "audio/", while NoEnclosures selects only the articles without any enclosure.
+// HasFullText selects only the articles whose full text is populated, and HasImage the ones having an
+// image URL. Batch selects the articles ingested by the feed load with that batch ID. HideFuture
+// leaves out the articles whose publish date is after the current time of the store.
+// Snapshot, when set to a token returned by the store, selects only the articles that were present
+// when the snapshot was taken.
+type ArticleFilter struct {
+	Feed          string
+	Categories    []string
+	Labels        []string
+	EnclosureType string
+	NoEnclosures  bool
+	HasFullText   bool
+	HasImage      bool
+	HideFuture    bool
+	Batch         string
+	Snapshot      string
+}
+
+// CategoryCount holds the number of articles in a category.
+type CategoryCount struct {
+	Category string
+	Count    int
+}
+
+// ArticleOrder defines the order in which articles are listed. Orders are ascending unless combined
+// with OrderDescending, such as in OrderTitle|OrderDescending.
+type ArticleOrder int
+
+const (
+	// OrderPublished lists articles by publish date.
+	OrderPublished ArticleOrder = iota
+	// OrderIngested lists articles by the time they were ingested.
+	OrderIngested
+	// OrderTitle lists articles by title, ignoring case.
+	OrderTitle
+)
+
+// OrderDescending reverses the order it is combined with.
+const OrderDescending ArticleOrder = 1 << 8
+
+// ArticleDiff describes the outcome of upserting an article. Created is set when the article was
+// not present before, otherwise Fields lists the names of the fields whose values changed.
+type ArticleDiff struct {
+	Created bool
+	Fields  []string
+}
+
+// Changed reports whether the upsert modified an existing article.
+func (d *ArticleDiff) Changed() bool {
+	return len(d.Fields) > 0
+}
+
+// LoadSummary summarizes the outcome of loading a feed. Articles already present in the store are
+// counted as updated when any of their fields changed, or as unchanged otherwise. Changes holds the
+// changed fields of each updated article, keyed by article ID. Skipped counts the items of the feed
+// that were discarded for being invalid, Expired the ones discarded for being older than the maximum
+// article age, Capped the ones discarded for exceeding the maximum articles stored per load, and
+// Deleted the articles that were not stored for having been deleted or evicted right away by the
+// eviction policy of the store. Attempts counts the requests made to the address the feed was read
+// from, and Retried is set when it took more than one. BatchID is the ID the created articles were
+// tagged with, when batch IDs are enabled.
+type LoadSummary struct {
+	Created   int
+	Updated   int
+	Unchanged int
+	Skipped   int
+	Expired   int
+	Capped    int
+	Deleted   int
+	Attempts  int
+	Retried   bool
+	Changes   map[string][]string
+	BatchID   string
+}
+
+// FeedLoadResult holds the outcome of loading one of many feeds, which is either its Summary or, if
+// the load failed, its Error.
+type FeedLoadResult struct {
+	FeedID  string
+	Summary *LoadSummary
+	Error   string
+}
+
+// LoadJobStatus is the state of a queued feed load.
+type LoadJobStatus string
+
+const (
+	// LoadJobQueued is the status of loads waiting in the queue.
+	LoadJobQueued LoadJobStatus = "queued"
+	// LoadJobRunning is the status of loads being made.
+	LoadJobRunning LoadJobStatus = "running"
+	// LoadJobDone is the status of loads that finished successfully, reporting their Summary.
+	LoadJobDone LoadJobStatus = "done"
+	// LoadJobFailed is the status of loads that failed, reporting their Error.
+	LoadJobFailed LoadJobStatus = "failed"
+)
+
+// LoadJob represents a feed load made asynchronously through the load queue.
+type LoadJob struct {
+	ID      string
+	FeedID  string
+	Status  LoadJobStatus
+	Summary *LoadSummary
+	Error   string
 }